@@ -0,0 +1,54 @@
+// Package bundle writes a museum's charts to a single, reproducible tarball,
+// verifies such a bundle, and serves it as a museum.  A bundle holds the
+// archives and provenance files of the exported chart versions under
+// `charts/`, an `index.yaml` that refers to them, and a `manifest.json` that
+// records the digest of every file.  Every file has the bundle's timestamp,
+// so that exporting the same charts at the same timestamp writes the same
+// bytes.
+package bundle
+
+import (
+	"time"
+)
+
+// Version is the format of the bundles written by this package
+const Version = "v1"
+
+const (
+	manifestName  = "manifest.json"
+	indexName     = "index.yaml"
+	chartsDir     = "charts/"
+	provenanceExt = ".prov"
+)
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	APIVersion string    `json:"apiVersion"`
+	Museum     string    `json:"museum"`
+	Timestamp  time.Time `json:"timestamp"`
+	Charts     []*Chart  `json:"charts"`
+	Files      []*File   `json:"files"`
+}
+
+// Chart is a chart version in a bundle
+type Chart struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Digest     string    `json:"digest"`
+	Created    time.Time `json:"created"`
+	Archive    string    `json:"archive"`
+	Provenance string    `json:"provenance,omitempty"`
+}
+
+// File is a file in a bundle, other than the manifest, with its sha256 digest
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
+
+// Problem is a way in which a bundle does not match its manifest
+type Problem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,222 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+var testTimestamp = time.Date(2020, 4, 1, 12, 30, 45, 500, time.FixedZone("test", 3600))
+
+func testVersion(name, version string, archive []byte) *repo.ChartVersion {
+	return &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: name, Version: version},
+		URLs:     []string{"http://museum.example.com/charts/" + name + "-" + version + ".tgz"},
+		Digest:   sha256sum(archive),
+		Created:  testTimestamp,
+	}
+}
+
+func testBundle(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "prod", testTimestamp)
+	if err := w.Add(testVersion("foo", "1.0.0", []byte("foo archive")), []byte("foo archive"), []byte("signature")); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err := w.Add(testVersion("bar", "0.1.0", []byte("bar archive")), []byte("bar archive"), nil); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	return buf.Bytes()
+}
+
+// rewrite copies a bundle, passing each file through `f`; a file for which
+// `f` returns nil is dropped
+func rewrite(t *testing.T, b []byte, f func(name string, content []byte) []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		content, _ := ioutil.ReadAll(tr)
+		if content = f(hdr.Name, content); content == nil {
+			continue
+		}
+		hdr.Size = int64(len(content))
+		tw.WriteHeader(hdr)
+		tw.Write(content)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func Test_Bundle_Writer(t *testing.T) {
+	b := testBundle(t)
+	if !bytes.Equal(b, testBundle(t)) {
+		t.Errorf("Bundles with the same charts and timestamp differ")
+	}
+
+	m, problems, err := Verify(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if len(problems) != 0 {
+		t.Errorf("Unexpected problems: %+v", problems[0])
+	}
+	if m.Museum != "prod" || !m.Timestamp.Equal(testTimestamp.Truncate(time.Second)) || m.Timestamp.Location() != time.UTC {
+		t.Errorf("Incorrect manifest: %+v", m)
+	}
+	if len(m.Charts) != 2 || m.Charts[0].Provenance != "charts/foo-1.0.0.tgz.prov" || m.Charts[1].Provenance != "" {
+		t.Errorf("Incorrect charts: %+v", m.Charts)
+	}
+	if len(m.Files) != 4 || m.Files[3].Path != indexName {
+		t.Errorf("Incorrect files: %+v", m.Files)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "prod", testTimestamp)
+	if err = w.Add(testVersion("foo", "1.0.0", []byte("other")), []byte("foo archive"), nil); err == nil {
+		t.Errorf("Archive that does not match its digest was added")
+	}
+}
+
+func Test_Bundle_Verify(t *testing.T) {
+	b := testBundle(t)
+
+	tcs := []struct {
+		name     string
+		f        func(name string, content []byte) []byte
+		expected []string
+	}{
+		{
+			name: "modified archive",
+			f: func(name string, content []byte) []byte {
+				if name == "charts/bar-0.1.0.tgz" {
+					return []byte("bar ARCHIVE")
+				}
+				return content
+			},
+			expected: []string{"charts/bar-0.1.0.tgz"},
+		},
+		{
+			name: "missing provenance",
+			f: func(name string, content []byte) []byte {
+				if name == "charts/foo-1.0.0.tgz.prov" {
+					return nil
+				}
+				return content
+			},
+			expected: []string{"charts/foo-1.0.0.tgz.prov"},
+		},
+		{
+			name: "modified index",
+			f: func(name string, content []byte) []byte {
+				if name == indexName {
+					return append(content, []byte("# comment\n")...)
+				}
+				return content
+			},
+			expected: []string{indexName},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, problems, err := Verify(bytes.NewReader(rewrite(t, b, tc.f)))
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if len(problems) != len(tc.expected) {
+				t.Fatalf("Incorrect number of problems; expected %d, actual %d: %+v", len(tc.expected), len(problems), problems)
+			}
+			for k, p := range problems {
+				if p.Path != tc.expected[k] {
+					t.Errorf("Incorrect problem %d; expected '%s', actual '%s': %s", k, tc.expected[k], p.Path, p.Message)
+				}
+			}
+		})
+	}
+
+	noManifest := rewrite(t, b, func(name string, content []byte) []byte {
+		if name == manifestName {
+			return nil
+		}
+		return content
+	})
+	if _, _, err := Verify(bytes.NewReader(noManifest)); err == nil {
+		t.Errorf("Bundle without a manifest was verified")
+	}
+	if _, _, err := Verify(bytes.NewReader([]byte("not a bundle"))); err == nil {
+		t.Errorf("Garbage was verified")
+	}
+}
+
+func Test_Bundle_RoundTrip(t *testing.T) {
+	bd, err := read(bytes.NewReader(testBundle(t)))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	tcs := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/health", status: http.StatusOK, body: `{"healthy":true}`},
+		{method: http.MethodGet, path: "/charts/foo-1.0.0.tgz", status: http.StatusOK, body: "foo archive"},
+		{method: http.MethodGet, path: "/charts/foo-1.0.0.tgz.prov", status: http.StatusOK, body: "signature"},
+		{method: http.MethodHead, path: "/charts/foo-1.0.0.tgz", status: http.StatusOK},
+		{method: http.MethodGet, path: "/charts/bar-0.1.0.tgz.prov", status: http.StatusNotFound, body: `{"error":"not found"}`},
+		{method: http.MethodGet, path: "/manifest.json", status: http.StatusNotFound, body: `{"error":"not found"}`},
+		{method: http.MethodGet, path: "/api/charts/baz", status: http.StatusNotFound, body: `{"error":"not found"}`},
+		{method: http.MethodPost, path: "/api/charts", status: http.StatusMethodNotAllowed, body: `{"error":"method not allowed"}`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			resp, err := bd.RoundTrip(httptest.NewRequest(tc.method, "http://bundle"+tc.path, nil))
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			b, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status || string(b) != tc.body {
+				t.Errorf("Incorrect response; expected %d '%s', actual %d '%s'", tc.status, tc.body, resp.StatusCode, string(b))
+			}
+		})
+	}
+
+	resp, _ := bd.RoundTrip(httptest.NewRequest(http.MethodGet, "http://bundle/api/charts/foo/1.0.0", nil))
+	cv := &repo.ChartVersion{}
+	if err = json.NewDecoder(resp.Body).Decode(cv); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if cv.Name != "foo" || len(cv.URLs) != 1 || cv.URLs[0] != "charts/foo-1.0.0.tgz" {
+		t.Errorf("Incorrect chart version: %+v", cv)
+	}
+}
@@ -0,0 +1,211 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Bundle is a bundle that was read into memory
+type Bundle struct {
+	manifest *Manifest
+	index    *repo.IndexFile
+	files    map[string][]byte
+}
+
+// Open reads the bundle at `path`, and verifies it.  A bundle that does not
+// match its manifest is an error.
+func Open(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open bundle '%s'", path)
+	}
+	defer f.Close()
+
+	b, err := read(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read bundle '%s'", path)
+	}
+	if problems := b.verify(); len(problems) != 0 {
+		return nil, errors.Errorf("Bundle '%s' is corrupt: '%s': %s", path, problems[0].Path, problems[0].Message)
+	}
+	return b, nil
+}
+
+// Verify reads a bundle, and returns its manifest and the ways in which the
+// bundle does not match it.  A bundle that cannot be read at all, or that has
+// no manifest, is an error.
+func Verify(r io.Reader) (*Manifest, []*Problem, error) {
+	b, err := read(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.manifest, b.verify(), nil
+}
+
+// Manifest returns the bundle's manifest
+func (b *Bundle) Manifest() *Manifest {
+	return b.manifest
+}
+
+// Index returns the bundle's repository index
+func (b *Bundle) Index() *repo.IndexFile {
+	return b.index
+}
+
+func read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Not a bundle")
+	}
+	defer gz.Close()
+
+	b := &Bundle{files: map[string][]byte{}}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read bundle")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read '%s' from bundle", hdr.Name)
+		}
+		b.files[hdr.Name] = content
+	}
+
+	content, ok := b.files[manifestName]
+	if !ok {
+		return nil, errors.Errorf("Bundle has no '%s'", manifestName)
+	}
+	delete(b.files, manifestName)
+	b.manifest = &Manifest{}
+	if err = json.Unmarshal(content, b.manifest); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode '%s'", manifestName)
+	}
+	if b.manifest.APIVersion != Version {
+		return nil, errors.Errorf("Unsupported bundle version '%s'", b.manifest.APIVersion)
+	}
+
+	// A bundle whose index is missing or damaged is still read, so that the
+	// problem is reported by verify.
+	b.index = repo.NewIndexFile()
+	if content, ok := b.files[indexName]; ok {
+		i := &repo.IndexFile{}
+		if yaml.Unmarshal(content, i) == nil {
+			if i.Entries == nil {
+				i.Entries = map[string]repo.ChartVersions{}
+			}
+			i.SortEntries()
+			b.index = i
+		}
+	}
+
+	return b, nil
+}
+
+// verify returns the ways in which the bundle does not match its manifest,
+// ordered by path
+func (b *Bundle) verify() []*Problem {
+	problems := []*Problem{}
+	add := func(p, format string, args ...interface{}) {
+		problems = append(problems, &Problem{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	listed := map[string]string{}
+	for _, f := range b.manifest.Files {
+		listed[f.Path] = f.Digest
+		content, ok := b.files[f.Path]
+		if !ok {
+			add(f.Path, "missing")
+			continue
+		}
+		if int64(len(content)) != f.Size {
+			add(f.Path, "size is %d, expected %d", len(content), f.Size)
+			continue
+		}
+		if digest := sha256sum(content); digest != f.Digest {
+			add(f.Path, "digest is '%s', expected '%s'", digest, f.Digest)
+		}
+	}
+	for p := range b.files {
+		if _, ok := listed[p]; !ok {
+			add(p, "not in manifest")
+		}
+	}
+
+	if _, ok := listed[indexName]; !ok {
+		if _, ok := b.files[indexName]; !ok {
+			add(indexName, "missing")
+		}
+	}
+
+	charts := map[string]bool{}
+	for _, c := range b.manifest.Charts {
+		charts[c.Name+"-"+c.Version] = true
+		if digest, ok := listed[c.Archive]; !ok {
+			add(c.Archive, "archive of chart '%s' version '%s' is not in manifest", c.Name, c.Version)
+		} else if digest != c.Digest {
+			add(c.Archive, "digest is '%s', expected '%s' for chart '%s' version '%s'", digest, c.Digest, c.Name, c.Version)
+		}
+		if c.Provenance != "" {
+			if _, ok := listed[c.Provenance]; !ok {
+				add(c.Provenance, "provenance of chart '%s' version '%s' is not in manifest", c.Name, c.Version)
+			}
+		}
+		cv := find(b.index, c.Name, c.Version)
+		if cv == nil {
+			add(indexName, "chart '%s' version '%s' is missing", c.Name, c.Version)
+		} else if cv.Digest != c.Digest {
+			add(indexName, "chart '%s' version '%s' has digest '%s', expected '%s'", c.Name, c.Version, cv.Digest, c.Digest)
+		}
+	}
+	for name, cvs := range b.index.Entries {
+		for _, cv := range cvs {
+			if !charts[name+"-"+cv.Version] {
+				add(indexName, "chart '%s' version '%s' is not in manifest", name, cv.Version)
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Path != problems[j].Path {
+			return problems[i].Path < problems[j].Path
+		}
+		return problems[i].Message < problems[j].Message
+	})
+	return problems
+}
+
+// find returns the entry for exactly `version` of the chart; unlike
+// repo.IndexFile.Get, it does not treat the version as a constraint.
+func find(i *repo.IndexFile, name, version string) *repo.ChartVersion {
+	for _, cv := range i.Entries[name] {
+		if cv.Version == version {
+			return cv
+		}
+	}
+	return nil
+}
+
+func sha256sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
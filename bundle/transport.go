@@ -0,0 +1,77 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RoundTrip answers requests as a read-only chart museum would, from the
+// bundle: the index, the archives and provenance files, the chart API, and
+// the health check.  It never dials, so a bundle is a museum that needs no
+// network.
+func (b *Bundle) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return b.respond(req, http.StatusMethodNotAllowed, nil, "method not allowed"), nil
+	}
+
+	p := strings.TrimPrefix(req.URL.Path, "/")
+	switch {
+	case p == "health":
+		return b.respond(req, http.StatusOK, map[string]bool{"healthy": true}, ""), nil
+	case p == indexName || strings.HasPrefix(p, chartsDir):
+		if content, ok := b.files[p]; ok {
+			return b.response(req, http.StatusOK, "application/octet-stream", content), nil
+		}
+	case p == "api/charts":
+		return b.respond(req, http.StatusOK, b.index.Entries, ""), nil
+	case strings.HasPrefix(p, "api/charts/"):
+		parts := strings.Split(strings.TrimPrefix(p, "api/charts/"), "/")
+		switch len(parts) {
+		case 1:
+			if cvs, ok := b.index.Entries[parts[0]]; ok {
+				return b.respond(req, http.StatusOK, cvs, ""), nil
+			}
+		case 2:
+			if cv := find(b.index, parts[0], parts[1]); cv != nil {
+				return b.respond(req, http.StatusOK, cv, ""), nil
+			}
+		}
+	}
+	return b.respond(req, http.StatusNotFound, nil, "not found"), nil
+}
+
+// respond encodes `v` as JSON, or, if `message` is set, an error as
+// ChartMuseum reports it
+func (b *Bundle) respond(req *http.Request, status int, v interface{}, message string) *http.Response {
+	if message != "" {
+		v = map[string]string{"error": message}
+	}
+	content, _ := json.Marshal(v)
+	return b.response(req, status, "application/json", content)
+}
+
+func (b *Bundle) response(req *http.Request, status int, contentType string, content []byte) *http.Response {
+	h := http.Header{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(content)))
+	length := int64(len(content))
+	if req.Method == http.MethodHead {
+		content = nil
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(content)),
+		ContentLength: length,
+		Request:       req,
+	}
+}
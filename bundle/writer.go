@@ -0,0 +1,151 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Writer writes a bundle.  The bundle is reproducible as long as the chart
+// versions are added in the same order.
+type Writer struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+
+	index    *repo.IndexFile
+	manifest *Manifest
+}
+
+// NewWriter starts a bundle of the charts of `museum`, written to `w`.  Every
+// file in the bundle has the `timestamp`, rounded down to the second.
+func NewWriter(w io.Writer, museum string, timestamp time.Time) *Writer {
+	timestamp = timestamp.UTC().Truncate(time.Second)
+
+	gz := gzip.NewWriter(w)
+	gz.ModTime = timestamp
+
+	index := repo.NewIndexFile()
+	index.Generated = timestamp
+
+	return &Writer{
+		gz:    gz,
+		tw:    tar.NewWriter(gz),
+		index: index,
+		manifest: &Manifest{
+			APIVersion: Version,
+			Museum:     museum,
+			Timestamp:  timestamp,
+			Charts:     []*Chart{},
+			Files:      []*File{},
+		},
+	}
+}
+
+// Add writes the archive of the chart version `cv`, i.e., an entry from the
+// museum's index, and its provenance file if `prov` is not empty.  The
+// archive must match the entry's digest.
+func (w *Writer) Add(cv *repo.ChartVersion, archive, prov []byte) error {
+	digest := sha256sum(archive)
+	if cv.Digest != "" && cv.Digest != digest {
+		return errors.Errorf("Digest mismatch for chart '%s' version '%s': expected '%s', actual '%s'", cv.Name, cv.Version, cv.Digest, digest)
+	}
+
+	p := fmt.Sprintf("%s%s-%s.tgz", chartsDir, cv.Name, cv.Version)
+	if err := w.writeFile(p, archive); err != nil {
+		return err
+	}
+	c := &Chart{
+		Name:    cv.Name,
+		Version: cv.Version,
+		Digest:  digest,
+		Created: cv.Created.UTC(),
+		Archive: p,
+	}
+	if len(prov) != 0 {
+		c.Provenance = p + provenanceExt
+		if err := w.writeFile(c.Provenance, prov); err != nil {
+			return err
+		}
+	}
+	w.manifest.Charts = append(w.manifest.Charts, c)
+
+	// The index refers to the archives relative to itself, so that it is
+	// served from wherever the bundle is.
+	w.index.Entries[cv.Name] = append(w.index.Entries[cv.Name], &repo.ChartVersion{
+		Metadata: cv.Metadata,
+		URLs:     []string{p},
+		Created:  c.Created,
+		Digest:   digest,
+	})
+	return nil
+}
+
+// Close writes the index and the manifest, and completes the bundle.  It does
+// not close the underlying writer.
+func (w *Writer) Close() error {
+	w.index.SortEntries()
+	b, err := yaml.Marshal(w.index)
+	if err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode index")
+	}
+	if err = w.writeFile(indexName, b); err != nil {
+		return err
+	}
+
+	// The manifest is last, so that it can list every other file.
+	b, err = json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode manifest")
+	}
+	if err = w.writeEntry(manifestName, b); err != nil {
+		return err
+	}
+
+	if err = w.tw.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write bundle")
+	}
+	if err = w.gz.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write bundle")
+	}
+	return nil
+}
+
+// Manifest returns the bundle's manifest; it is complete once the bundle is
+// closed
+func (w *Writer) Manifest() *Manifest {
+	return w.manifest
+}
+
+// writeFile writes a file, and lists it in the manifest
+func (w *Writer) writeFile(p string, b []byte) error {
+	if err := w.writeEntry(p, b); err != nil {
+		return err
+	}
+	w.manifest.Files = append(w.manifest.Files, &File{Path: p, Size: int64(len(b)), Digest: sha256sum(b)})
+	return nil
+}
+
+func (w *Writer) writeEntry(p string, b []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     p,
+		Mode:     0644,
+		Size:     int64(len(b)),
+		ModTime:  w.manifest.Timestamp,
+		Format:   tar.FormatPAX,
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, "Failed to write '%s' to bundle", p)
+	}
+	if _, err := w.tw.Write(b); err != nil {
+		return errors.Wrapf(err, "Failed to write '%s' to bundle", p)
+	}
+	return nil
+}
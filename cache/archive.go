@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ArchivePath returns the path that the archive with `digest` is stored at;
+// the file may not exist.  The digest must be valid; see OpenArchive.
+func (c *Cache) ArchivePath(digest string) string {
+	return filepath.Join(c.dir, archivesDir, digest+ArchiveExt)
+}
+
+// OpenArchive opens the cached archive with the sha256 `digest`, which comes
+// from a museum's index, and so must be 64 lowercase hex characters.  The
+// archive is verified against the digest; an archive that does not match is
+// removed.  Opening the archive updates its modification time, so that Prune
+// keeps the archives that are in use.  The second return value is false if
+// the archive is not cached.
+func (c *Cache) OpenArchive(digest string) (io.ReadCloser, bool, error) {
+	if err := checkDigest(digest); err != nil {
+		return nil, false, err
+	}
+
+	p := c.ArchivePath(digest)
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, errors.Wrapf(err, "Failed to open cached archive '%s'", digest)
+	}
+
+	h := sha256.Sum256(b)
+	if hex.EncodeToString(h[:]) != digest {
+		if err = os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return nil, false, errors.Wrapf(err, "Failed to remove corrupt cached archive '%s'", digest)
+		}
+		return nil, false, nil
+	}
+
+	// The archive has been read, so a cache that cannot be written to can
+	// still answer from it.
+	now := time.Now()
+	os.Chtimes(p, now, now)
+
+	return ioutil.NopCloser(bytes.NewReader(b)), true, nil
+}
+
+// PutArchive stores the archive read from `r`, after verifying that its
+// sha256 matches `digest`
+func (c *Cache) PutArchive(digest string, r io.Reader) error {
+	if err := checkDigest(digest); err != nil {
+		return err
+	}
+
+	target := c.ArchivePath(digest)
+	return writeFileFrom(target, func(w io.Writer) error {
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(w, h), r); err != nil {
+			return err
+		}
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+			return errors.Errorf("Digest mismatch: expected '%s', actual '%s'", digest, actual)
+		}
+		return nil
+	})
+}
+
+// checkDigest returns an error unless `digest` is a hex-encoded sha256, so
+// that a digest from an index cannot name a file outside of the cache
+func checkDigest(digest string) error {
+	if len(digest) != hex.EncodedLen(sha256.Size) {
+		return errors.Errorf("Invalid archive digest '%s'", digest)
+	}
+	for _, r := range digest {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return errors.Errorf("Invalid archive digest '%s'", digest)
+		}
+	}
+	return nil
+}
+
+// Archives returns the cached archives, sorted by digest
+func (c *Cache) Archives() ([]*Archive, error) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, archivesDir, "*"+ArchiveExt))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Internal error: failed to list cached archives")
+	}
+	sort.Strings(matches)
+
+	archives := make([]*Archive, 0, len(matches))
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read cached archive '%s'", m)
+		}
+		archives = append(archives, &Archive{
+			Digest:  strings.TrimSuffix(filepath.Base(m), ArchiveExt),
+			Fetched: fi.ModTime().UTC(),
+			Size:    fi.Size(),
+		})
+	}
+
+	return archives, nil
+}
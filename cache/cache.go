@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	archivesDir = "archives"
+	museumsDir  = "museums"
+
+	bodyExt = ".body"
+	metaExt = ".json"
+
+	// ArchiveExt is the extension of cached chart archives
+	ArchiveExt = ".tgz"
+)
+
+// Cache is an on-disk store of chart museum responses, keyed by museum, the
+// server that the museum names, and request path, and of chart archives, keyed
+// by digest
+type Cache struct {
+	dir string
+}
+
+// Entry describes a cached response
+type Entry struct {
+	Museum string `json:"museum"`
+
+	// Source identifies the server that the museum named when the response
+	// was cached, so that a museum that is redefined to name another server
+	// does not answer from the old server's responses
+	Source string `json:"source,omitempty"`
+
+	Path         string    `json:"path"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	Status       int       `json:"status,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Size         int64     `json:"size"`
+
+	key string
+}
+
+// Archive describes a cached chart archive
+type Archive struct {
+	Digest string `json:"digest"`
+
+	// Fetched is when the archive was last stored or opened
+	Fetched time.Time `json:"fetched"`
+
+	Size int64 `json:"size"`
+}
+
+// DefaultDir returns the `churl` directory under the OS-specific user cache
+// directory
+func DefaultDir() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to find user cache directory")
+	}
+	return filepath.Join(d, "churl"), nil
+}
+
+// Open returns a cache rooted at `dir`, creating the directory as needed
+func Open(dir string) (*Cache, error) {
+	for _, d := range []string{archivesDir, museumsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			return nil, errors.Wrapf(err, "Failed to create cache directory '%s'", dir)
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the root directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the cached entry and body for the museum, source, and path.  The
+// second return value is false if nothing is cached.
+func (c *Cache) Get(museum, source, p string) (*Entry, []byte, bool, error) {
+	key := c.key(museum, source, p)
+
+	e, err := readEntry(key + metaExt)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil, false, nil
+	} else if err != nil {
+		return nil, nil, false, err
+	}
+
+	body, err := ioutil.ReadFile(key + bodyExt)
+	if os.IsNotExist(err) {
+		return nil, nil, false, nil
+	} else if err != nil {
+		return nil, nil, false, errors.Wrapf(err, "Failed to read cached response for '%s' from museum '%s'", p, museum)
+	}
+
+	return e, body, true, nil
+}
+
+// Put stores the body and validators for the museum, source, and path
+func (c *Cache) Put(e *Entry, body []byte) error {
+	key := c.key(e.Museum, e.Source, e.Path)
+	if err := os.MkdirAll(filepath.Dir(key), 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create cache directory for museum '%s'", e.Museum)
+	}
+
+	e.Size = int64(len(body))
+	if err := writeFile(key+bodyExt, body); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode cache entry")
+	}
+	return writeFile(key+metaExt, b)
+}
+
+// Touch records that the cached entry was revalidated
+func (c *Cache) Touch(e *Entry) error {
+	e.Fetched = time.Now().UTC()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode cache entry")
+	}
+	return writeFile(c.key(e.Museum, e.Source, e.Path)+metaExt, b)
+}
+
+// List returns the cached entries, sorted by museum and path
+func (c *Cache) List() ([]*Entry, error) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, museumsDir, "*", "*"+metaExt))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Internal error: failed to list cache")
+	}
+
+	entries := make([]*Entry, 0, len(matches))
+	for _, m := range matches {
+		e, err := readEntry(m)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Museum != entries[j].Museum {
+			return entries[i].Museum < entries[j].Museum
+		}
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// Clear removes the cached entries for each of `museums`.  If no museums are
+// provided, all entries and archives are removed.
+func (c *Cache) Clear(museums ...string) error {
+	dirs := []string{}
+	if len(museums) == 0 {
+		dirs = append(dirs, filepath.Join(c.dir, museumsDir), filepath.Join(c.dir, archivesDir))
+	}
+	for _, m := range museums {
+		dirs = append(dirs, c.museumDir(m))
+	}
+
+	for _, d := range dirs {
+		if err := os.RemoveAll(d); err != nil {
+			return errors.Wrapf(err, "Failed to remove cache directory '%s'", d)
+		}
+	}
+
+	_, err := Open(c.dir)
+	return err
+}
+
+// Prune removes the entries that were last fetched before `before`, and the
+// archives that were last stored or opened before it, and returns the number
+// removed
+func (c *Cache) Prune(before time.Time) (int, error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, e := range entries {
+		if !e.Fetched.Before(before) {
+			continue
+		}
+		if err = e.remove(); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	archives, err := c.Archives()
+	if err != nil {
+		return count, err
+	}
+	for _, a := range archives {
+		if !a.Fetched.Before(before) {
+			continue
+		}
+		if err = os.Remove(c.ArchivePath(a.Digest)); err != nil {
+			return count, errors.Wrapf(err, "Failed to remove archive '%s'", a.Digest)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// key returns the path of the entry's files, without their extension.  The
+// entries of a museum share a directory, so that they are cleared together,
+// whichever server they came from.
+func (c *Cache) key(museum, source, p string) string {
+	h := sha256.Sum256([]byte(source + "\x00" + p))
+	return filepath.Join(c.museumDir(museum), hex.EncodeToString(h[:]))
+}
+
+func (c *Cache) museumDir(museum string) string {
+	return filepath.Join(c.dir, museumsDir, url.PathEscape(museum))
+}
+
+func (e *Entry) remove() error {
+	for _, ext := range []string{bodyExt, metaExt} {
+		if err := os.Remove(e.key + ext); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Failed to remove cached response for '%s' from museum '%s'", e.Path, e.Museum)
+		}
+	}
+	return nil
+}
+
+func readEntry(metaFile string) (*Entry, error) {
+	b, err := ioutil.ReadFile(metaFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read cache entry '%s'", metaFile)
+	}
+
+	e := &Entry{}
+	if err = json.Unmarshal(b, e); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode cache entry '%s'", metaFile)
+	}
+	e.key = strings.TrimSuffix(metaFile, metaExt)
+
+	return e, nil
+}
+
+// writeFile writes to a temporary file and renames it, so that a concurrent
+// reader never sees a partial file
+func writeFile(target string, b []byte) error {
+	return writeFileFrom(target, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+func writeFileFrom(target string, fn func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create cache file for '%s'", target)
+	}
+	defer os.Remove(f.Name())
+
+	err = fn(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write cache file '%s'", target)
+	}
+
+	if err = os.Rename(f.Name(), target); err != nil {
+		return errors.Wrapf(err, "Failed to write cache file '%s'", target)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func openTestCache(t *testing.T) (*Cache, func()) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	c, err := Open(root)
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatalf("Failed to open cache:\n%s", err.Error())
+	}
+	return c, func() { os.RemoveAll(root) }
+}
+
+func Test_Cache_PutGet(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	if _, _, ok, err := c.Get("dev", "http://dev", "/api/charts/foo"); err != nil || ok {
+		t.Fatalf("Expected empty cache; ok: %t, err: %v", ok, err)
+	}
+
+	body := []byte(`[{"name":"foo"}]`)
+	e := &Entry{Museum: "dev", Source: "http://dev", Path: "/api/charts/foo", ETag: `"abc"`, Fetched: time.Now().UTC()}
+	if err := c.Put(e, body); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	tcs := []struct {
+		name   string
+		museum string
+		source string
+		path   string
+		ok     bool
+	}{
+		{name: "hit", museum: "dev", source: "http://dev", path: "/api/charts/foo", ok: true},
+		{name: "other path", museum: "dev", source: "http://dev", path: "/api/charts/bar", ok: false},
+		{name: "other museum", museum: "prod", source: "http://dev", path: "/api/charts/foo", ok: false},
+		{name: "other source", museum: "dev", source: "http://other", path: "/api/charts/foo", ok: false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, b, ok, err := c.Get(tc.museum, tc.source, tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if ok != tc.ok {
+				t.Fatalf("Incorrect hit; expected %t, actual %t", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if !bytes.Equal(b, body) {
+				t.Errorf("Incorrect body; expected '%s', actual '%s'", body, b)
+			}
+			if actual.ETag != e.ETag || actual.Size != int64(len(body)) {
+				t.Errorf("Incorrect entry: %#v", actual)
+			}
+		})
+	}
+}
+
+func Test_Cache_ClearAndPrune(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	old := time.Now().Add(-48 * time.Hour).UTC()
+	c.Put(&Entry{Museum: "dev", Path: "/a", Fetched: old}, []byte("a"))
+	c.Put(&Entry{Museum: "dev", Path: "/b", Fetched: time.Now().UTC()}, []byte("b"))
+	c.Put(&Entry{Museum: "prod", Path: "/a", Fetched: old}, []byte("a"))
+
+	count, err := c.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if count != 2 {
+		t.Errorf("Incorrect prune count; expected 2, actual %d", count)
+	}
+
+	entries, _ := c.List()
+	if len(entries) != 1 || entries[0].Museum != "dev" || entries[0].Path != "/b" {
+		t.Fatalf("Incorrect entries after prune: %#v", entries)
+	}
+
+	if err = c.Clear("dev"); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if entries, _ = c.List(); len(entries) != 0 {
+		t.Errorf("Expected no entries after clear, got %d", len(entries))
+	}
+}
+
+func Test_Cache_Archive(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	archive := []byte("not really a tarball")
+	h := sha256.Sum256(archive)
+	digest := hex.EncodeToString(h[:])
+
+	if err := c.PutArchive("0000", bytes.NewReader(archive)); err == nil {
+		t.Errorf("Expected digest mismatch error")
+	}
+	if _, ok, _ := c.OpenArchive("0000"); ok {
+		t.Errorf("Archive with mismatched digest was stored")
+	}
+
+	if err := c.PutArchive(digest, bytes.NewReader(archive)); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	rc, ok, err := c.OpenArchive(digest)
+	if err != nil || !ok {
+		t.Fatalf("Expected cached archive; ok: %t, err: %v", ok, err)
+	}
+	defer rc.Close()
+	b, _ := ioutil.ReadAll(rc)
+	if !bytes.Equal(b, archive) {
+		t.Errorf("Incorrect archive contents")
+	}
+
+	archives, _ := c.Archives()
+	if len(archives) != 1 || archives[0].Digest != digest {
+		t.Errorf("Incorrect archives: %#v", archives)
+	}
+
+	// Opening the archive keeps it from being pruned.
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(c.ArchivePath(digest), old, old)
+	rc, _, _ = c.OpenArchive(digest)
+	rc.Close()
+	if count, err := c.Prune(time.Now().Add(-24 * time.Hour)); err != nil || count != 0 {
+		t.Errorf("Opened archive was pruned; count: %d, err: %v", count, err)
+	}
+
+	// A corrupted archive is removed rather than returned.
+	if err = ioutil.WriteFile(c.ArchivePath(digest), []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if _, ok, err = c.OpenArchive(digest); err != nil || ok {
+		t.Errorf("Expected corrupted archive to be uncached; ok: %t, err: %v", ok, err)
+	}
+	if archives, _ = c.Archives(); len(archives) != 0 {
+		t.Errorf("Corrupted archive was not removed: %#v", archives)
+	}
+}
+
+func Test_Cache_Archive_InvalidDigest(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	tcs := []string{
+		"",
+		"0000",
+		"../../../../etc/passwd",
+		strings.Repeat("A", 64),
+		strings.Repeat("0", 63) + "/",
+		strings.Repeat("0", 65),
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc, func(t *testing.T) {
+			if err := c.PutArchive(tc, bytes.NewReader(nil)); err == nil {
+				t.Errorf("Expected invalid digest error from PutArchive")
+			}
+			if _, _, err := c.OpenArchive(tc); err == nil {
+				t.Errorf("Expected invalid digest error from OpenArchive")
+			}
+		})
+	}
+}
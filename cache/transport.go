@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+	contentTypeHeader     = "Content-Type"
+)
+
+// Transport is an http.RoundTripper that stores successful GET responses from
+// a museum in the cache, and revalidates them with If-None-Match and
+// If-Modified-Since.  A 404 response is stored as well, so that offline, a
+// path that the museum did not have, such as the provenance file of an
+// unsigned chart, is still not found.  Other requests are passed through
+// unchanged.
+type Transport struct {
+	// Base performs the requests; http.DefaultTransport is used if nil
+	Base http.RoundTripper
+
+	Cache  *Cache
+	Museum string
+
+	// Source identifies the server that the museum names, i.e., its URL; see
+	// Entry
+	Source string
+
+	// Offline answers from the cache only, and fails any request that is not
+	// cached
+	Offline bool
+}
+
+// Offline returns an http.RoundTripper for requests that are never cached,
+// such as archive downloads, which fails each of them as an offline Transport
+// fails a request that is not cached
+func Offline(museum string) http.RoundTripper {
+	return &offline{museum: museum}
+}
+
+type offline struct {
+	museum string
+}
+
+func (o *offline) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, &NotCachedError{Museum: o.museum, Path: req.URL.RequestURI()}
+}
+
+// NotCachedError is returned by an offline Transport when the request is not
+// in the cache
+type NotCachedError struct {
+	Museum string
+	Path   string
+}
+
+func (e *NotCachedError) Error() string {
+	return fmt.Sprintf("Offline, and '%s' from museum '%s' is not cached", e.Path, e.Museum)
+}
+
+// RoundTrip satisfies http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		if t.Offline {
+			return nil, errors.Errorf("Offline, cannot perform '%s %s'", req.Method, req.URL.RequestURI())
+		}
+		return t.base().RoundTrip(req)
+	}
+
+	// The key does not include the host, because the local port of the port
+	// forward changes with each invocation.
+	p := req.URL.RequestURI()
+	e, body, ok, err := t.Cache.Get(t.Museum, t.Source, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Offline {
+		if !ok {
+			return nil, &NotCachedError{Museum: t.Museum, Path: p}
+		}
+		return cachedResponse(req, e, body), nil
+	}
+
+	if ok {
+		req = req.Clone(req.Context())
+		if e.ETag != "" {
+			req.Header.Set(ifNoneMatchHeader, e.ETag)
+		}
+		if e.LastModified != "" {
+			req.Header.Set(ifModifiedSinceHeader, e.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ok && resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		if err = t.Cache.Touch(e); err != nil {
+			return nil, err
+		}
+		return cachedResponse(req, e, body), nil
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusNotFound:
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read response for '%s'", p)
+		}
+		e = &Entry{
+			Museum:       t.Museum,
+			Source:       t.Source,
+			Path:         p,
+			ETag:         resp.Header.Get(etagHeader),
+			LastModified: resp.Header.Get(lastModifiedHeader),
+			ContentType:  resp.Header.Get(contentTypeHeader),
+			Fetched:      time.Now().UTC(),
+		}
+		if resp.StatusCode != http.StatusOK {
+			e.Status = resp.StatusCode
+		}
+		if err = t.Cache.Put(e, b); err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+		return resp, nil
+	default:
+		return resp, nil
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// cachedResponse creates a response to `req` from the cached entry, with the
+// entry's status, or 200
+func cachedResponse(req *http.Request, e *Entry, body []byte) *http.Response {
+	status := http.StatusOK
+	if e.Status != 0 {
+		status = e.Status
+	}
+
+	h := http.Header{}
+	if e.ETag != "" {
+		h.Set(etagHeader, e.ETag)
+	}
+	if e.LastModified != "" {
+		h.Set(lastModifiedHeader, e.LastModified)
+	}
+	if e.ContentType != "" {
+		h.Set(contentTypeHeader, e.ContentType)
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
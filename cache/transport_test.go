@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Cache_Transport(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	hits := 0
+	notModified := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get(ifNoneMatchHeader) == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(etagHeader, `"v1"`)
+		w.Write([]byte("index"))
+	}))
+	defer s.Close()
+
+	get := func(offline bool) (string, error) {
+		client := http.Client{
+			Transport: &Transport{Cache: c, Museum: "dev", Offline: offline},
+		}
+		resp, err := client.Get(s.URL + "/index.yaml")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b), nil
+	}
+
+	if _, err := get(true); err == nil {
+		t.Fatalf("Expected offline request to fail before caching")
+	}
+
+	tcs := []struct {
+		name        string
+		offline     bool
+		hits        int
+		notModified int
+	}{
+		{name: "fetch", hits: 1},
+		{name: "revalidate", hits: 2, notModified: 1},
+		{name: "offline", offline: true, hits: 2, notModified: 1},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := get(tc.offline)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if body != "index" {
+				t.Errorf("Incorrect body; expected 'index', actual '%s'", body)
+			}
+			if hits != tc.hits || notModified != tc.notModified {
+				t.Errorf("Incorrect requests; expected %d (%d not modified), actual %d (%d not modified)", tc.hits, tc.notModified, hits, notModified)
+			}
+		})
+	}
+}
+
+func Test_Cache_Transport_NotFound(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer s.Close()
+
+	get := func(rt http.RoundTripper) (int, error) {
+		client := http.Client{Transport: rt}
+		resp, err := client.Get(s.URL + "/charts/foo-1.0.0.tgz.prov")
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := get(&Transport{Cache: c, Museum: "dev"}); err != nil || code != http.StatusNotFound {
+		t.Fatalf("Incorrect response %d: %v", code, err)
+	}
+	s.Close()
+
+	// Offline, the museum still does not have the file.
+	if code, err := get(&Transport{Cache: c, Museum: "dev", Offline: true}); err != nil || code != http.StatusNotFound {
+		t.Errorf("Incorrect offline response %d: %v", code, err)
+	}
+	if _, err := get(Offline("dev")); err == nil {
+		t.Errorf("Offline request was not refused")
+	}
+}
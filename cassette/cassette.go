@@ -0,0 +1,267 @@
+// Package cassette records the HTTP exchanges between churl and its chart
+// museums into a file, and replays them without a museum.  Cassettes are
+// sanitized as they are recorded: credentials and cookies are removed, and
+// requests are recorded by path, so that the museum's address is not written.
+package cassette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the format of the cassettes written by this package
+const Version = 1
+
+const base64Encoding = "base64"
+
+// sensitiveHeaders are never recorded
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// Cassette is a recorded session.  Its methods are safe to call from several
+// goroutines, so that several museums may share a cassette.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Recorded     time.Time      `json:"recorded"`
+	Interactions []*Interaction `json:"interactions"`
+
+	mu     sync.Mutex
+	path   string
+	played map[*Interaction]bool
+
+	// end is the offset in the file of the end of the last interaction, where
+	// the next one is written; zero if the file must be rewritten first
+	end int64
+}
+
+// Interaction is a request to a museum and its response
+type Interaction struct {
+	Museum   string   `json:"museum"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.  Path includes the query, but not the
+// scheme or host.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// Response is a recorded response.  A body that is not UTF-8, such as a
+// chart archive, is base64 encoded, and Encoding is set.
+type Response struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+// NotRecordedError is returned by a player when the cassette has no
+// interaction for the request
+type NotRecordedError struct {
+	Museum string
+	Method string
+	Path   string
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf("Replaying, and '%s %s' to museum '%s' was not recorded", e.Method, e.Path, e.Museum)
+}
+
+// Create returns an empty cassette that is written to `path`, replacing any
+// existing file.  Each interaction is appended to the file as it is recorded.
+func Create(path string) (*Cassette, error) {
+	c := &Cassette{
+		Version:      Version,
+		Recorded:     time.Now().UTC(),
+		Interactions: []*Interaction{},
+		path:         path,
+	}
+	if err := c.Save(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads the cassette at `path`
+func Load(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read cassette '%s'", path)
+	}
+
+	c := &Cassette{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode cassette '%s'", path)
+	}
+	if c.Version != Version {
+		return nil, errors.Errorf("Cassette '%s' has unsupported version %d; expected %d", path, c.Version, Version)
+	}
+	c.path = path
+
+	return c, nil
+}
+
+// Save writes the cassette to the file it was created with or loaded from
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+// Add appends an interaction, and writes it to the cassette's file, if it has
+// one
+func (c *Cassette) Add(i *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, i)
+	if c.path == "" {
+		return nil
+	}
+	if c.end == 0 {
+		return c.save()
+	}
+	return c.append(i)
+}
+
+// find returns the first interaction for the request that has not been
+// played, or, once they all have been, the last one
+func (c *Cassette) find(museum, method, path string) (*Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.played == nil {
+		c.played = map[*Interaction]bool{}
+	}
+
+	var last *Interaction
+	for _, i := range c.Interactions {
+		if i.Museum != museum || i.Request.Method != method || i.Request.Path != path {
+			continue
+		}
+		if !c.played[i] {
+			c.played[i] = true
+			return i, true
+		}
+		last = i
+	}
+	return last, last != nil
+}
+
+// save writes the cassette; the caller must hold the lock
+func (c *Cassette) save() error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to encode cassette")
+	}
+
+	// Write to a temporary file and rename it, so that an interrupted session
+	// leaves the previous, complete cassette.
+	f, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create cassette '%s'", c.path)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "Failed to write cassette '%s'", c.path)
+	}
+	if err = f.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write cassette '%s'", c.path)
+	}
+	if err = os.Rename(f.Name(), c.path); err != nil {
+		return errors.Wrapf(err, "Failed to write cassette '%s'", c.path)
+	}
+	c.end = int64(len(b)) - int64(len(closing(len(c.Interactions))))
+	return nil
+}
+
+// append writes the last interaction, `i`, over the end of the file, followed
+// by the end of the document, so that the file is complete after each
+// interaction without encoding the earlier ones again; the caller must hold
+// the lock.  The layout is the same as save's.
+func (c *Cassette) append(i *Interaction) error {
+	b, err := json.MarshalIndent(i, "    ", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to encode cassette")
+	}
+	sep := ",\n    "
+	if len(c.Interactions) == 1 {
+		sep = "\n    "
+	}
+	b = append([]byte(sep), b...)
+
+	f, err := os.OpenFile(c.path, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open cassette '%s'", c.path)
+	}
+	if _, err = f.WriteAt(append(b, closing(len(c.Interactions))+"\n"...), c.end); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "Failed to write cassette '%s'", c.path)
+	}
+	if err = f.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write cassette '%s'", c.path)
+	}
+	c.end += int64(len(b))
+	return nil
+}
+
+// closing returns what follows the last interaction in an indented cassette
+// with `n` interactions; an empty list is closed on the same line
+func closing(n int) string {
+	if n == 0 {
+		return "]\n}"
+	}
+	return "\n  ]\n}"
+}
+
+// sanitize returns a copy of `h` without the sensitive headers
+func sanitize(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	h = h.Clone()
+	for _, k := range sensitiveHeaders {
+		h.Del(k)
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
+
+// encodeBody returns the body as a string, and its encoding
+func encodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), base64Encoding
+}
+
+// Bytes returns the decoded body
+func (r *Response) Bytes() ([]byte, error) {
+	switch r.Encoding {
+	case "":
+		return []byte(r.Body), nil
+	case base64Encoding:
+		return base64.StdEncoding.DecodeString(r.Body)
+	default:
+		return nil, errors.Errorf("Unknown body encoding '%s'", r.Encoding)
+	}
+}
@@ -0,0 +1,179 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/object88/churl/churltest"
+)
+
+func Test_Cassette_RecordAndReplay(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	path := filepath.Join(root, "session.json")
+
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	s.SetBasicAuth("admin", "hunter2")
+
+	c, err := Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create cassette:\n%s", err.Error())
+	}
+	client := &http.Client{Transport: c.Recorder("default", nil)}
+
+	paths := []string{"/api/charts/foo", "/charts/foo-1.0.0.tgz", "/api/charts/bar"}
+	recorded := map[string][]byte{}
+	for _, p := range paths {
+		req, _ := http.NewRequest(http.MethodGet, s.URL+p, nil)
+		req.SetBasicAuth("admin", "hunter2")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to request '%s':\n%s", p, err.Error())
+		}
+		recorded[p], _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	s.Close()
+
+	raw, _ := ioutil.ReadFile(path)
+	for _, secret := range []string{"hunter2", "Authorization", s.Listener.Addr().String()} {
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Errorf("Cassette contains '%s'", secret)
+		}
+	}
+
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette:\n%s", err.Error())
+	}
+	client = &http.Client{Transport: c.Player("default")}
+
+	tcs := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "json", path: "/api/charts/foo", expected: http.StatusOK},
+		{name: "archive", path: "/charts/foo-1.0.0.tgz", expected: http.StatusOK},
+		{name: "not found", path: "/api/charts/bar", expected: http.StatusNotFound},
+		{name: "repeated", path: "/api/charts/foo", expected: http.StatusOK},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.Get("http://localhost:0" + tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			defer resp.Body.Close()
+			b, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tc.expected {
+				t.Errorf("Incorrect status; expected %d, actual %d", tc.expected, resp.StatusCode)
+			}
+			if !bytes.Equal(b, recorded[tc.path]) {
+				t.Errorf("Replayed body differs from the recorded body")
+			}
+		})
+	}
+}
+
+func Test_Cassette_Player_NotRecorded(t *testing.T) {
+	c := &Cassette{
+		Version: Version,
+		Interactions: []*Interaction{
+			{
+				Museum:   "default",
+				Request:  Request{Method: http.MethodGet, Path: "/health"},
+				Response: Response{Status: http.StatusOK, Body: `{"healthy":true}`},
+			},
+		},
+	}
+
+	tcs := []struct {
+		name   string
+		museum string
+		path   string
+	}{
+		{name: "other path", museum: "default", path: "/info"},
+		{name: "other museum", museum: "staging", path: "/health"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: c.Player(tc.museum)}
+			_, err := client.Get("http://localhost:0" + tc.path)
+			if err == nil {
+				t.Fatalf("Expected error")
+			}
+			if _, ok := err.(interface{ Unwrap() error }).Unwrap().(*NotRecordedError); !ok {
+				t.Errorf("Expected *NotRecordedError, got %#v", err)
+			}
+		})
+	}
+}
+
+func Test_Cassette_Load_Version(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	path := filepath.Join(root, "session.json")
+	ioutil.WriteFile(path, []byte(`{"version": 99, "interactions": []}`), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Errorf("Expected error for unsupported version")
+	}
+}
+
+func Test_Cassette_Add(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	path := filepath.Join(root, "session.json")
+
+	c, err := Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create cassette:\n%s", err.Error())
+	}
+
+	// Each interaction is appended, and the file is the same as if the whole
+	// cassette had been written.
+	for k, body := range []string{"first", "</second>", string([]byte{0x1f, 0x8b})} {
+		enc, encoding := encodeBody([]byte(body))
+		i := &Interaction{
+			Museum:   "default",
+			Request:  Request{Method: http.MethodGet, Path: "/api/charts"},
+			Response: Response{Status: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: enc, Encoding: encoding},
+		}
+		if err = c.Add(i); err != nil {
+			t.Fatalf("Failed to add interaction %d:\n%s", k, err.Error())
+		}
+
+		expected, _ := json.MarshalIndent(c, "", "  ")
+		actual, _ := ioutil.ReadFile(path)
+		if !bytes.Equal(append(expected, '\n'), actual) {
+			t.Fatalf("Incorrect cassette after %d interactions:\n%s", k+1, actual)
+		}
+	}
+
+	// A loaded cassette is rewritten once, and then appended to.
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette:\n%s", err.Error())
+	}
+	for k := 0; k < 2; k++ {
+		if err = c.Add(&Interaction{Museum: "default", Request: Request{Method: http.MethodHead, Path: "/health"}}); err != nil {
+			t.Fatalf("Failed to add interaction:\n%s", err.Error())
+		}
+	}
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette:\n%s", err.Error())
+	}
+	if len(c.Interactions) != 5 {
+		t.Errorf("Incorrect number of interactions; expected 5, actual %d", len(c.Interactions))
+	}
+}
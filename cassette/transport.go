@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Recorder returns an http.RoundTripper that performs requests with `base`,
+// and adds each response from the museum to the cassette.  If `base` is nil,
+// http.DefaultTransport is used.
+func (c *Cassette) Recorder(museum string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &recorder{
+		c:      c,
+		museum: museum,
+		base:   base,
+	}
+}
+
+// Player returns an http.RoundTripper that answers requests to the museum
+// from the cassette, without contacting it.  Identical requests are answered
+// in the order they were recorded, and the last answer is repeated.
+func (c *Cassette) Player(museum string) http.RoundTripper {
+	return &player{
+		c:      c,
+		museum: museum,
+	}
+}
+
+type recorder struct {
+	c      *Cassette
+	museum string
+	base   http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	p := req.URL.RequestURI()
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read response for '%s'", p)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	body, encoding := encodeBody(b)
+	i := &Interaction{
+		Museum: r.museum,
+		Request: Request{
+			Method: req.Method,
+			Path:   p,
+			Header: sanitize(req.Header),
+		},
+		Response: Response{
+			Status:   resp.StatusCode,
+			Header:   sanitize(resp.Header),
+			Body:     body,
+			Encoding: encoding,
+		},
+	}
+	if err = r.c.Add(i); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type player struct {
+	c      *Cassette
+	museum string
+}
+
+func (p *player) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := req.URL.RequestURI()
+	i, ok := p.c.find(p.museum, req.Method, path)
+	if !ok {
+		return nil, &NotRecordedError{Museum: p.museum, Method: req.Method, Path: path}
+	}
+
+	b, err := i.Response.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode recorded response for '%s'", path)
+	}
+
+	h := i.Response.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set("Content-Length", strconv.Itoa(len(b)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(i.Response.Status) + " " + http.StatusText(i.Response.Status),
+		StatusCode:    i.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package churltest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Chart describes a chart version served by a Server
+type Chart struct {
+	Name        string
+	Version     string
+	AppVersion  string
+	Description string
+
+	// Files are added to the archive, keyed by their path relative to the
+	// chart directory, i.e., `templates/deployment.yaml`.  `Chart.yaml` is
+	// generated from the fields above unless it is provided.
+	Files map[string]string
+
+	// Provenance, if set, is served as the archive's `.prov` file
+	Provenance string
+}
+
+// archiveTime is the modification time of every archived file, so that an
+// archive, and so its digest, depends only on the chart
+var archiveTime = time.Date(2019, time.November, 21, 0, 0, 0, 0, time.UTC)
+
+// Metadata returns the chart's Chart.yaml contents
+func (c *Chart) Metadata() *chart.Metadata {
+	return &chart.Metadata{
+		ApiVersion:  "v1",
+		Name:        c.Name,
+		Version:     c.Version,
+		AppVersion:  c.AppVersion,
+		Description: c.Description,
+	}
+}
+
+// Filename returns the name of the chart's archive
+func (c *Chart) Filename() string {
+	return fmt.Sprintf("%s-%s.tgz", c.Name, c.Version)
+}
+
+// Archive builds the chart's .tgz archive, and returns it with its sha256
+// digest
+func (c *Chart) Archive() ([]byte, string, error) {
+	files := map[string]string{}
+	for k, v := range c.Files {
+		files[k] = v
+	}
+	if _, ok := files["Chart.yaml"]; !ok {
+		b, err := yaml.Marshal(c.Metadata())
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "Failed to encode Chart.yaml for '%s'", c.Name)
+		}
+		files["Chart.yaml"] = string(b)
+	}
+
+	names := make([]string, 0, len(files))
+	for k := range files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.ModTime = archiveTime
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		content := files[name]
+		hdr := &tar.Header{
+			Name:    path.Join(c.Name, name),
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: archiveTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, "", errors.Wrapf(err, "Failed to archive '%s'", hdr.Name)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return nil, "", errors.Wrapf(err, "Failed to archive '%s'", hdr.Name)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", errors.Wrapf(err, "Failed to archive '%s'", c.Name)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", errors.Wrapf(err, "Failed to archive '%s'", c.Name)
+	}
+
+	h := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(h[:]), nil
+}
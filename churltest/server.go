@@ -0,0 +1,371 @@
+// Package churltest provides an in-process chart museum for tests.  It serves
+// the subset of ChartMuseum's API that churl uses from an in-memory set of
+// charts, and can inject errors and latency.
+package churltest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/manifest"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Version is reported by the server's /info endpoint
+const Version = "v0.12.0"
+
+const provenanceExt = ".prov"
+
+// Fault makes the server fail the requests whose path starts with Path
+type Fault struct {
+	Path    string
+	Status  int
+	Message string
+
+	// Times is the number of requests to fail; zero fails every request until
+	// the faults are cleared
+	Times int
+}
+
+// Server is a fake chart museum, listening on a loopback address.  Its methods
+// are safe to call while it is serving requests.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	index    *repo.IndexFile
+	archives map[string][]byte
+	faults   []*Fault
+	latency  time.Duration
+	username string
+	password string
+	requests []string
+}
+
+// NewServer starts a server with the provided charts.  Like
+// httptest.NewServer, it panics if it cannot start.
+func NewServer(charts ...*Chart) *Server {
+	s := &Server{
+		index:    repo.NewIndexFile(),
+		archives: map[string][]byte{},
+	}
+	for _, c := range charts {
+		if err := s.AddChart(c); err != nil {
+			panic(fmt.Sprintf("churltest: %s", err.Error()))
+		}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Museum returns a museum definition that reaches the server directly
+func (s *Server) Museum() *manifest.ChartMuseum {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &manifest.ChartMuseum{
+		URL:      s.URL,
+		Username: s.username,
+		Password: s.password,
+	}
+}
+
+// AddChart adds a chart version to the server
+func (s *Server) AddChart(c *Chart) error {
+	b, digest, err := c.Archive()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index.Add(c.Metadata(), "charts/"+c.Filename(), "", digest)
+	s.index.SortEntries()
+	s.archives[c.Filename()] = b
+	if c.Provenance != "" {
+		s.archives[c.Filename()+provenanceExt] = []byte(c.Provenance)
+	}
+	return nil
+}
+
+// RemoveChart removes a chart version from the server, and reports whether
+// the server had it
+func (s *Server) RemoveChart(name, version string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cvs := s.index.Entries[name]
+	for k, cv := range cvs {
+		if cv.Version != version {
+			continue
+		}
+		if len(cvs) == 1 {
+			delete(s.index.Entries, name)
+		} else {
+			s.index.Entries[name] = append(cvs[:k], cvs[k+1:]...)
+		}
+		filename := (&Chart{Name: name, Version: version}).Filename()
+		delete(s.archives, filename)
+		delete(s.archives, filename+provenanceExt)
+		return true
+	}
+	return false
+}
+
+// Digest returns the digest of a chart version's archive, or an empty string
+func (s *Server) Digest(name, version string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cv, err := s.index.Get(name, version)
+	if err != nil {
+		return ""
+	}
+	return cv.Digest
+}
+
+// Inject adds a fault
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetLatency delays every response
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetBasicAuth requires the credentials on every request.  If both are empty,
+// no credentials are required.
+func (s *Server) SetBasicAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// Requests returns the paths of the requests served so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	latency := s.latency
+	fault := s.takeFault(r.URL.Path)
+	username, password := s.username, s.password
+	s.mu.Unlock()
+
+	if latency != 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fault != nil {
+		writeError(w, fault.Status, fault.Message)
+		return
+	}
+
+	if username != "" || password != "" {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="churltest"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost && (r.URL.Path == "/api/charts" || r.URL.Path == "/api/charts/") {
+		s.upload(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Responses are built and written under the lock, so that charts may be
+	// added or removed while serving.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := r.URL.Path
+	switch {
+	case p == "/health":
+		writeJSON(w, r, map[string]bool{"healthy": true})
+	case p == "/info":
+		writeJSON(w, r, map[string]string{"version": Version})
+	case p == "/index.yaml":
+		b, err := yaml.Marshal(s.index)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeBody(w, r, "application/x-yaml", b)
+	case p == "/api/charts" || p == "/api/charts/":
+		writeJSON(w, r, s.index.Entries)
+	case strings.HasPrefix(p, "/api/charts/"):
+		s.serveChart(w, r, strings.Split(strings.TrimPrefix(p, "/api/charts/"), "/"))
+	case strings.HasPrefix(p, "/charts/"):
+		b, ok := s.archives[strings.TrimPrefix(p, "/charts/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeBody(w, r, "application/x-tar", b)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// upload adds the chart archive in the multipart form's `chart` field, and
+// the provenance file in its `prov` field, like ChartMuseum's `POST
+// /api/charts`.  A chart version that the server already has is rejected.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	f, _, err := r.FormFile("chart")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ch, err := chartutil.LoadArchive(bytes.NewReader(b))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var prov []byte
+	if pf, _, err := r.FormFile("prov"); err == nil {
+		prov, err = ioutil.ReadAll(pf)
+		pf.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	md := ch.Metadata
+	if s.index.Has(md.Name, md.Version) {
+		writeError(w, http.StatusConflict, "file already exists")
+		return
+	}
+	filename := (&Chart{Name: md.Name, Version: md.Version}).Filename()
+	h := sha256.Sum256(b)
+	s.index.Add(md, "charts/"+filename, "", hex.EncodeToString(h[:]))
+	s.index.SortEntries()
+	s.archives[filename] = b
+	if len(prov) != 0 {
+		s.archives[filename+provenanceExt] = prov
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"saved":true}`))
+}
+
+// serveChart serves `/api/charts/NAME[/VERSION]`.  The caller must hold the
+// lock.
+func (s *Server) serveChart(w http.ResponseWriter, r *http.Request, segments []string) {
+	versions, ok := s.index.Entries[segments[0]]
+
+	switch {
+	case len(segments) == 1:
+		if !ok {
+			writeError(w, http.StatusNotFound, "chart not found")
+			return
+		}
+		writeJSON(w, r, versions)
+	case len(segments) == 2:
+		for _, cv := range versions {
+			if cv.Version == segments[1] {
+				writeJSON(w, r, cv)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no chart version found for %s-%s", segments[0], segments[1]))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// takeFault returns the first fault that matches `p`, counting it against its
+// Times.  The caller must hold the lock.
+func (s *Server) takeFault(p string) *Fault {
+	for k, f := range s.faults {
+		if !strings.HasPrefix(p, f.Path) {
+			continue
+		}
+		if f.Times != 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:k], s.faults[k+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeBody(w, r, "application/json", b)
+}
+
+// writeBody writes a 200 response with an ETag, or a 304 response if the
+// request already has the body
+func writeBody(w http.ResponseWriter, r *http.Request, contentType string, b []byte) {
+	h := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(h[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(b)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	b, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,158 @@
+package churltest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+func Test_Server(t *testing.T) {
+	s := NewServer(&Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	tcs := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "health", path: "/health", expected: http.StatusOK},
+		{name: "info", path: "/info", expected: http.StatusOK},
+		{name: "index", path: "/index.yaml", expected: http.StatusOK},
+		{name: "charts", path: "/api/charts", expected: http.StatusOK},
+		{name: "chart", path: "/api/charts/foo", expected: http.StatusOK},
+		{name: "version", path: "/api/charts/foo/1.0.0", expected: http.StatusOK},
+		{name: "archive", path: "/charts/foo-1.0.0.tgz", expected: http.StatusOK},
+		{name: "missing chart", path: "/api/charts/bar", expected: http.StatusNotFound},
+		{name: "missing version", path: "/api/charts/foo/2.0.0", expected: http.StatusNotFound},
+		{name: "missing archive", path: "/charts/foo-2.0.0.tgz", expected: http.StatusNotFound},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(s.URL + tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.expected {
+				t.Errorf("Incorrect status; expected %d, actual %d", tc.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func Test_Server_Fault(t *testing.T) {
+	s := NewServer(&Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	s.Inject(Fault{Path: "/api/", Status: http.StatusServiceUnavailable, Message: "down", Times: 1})
+
+	expected := []int{http.StatusServiceUnavailable, http.StatusOK}
+	for k, e := range expected {
+		resp, err := http.Get(s.URL + "/api/charts/foo")
+		if err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != e {
+			t.Errorf("Request %d: incorrect status; expected %d, actual %d", k, e, resp.StatusCode)
+		}
+	}
+}
+
+func Test_Server_Archive_Digest(t *testing.T) {
+	c := &Chart{Name: "foo", Version: "1.0.0", Files: map[string]string{"values.yaml": "replicas: 1\n"}}
+	_, first, err := c.Archive()
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	_, second, _ := c.Archive()
+	if first != second {
+		t.Errorf("Archive is not reproducible; '%s' != '%s'", first, second)
+	}
+
+	s := NewServer(c)
+	defer s.Close()
+	resp, err := http.Get(s.URL + "/charts/foo-1.0.0.tgz")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	defer resp.Body.Close()
+	if b, _ := ioutil.ReadAll(resp.Body); len(b) == 0 || s.Digest("foo", "1.0.0") != first {
+		t.Errorf("Incorrect archive or digest")
+	}
+}
+
+func Test_Server_RemoveChart(t *testing.T) {
+	s := NewServer(&Chart{Name: "foo", Version: "1.0.0"}, &Chart{Name: "foo", Version: "2.0.0"})
+	defer s.Close()
+
+	if !s.RemoveChart("foo", "1.0.0") {
+		t.Fatalf("Failed to remove 'foo-1.0.0'")
+	}
+	if s.RemoveChart("foo", "1.0.0") {
+		t.Errorf("Removed 'foo-1.0.0' twice")
+	}
+	if s.Digest("foo", "1.0.0") != "" || s.Digest("foo", "2.0.0") == "" {
+		t.Errorf("Incorrect versions after removal")
+	}
+
+	resp, err := http.Get(s.URL + "/charts/foo-1.0.0.tgz")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Incorrect status; expected %d, actual %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func Test_Server_Upload(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	c := &Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"}
+	archive, digest, err := c.Archive()
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	upload := func() int {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		fw, _ := w.CreateFormFile("chart", c.Filename())
+		fw.Write(archive)
+		fw, _ = w.CreateFormFile("prov", c.Filename()+".prov")
+		fw.Write([]byte(c.Provenance))
+		w.Close()
+
+		resp, err := http.Post(s.URL+"/api/charts", w.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := upload(); code != http.StatusCreated {
+		t.Fatalf("Incorrect status; expected %d, actual %d", http.StatusCreated, code)
+	}
+	if s.Digest("foo", "1.0.0") != digest {
+		t.Errorf("Incorrect digest after upload")
+	}
+	resp, err := http.Get(s.URL + "/charts/foo-1.0.0.tgz.prov")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != c.Provenance {
+		t.Errorf("Incorrect provenance '%s'", string(b))
+	}
+
+	if code := upload(); code != http.StatusConflict {
+		t.Errorf("Incorrect status for a repeated upload; expected %d, actual %d", http.StatusConflict, code)
+	}
+}
@@ -0,0 +1,500 @@
+package churl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/bundle"
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/forwarder"
+	"github.com/object88/churl/internal/request"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+const (
+	// provenanceExt is appended to an archive's path to get its provenance
+	// file
+	provenanceExt = ".prov"
+
+	// uploadPath is ChartMuseum's endpoint for adding a chart
+	uploadPath = "api/charts"
+)
+
+// Client accesses a single chart museum.  Unless the museum has a URL, the
+// client opens a port forward to it, which is held until Close.
+type Client struct {
+	name string
+	o    *ClientOptions
+
+	// meta performs the metadata requests, which are cached by museum and
+	// path; archives are fetched with `raw`, and cached by digest instead.
+	meta *request.Request
+	raw  *request.Request
+
+	fw *forwarder.Forwarder
+}
+
+// NewClient connects to the chart museum `cm`, named `name`.  The museum's
+// references are resolved first; a reference that cannot be resolved is a
+// ConfigError.  If the museum is reached through a port forward, NewClient
+// waits until it is ready, or until `ctx` is done.
+func NewClient(ctx context.Context, name string, cm *manifest.ChartMuseum, options ...ClientOption) (*Client, error) {
+	cm, err := cm.Resolve()
+	if err != nil {
+		return nil, &ConfigError{Err: errors.Wrapf(err, "Failed to resolve museum '%s'", name)}
+	}
+
+	o := NewClientOptions()
+	for _, opt := range options {
+		if err := opt(o); err != nil {
+			return nil, errors.Wrapf(err, "Option invalid")
+		}
+	}
+	if o.record != nil && o.replay != nil {
+		return nil, errors.Errorf("Cannot both record and replay museum '%s'", name)
+	}
+	if o.replay != nil {
+		// The cassette is the only source of responses.
+		o.cache = nil
+		o.offline = false
+	}
+	b, err := openBundle(name, cm)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		// A bundle is read from disk, so there is nothing to cache, and it is
+		// available offline.
+		o.cache = nil
+		o.offline = false
+	}
+	if o.offline && o.cache == nil {
+		return nil, errors.Errorf("Offline access to museum '%s' requires a cache", name)
+	}
+
+	c := &Client{
+		name: name,
+		o:    o,
+	}
+
+	baseURL := cm.URL
+	switch {
+	case b != nil:
+		baseURL = "http://bundle"
+	case baseURL != "":
+	case o.offline, o.replay != nil:
+		// No port forward is opened, so there is no local port; the offline
+		// and replay transports never dial.
+		baseURL = "http://localhost:0"
+	default:
+		port, err := c.forward(ctx, cm)
+		if err != nil {
+			return nil, err
+		}
+		baseURL = fmt.Sprintf("http://localhost:%s", port)
+	}
+
+	c.meta, err = c.newRequest(baseURL, cm)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.raw, err = c.newRequest(baseURL, cm)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	if b != nil {
+		c.meta.Transport = b
+		c.raw.Transport = b
+	}
+
+	if o.cache != nil {
+		c.meta.Transport = &cache.Transport{
+			Base:    c.meta.Transport,
+			Cache:   o.cache,
+			Museum:  name,
+			Source:  cacheSource(o.kubeFlags, cm),
+			Offline: o.offline,
+		}
+	}
+	if o.offline {
+		// Archives are cached by digest rather than by path, so `raw` cannot
+		// answer from the cache; it must not reach the museum either, which
+		// has a real address if it has a URL.
+		c.raw.Transport = cache.Offline(name)
+	}
+
+	// The recorder is outermost, so that it sees what the client sees, even if
+	// the cache answered.
+	switch {
+	case o.replay != nil:
+		c.meta.Transport = o.replay.Player(name)
+		c.raw.Transport = o.replay.Player(name)
+	case o.record != nil:
+		c.meta.Transport = o.record.Recorder(name, c.meta.Transport)
+		c.raw.Transport = o.record.Recorder(name, c.raw.Transport)
+	}
+
+	return c, nil
+}
+
+// Name returns the name of the museum
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Close stops the port forward, if one is open
+func (c *Client) Close() error {
+	if c == nil || c.fw == nil {
+		return nil
+	}
+	err := c.fw.Close()
+	c.fw = nil
+	return err
+}
+
+// Versions returns every version of the chart, newest first
+func (c *Client) Versions(ctx context.Context, chart string) ([]*repo.ChartVersion, error) {
+	cvs := []*repo.ChartVersion{}
+	err := c.getJSON(ctx, fmt.Sprintf("api/charts/%s", chart), &cvs, &NotFoundError{Museum: c.name, Chart: chart})
+	if err != nil {
+		return nil, err
+	}
+	return cvs, nil
+}
+
+// Latest returns the newest version of the chart
+func (c *Client) Latest(ctx context.Context, chart string) (*repo.ChartVersion, error) {
+	cvs, err := c.Versions(ctx, chart)
+	if err != nil {
+		return nil, err
+	}
+	if len(cvs) == 0 {
+		return nil, &NotFoundError{Museum: c.name, Chart: chart}
+	}
+	return cvs[0], nil
+}
+
+// Get returns a specific version of the chart
+func (c *Client) Get(ctx context.Context, chart, version string) (*repo.ChartVersion, error) {
+	cv := &repo.ChartVersion{}
+	err := c.getJSON(ctx, fmt.Sprintf("api/charts/%s/%s", chart, version), cv, &NotFoundError{Museum: c.name, Chart: chart, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	return cv, nil
+}
+
+// Index returns the museum's repository index
+func (c *Client) Index(ctx context.Context) (*repo.IndexFile, error) {
+	b, err := c.getBytes(ctx, c.meta, "index.yaml", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &repo.IndexFile{}
+	if err = yaml.Unmarshal(b, i); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode index from museum '%s'", c.name)
+	}
+	i.SortEntries()
+
+	return i, nil
+}
+
+// Download returns the archive of a version of the chart, or of the newest
+// version if `version` is empty, as DownloadVersion does
+func (c *Client) Download(ctx context.Context, chart, version string) (io.ReadCloser, *repo.ChartVersion, error) {
+	var cv *repo.ChartVersion
+	var err error
+	if version == "" {
+		cv, err = c.Latest(ctx, chart)
+	} else {
+		cv, err = c.Get(ctx, chart, version)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := c.DownloadVersion(ctx, cv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, cv, nil
+}
+
+// DownloadVersion returns the archive of the chart version `cv`, i.e., an
+// entry from the museum's index.  With a cache, archives are stored by digest,
+// and the digest is verified both when the archive is stored and each time it is
+// read back from the cache.
+func (c *Client) DownloadVersion(ctx context.Context, cv *repo.ChartVersion) (io.ReadCloser, error) {
+	if len(cv.URLs) == 0 {
+		return nil, errors.Errorf("Chart '%s' version '%s' in museum '%s' has no archive URL", cv.Name, cv.Version, c.name)
+	}
+
+	if c.o.cache != nil && cv.Digest != "" {
+		rc, ok, err := c.o.cache.OpenArchive(cv.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rc, nil
+		}
+		if c.o.offline {
+			return nil, &cache.NotCachedError{Museum: c.name, Path: cv.URLs[0]}
+		}
+	}
+
+	// ChartMuseum may be configured to advertise absolute URLs; the archive is
+	// still fetched through this client's connection.
+	p := c.raw.Relative(cv.URLs[0])
+
+	rc, err := c.get(ctx, c.raw, p, &NotFoundError{Museum: c.name, Chart: cv.Name, Version: cv.Version})
+	if err != nil {
+		return nil, err
+	}
+	if c.o.cache == nil || cv.Digest == "" {
+		return rc, nil
+	}
+
+	err = c.o.cache.PutArchive(cv.Digest, rc)
+	rc.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to store archive for chart '%s' version '%s' from museum '%s'", cv.Name, cv.Version, c.name)
+	}
+
+	rc, _, err = c.o.cache.OpenArchive(cv.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Provenance returns the provenance file of the chart version `cv`, i.e., an
+// entry from the museum's index.  A chart version that was not signed has no
+// provenance file, and is reported as a *NotFoundError.
+func (c *Client) Provenance(ctx context.Context, cv *repo.ChartVersion) ([]byte, error) {
+	if len(cv.URLs) == 0 {
+		return nil, errors.Errorf("Chart '%s' version '%s' in museum '%s' has no archive URL", cv.Name, cv.Version, c.name)
+	}
+
+	p := c.meta.Relative(cv.URLs[0])
+
+	// Provenance files are small, and are cached by path like the metadata, so
+	// that they are available offline.
+	return c.getBytes(ctx, c.meta, p+provenanceExt, &NotFoundError{Museum: c.name, Chart: cv.Name, Version: cv.Version})
+}
+
+// Upload adds a chart archive, and its provenance file if `prov` is not
+// empty, to the museum.  Unlike the other methods, Upload modifies the museum;
+// a museum that already has the chart version rejects it, unless it allows
+// overwrites.
+func (c *Client) Upload(ctx context.Context, filename string, archive, prov []byte) error {
+	if c.o.offline {
+		return errors.Errorf("Offline, cannot upload '%s' to museum '%s'", filename, c.name)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	parts := []struct {
+		field, filename string
+		b               []byte
+	}{
+		{field: "chart", filename: filename, b: archive},
+		{field: "prov", filename: filename + provenanceExt, b: prov},
+	}
+	for _, part := range parts {
+		if len(part.b) == 0 {
+			continue
+		}
+		pw, err := w.CreateFormFile(part.field, part.filename)
+		if err == nil {
+			_, err = pw.Write(part.b)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode upload of '%s'", filename)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode upload of '%s'", filename)
+	}
+
+	rc, code, err := c.raw.Post(ctx, uploadPath, w.FormDataContentType(), &body)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to upload '%s' to museum '%s'", filename, c.name)
+	}
+	defer rc.Close()
+	if code == http.StatusCreated || code == http.StatusOK {
+		return nil
+	}
+	return responseError(c.name, c.raw.URL(uploadPath), code, rc, nil)
+}
+
+// Load downloads the archive of a version of the chart, or of the newest
+// version if `version` is empty, and loads it
+func (c *Client) Load(ctx context.Context, chartname, version string) (*chart.Chart, *repo.ChartVersion, error) {
+	rc, cv, err := c.Download(ctx, chartname, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	ch, err := c.loadArchive(rc, cv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, cv, nil
+}
+
+// LoadVersion downloads the archive of the chart version `cv`, i.e., an entry
+// from the museum's index, and loads it
+func (c *Client) LoadVersion(ctx context.Context, cv *repo.ChartVersion) (*chart.Chart, error) {
+	rc, err := c.DownloadVersion(ctx, cv)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return c.loadArchive(rc, cv)
+}
+
+func (c *Client) loadArchive(r io.Reader, cv *repo.ChartVersion) (*chart.Chart, error) {
+	ch, err := chartutil.LoadArchive(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to load archive for chart '%s' version '%s' from museum '%s'", cv.Name, cv.Version, c.name)
+	}
+	return ch, nil
+}
+
+// Raw performs a GET or HEAD request for `query`, i.e., `api/charts?offset=10`,
+// relative to the museum, and returns the response.  Unlike the other methods,
+// the response is not cached.  A response other than 2xx is returned with the
+// corresponding typed error, and its body can still be read; the caller must
+// close the body in either case.
+func (c *Client) Raw(ctx context.Context, method, query string) (*http.Response, error) {
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, errors.Errorf("Method '%s' is not allowed; only GET and HEAD requests are performed", method)
+	}
+	if c.o.offline {
+		return nil, errors.Errorf("Offline, cannot request '%s' from museum '%s'", query, c.name)
+	}
+
+	resp, err := c.raw.Do(ctx, method, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to query museum '%s'", c.name)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	// The body is kept, so that the caller sees the museum's message, and the
+	// error is built from it as well.
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read '%s' from museum '%s'", query, c.name)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return resp, responseError(c.name, c.raw.URL(query), resp.StatusCode, bytes.NewReader(b), nil)
+}
+
+// Health returns an error if the museum does not report that it is healthy
+func (c *Client) Health(ctx context.Context) error {
+	h := struct {
+		Healthy bool `json:"healthy"`
+	}{}
+	rc, err := c.get(ctx, c.raw, "health", nil)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err = json.NewDecoder(rc).Decode(&h); err != nil {
+		return errors.Wrapf(err, "Failed to decode health of museum '%s'", c.name)
+	}
+	if !h.Healthy {
+		return errors.Errorf("Museum '%s' is not healthy", c.name)
+	}
+	return nil
+}
+
+// openBundle opens the bundle of a museum whose URL is a `file:` URL, such as
+// `file:///srv/prod.tgz`; other museums have no bundle.
+func openBundle(name string, cm *manifest.ChartMuseum) (*bundle.Bundle, error) {
+	u, err := url.Parse(cm.URL)
+	if err != nil || u.Scheme != "file" {
+		return nil, nil
+	}
+	p := u.Path
+	if u.Opaque != "" {
+		p = u.Opaque
+	}
+	b, err := bundle.Open(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open museum '%s'", name)
+	}
+	return b, nil
+}
+
+func (c *Client) newRequest(baseURL string, cm *manifest.ChartMuseum) (*request.Request, error) {
+	req, err := request.NewRequest(baseURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create request for museum '%s'", c.name)
+	}
+	req.Transport = c.o.transport
+	req.SetBasicAuth(cm.Username, cm.Password)
+	return req, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, query string, v interface{}, notFound *NotFoundError) error {
+	b, err := c.getBytes(ctx, c.meta, query, notFound)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "Failed to decode '%s' from museum '%s'", query, c.name)
+	}
+	return nil
+}
+
+func (c *Client) getBytes(ctx context.Context, req *request.Request, query string, notFound *NotFoundError) ([]byte, error) {
+	rc, err := c.get(ctx, req, query, notFound)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read '%s' from museum '%s'", query, c.name)
+	}
+	return b, nil
+}
+
+// get performs a GET request, and returns the body of a 200 response.  A 404
+// response is reported as `notFound`, if provided; other responses are
+// reported as the corresponding typed error.
+func (c *Client) get(ctx context.Context, req *request.Request, query string, notFound *NotFoundError) (io.ReadCloser, error) {
+	rc, code, err := req.Get(ctx, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to query museum '%s'", c.name)
+	}
+	if code == http.StatusOK {
+		return rc, nil
+	}
+	defer rc.Close()
+
+	return nil, responseError(c.name, req.URL(query), code, rc, notFound)
+}
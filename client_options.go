@@ -0,0 +1,135 @@
+package churl
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cassette"
+	"github.com/object88/churl/forwarder"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// ClientOption configures a Client
+type ClientOption func(o *ClientOptions) error
+
+// ClientOptions holds the configuration of a Client
+type ClientOptions struct {
+	kubeFlags  *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	backend    forwarder.Backend
+
+	err io.Writer
+	out io.Writer
+
+	cache     *cache.Cache
+	offline   bool
+	transport http.RoundTripper
+
+	record *cassette.Cassette
+	replay *cassette.Cassette
+}
+
+// NewClientOptions returns the default client configuration
+func NewClientOptions() *ClientOptions {
+	return &ClientOptions{
+		kubeFlags: genericclioptions.NewConfigFlags(false),
+		err:       ioutil.Discard,
+		out:       ioutil.Discard,
+	}
+}
+
+// KubeFlags sets the Kubernetes client configuration used to open the port
+// forward.  The museum's kube context is used unless `kf` sets one.
+func KubeFlags(kf *genericclioptions.ConfigFlags) ClientOption {
+	return func(o *ClientOptions) error {
+		if kf != nil {
+			o.kubeFlags = kf
+		}
+		return nil
+	}
+}
+
+// Backend sets how the port forward finds the museum's pod and tunnels to it,
+// in place of the Kubernetes API server that KubeFlags configures
+func Backend(b forwarder.Backend) ClientOption {
+	return func(o *ClientOptions) error {
+		o.backend = b
+		return nil
+	}
+}
+
+// PodTimeout sets how long to wait for a running museum pod
+func PodTimeout(t time.Duration) ClientOption {
+	return func(o *ClientOptions) error {
+		o.podTimeout = t
+		return nil
+	}
+}
+
+// Err sets the writer for the port forward's error messages
+func Err(w io.Writer) ClientOption {
+	return func(o *ClientOptions) error {
+		if w == nil {
+			w = ioutil.Discard
+		}
+		o.err = w
+		return nil
+	}
+}
+
+// Out sets the writer for the port forward's status messages
+func Out(w io.Writer) ClientOption {
+	return func(o *ClientOptions) error {
+		if w == nil {
+			w = ioutil.Discard
+		}
+		o.out = w
+		return nil
+	}
+}
+
+// Cache stores responses and chart archives in `c`
+func Cache(c *cache.Cache) ClientOption {
+	return func(o *ClientOptions) error {
+		o.cache = c
+		return nil
+	}
+}
+
+// Offline answers every request from the cache, without opening a port
+// forward or contacting the museum
+func Offline(offline bool) ClientOption {
+	return func(o *ClientOptions) error {
+		o.offline = offline
+		return nil
+	}
+}
+
+// Transport sets the http.RoundTripper used to reach the museum;
+// http.DefaultTransport is used if it is not set
+func Transport(rt http.RoundTripper) ClientOption {
+	return func(o *ClientOptions) error {
+		o.transport = rt
+		return nil
+	}
+}
+
+// Record adds every request to the museum, and its response, to the cassette
+func Record(c *cassette.Cassette) ClientOption {
+	return func(o *ClientOptions) error {
+		o.record = c
+		return nil
+	}
+}
+
+// Replay answers every request from the cassette, without opening a port
+// forward or contacting the museum.  The cache is not used.
+func Replay(c *cassette.Cassette) ClientOption {
+	return func(o *ClientOptions) error {
+		o.replay = c
+		return nil
+	}
+}
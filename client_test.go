@@ -0,0 +1,560 @@
+package churl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/object88/churl/bundle"
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cassette"
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/forwarder"
+	"github.com/object88/churl/forwarder/forwardertest"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newTestServer() *churltest.Server {
+	return churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "0.9.0"},
+		&churltest.Chart{Name: "foo", Version: "1.0.0"},
+	)
+}
+
+func Test_Client(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, "test", s.Museum())
+	if err != nil {
+		t.Fatalf("Failed to create client:\n%s", err.Error())
+	}
+	defer c.Close()
+
+	tcs := []struct {
+		name string
+		fn   func() error
+	}{
+		{
+			name: "latest",
+			fn: func() error {
+				cv, err := c.Latest(ctx, "foo")
+				if err == nil && cv.Version != "1.0.0" {
+					err = errors.Errorf("Incorrect version '%s'", cv.Version)
+				}
+				return err
+			},
+		},
+		{
+			name: "get",
+			fn: func() error {
+				_, err := c.Get(ctx, "foo", "1.0.0")
+				return err
+			},
+		},
+		{
+			name: "index",
+			fn: func() error {
+				i, err := c.Index(ctx)
+				if err == nil && !i.Has("foo", "1.0.0") {
+					err = errors.Errorf("Index is missing foo 1.0.0")
+				}
+				return err
+			},
+		},
+		{
+			name: "download",
+			fn: func() error {
+				rc, _, err := c.Download(ctx, "foo", "")
+				if err != nil {
+					return err
+				}
+				defer rc.Close()
+				b, _ := ioutil.ReadAll(rc)
+				h := sha256.Sum256(b)
+				if actual := hex.EncodeToString(h[:]); actual != s.Digest("foo", "1.0.0") {
+					return errors.Errorf("Incorrect archive digest '%s'", actual)
+				}
+				return nil
+			},
+		},
+		{
+			name: "load",
+			fn: func() error {
+				ch, _, err := c.Load(ctx, "foo", "0.9.0")
+				if err == nil && ch.Metadata.Version != "0.9.0" {
+					err = errors.Errorf("Incorrect loaded version '%s'", ch.Metadata.Version)
+				}
+				return err
+			},
+		},
+		{
+			name: "load version",
+			fn: func() error {
+				i, err := c.Index(ctx)
+				if err != nil {
+					return err
+				}
+				ch, err := c.LoadVersion(ctx, i.Entries["foo"][0])
+				if err == nil && ch.Metadata.Version != "1.0.0" {
+					err = errors.Errorf("Incorrect loaded version '%s'", ch.Metadata.Version)
+				}
+				return err
+			},
+		},
+		{
+			name: "health",
+			fn: func() error {
+				return c.Health(ctx)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.fn(); err != nil {
+				t.Errorf("Unexpected error:\n%s", err.Error())
+			}
+		})
+	}
+}
+
+func Test_Client_NotFound(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+
+	_, err := c.Latest(ctx, "bar")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("Expected *NotFoundError, got %#v", err)
+	}
+}
+
+func Test_Client_Offline(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	ch, _ := cache.Open(root)
+
+	s := newTestServer()
+	cm := s.Museum()
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", cm, Cache(ch))
+	if _, err := c.Latest(ctx, "foo"); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	rc, _, err := c.Download(ctx, "foo", "1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	rc.Close()
+	c.Close()
+	s.Close()
+
+	// The museum's responses are cached for its server, so that a museum of
+	// the same name that names another server does not answer from them.
+	other, _ := NewClient(ctx, "test", &manifest.ChartMuseum{URL: "http://other"}, Cache(ch), Offline(true))
+	if _, err = other.Latest(ctx, "foo"); err == nil {
+		t.Errorf("Expected error for a query cached from another server")
+	}
+	other.Close()
+
+	c, err = NewClient(ctx, "test", cm, Cache(ch), Offline(true))
+	if err != nil {
+		t.Fatalf("Failed to create offline client:\n%s", err.Error())
+	}
+	defer c.Close()
+
+	if _, err = c.Latest(ctx, "foo"); err != nil {
+		t.Errorf("Unexpected error from cached query:\n%s", err.Error())
+	}
+	if rc, _, err = c.Download(ctx, "foo", "1.0.0"); err != nil {
+		t.Errorf("Unexpected error from cached download:\n%s", err.Error())
+	} else {
+		rc.Close()
+	}
+	if _, err = c.Latest(ctx, "bar"); err == nil {
+		t.Errorf("Expected error for uncached query")
+	}
+}
+
+func Test_Client_Provenance(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"},
+		&churltest.Chart{Name: "foo", Version: "0.9.0"},
+	)
+	defer s.Close()
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+
+	i, err := c.Index(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	b, err := c.Provenance(ctx, i.Entries["foo"][0])
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if string(b) != "signature" {
+		t.Errorf("Incorrect provenance '%s'", string(b))
+	}
+	if _, err = c.Provenance(ctx, i.Entries["foo"][1]); KindOf(err) != KindNotFound {
+		t.Errorf("Expected not found for an unsigned chart, got %v", err)
+	}
+}
+
+func Test_Client_Upload(t *testing.T) {
+	s := churltest.NewServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+
+	ch := &churltest.Chart{Name: "foo", Version: "1.0.0"}
+	archive, digest, err := ch.Archive()
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err = c.Upload(ctx, ch.Filename(), archive, []byte("signature")); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if s.Digest("foo", "1.0.0") != digest {
+		t.Errorf("Chart was not uploaded")
+	}
+
+	err = c.Upload(ctx, ch.Filename(), archive, nil)
+	if herr, ok := AsError(err).(HTTPError); !ok || herr.StatusCode() != 409 {
+		t.Errorf("Expected a conflict for a repeated upload, got %v", err)
+	}
+}
+
+func Test_Client_Errors(t *testing.T) {
+	tcs := []struct {
+		name     string
+		setup    func(s *churltest.Server)
+		museum   func(s *churltest.Server) *manifest.ChartMuseum
+		expected ErrorKind
+		status   int
+	}{
+		{
+			name: "server error",
+			setup: func(s *churltest.Server) {
+				s.Inject(churltest.Fault{Path: "/api/charts", Status: 500, Message: "storage unavailable"})
+			},
+			expected: KindServerError,
+			status:   500,
+		},
+		{
+			name: "unauthorized",
+			setup: func(s *churltest.Server) {
+				s.SetBasicAuth("admin", "secret")
+			},
+			museum: func(s *churltest.Server) *manifest.ChartMuseum {
+				cm := s.Museum()
+				cm.Password = "wrong"
+				return cm
+			},
+			expected: KindUnauthorized,
+			status:   401,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer()
+			defer s.Close()
+			tc.setup(s)
+
+			cm := s.Museum()
+			if tc.museum != nil {
+				cm = tc.museum(s)
+			}
+
+			ctx := context.Background()
+			c, _ := NewClient(ctx, "test", cm)
+			defer c.Close()
+
+			_, err := c.Latest(ctx, "foo")
+			if actual := KindOf(err); actual != tc.expected {
+				t.Fatalf("Incorrect kind; expected '%s', actual '%s' (%v)", tc.expected, actual, err)
+			}
+			herr := AsError(err).(HTTPError)
+			if herr.StatusCode() != tc.status || herr.RequestURL() != s.URL+"/api/charts/foo" {
+				t.Errorf("Incorrect status or URL; status %d, URL '%s'", herr.StatusCode(), herr.RequestURL())
+			}
+		})
+	}
+}
+
+func Test_Client_Context(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	s.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+
+	if _, err := c.Latest(ctx, "foo"); err == nil {
+		t.Errorf("Expected the request to be cancelled")
+	}
+}
+
+func Test_Client_Forward(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	labels := map[string]string{"app": "chartmuseum"}
+	pod := forwardertest.RunningPod("charts", "cm-0", labels, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+	cluster := forwardertest.NewCluster(pod)
+	defer cluster.Close()
+	cluster.Targets[8080] = s.Listener.Addr().String()
+	b := forwarder.NewKubeBackendWithDialer(cluster.Factory(), cluster.Dialer)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, "test", &manifest.ChartMuseum{Namespace: "charts", ServiceName: "cm-0", Port: "http"}, Backend(b), PodTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to create client:\n%s", err.Error())
+	}
+	defer c.Close()
+
+	if _, err := c.Latest(ctx, "foo"); err != nil {
+		t.Errorf("Unexpected error:\n%s", err.Error())
+	}
+
+	_, err = NewClient(ctx, "test", &manifest.ChartMuseum{Namespace: "charts", ServiceName: "svc/cm", Port: "80"}, Backend(b))
+	if actual := KindOf(err); actual != KindForwardFailed {
+		t.Errorf("Incorrect kind for missing service; expected '%s', actual '%s'", KindForwardFailed, actual)
+	}
+
+	pending := forwardertest.RunningPod("charts", "cm-1", labels, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+	pending.Status.Phase = v1.PodPending
+	cluster.Clientset.CoreV1().Pods("charts").Create(pending)
+	_, err = NewClient(ctx, "test", &manifest.ChartMuseum{Namespace: "charts", ServiceName: "cm-1", Port: "http"}, Backend(b), PodTimeout(50*time.Millisecond))
+	if actual := KindOf(err); actual != KindPodNotReady {
+		t.Errorf("Incorrect kind for pending pod; expected '%s', actual '%s'", KindPodNotReady, actual)
+	}
+}
+
+func Test_Client_Replay(t *testing.T) {
+	// latest.json was recorded with `--record` against a museum with foo 0.9.0
+	// and 1.0.0
+	cs, err := cassette.Load("testdata/cassettes/latest.json")
+	if err != nil {
+		t.Fatalf("Failed to load cassette:\n%s", err.Error())
+	}
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, "default", &manifest.ChartMuseum{ServiceName: "unreachable"}, Replay(cs))
+	if err != nil {
+		t.Fatalf("Failed to create client:\n%s", err.Error())
+	}
+	defer c.Close()
+
+	cv, err := c.Latest(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if cv.Version != "1.0.0" {
+		t.Errorf("Incorrect version '%s'", cv.Version)
+	}
+
+	rc, _, err := c.Download(ctx, "foo", "1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error from download:\n%s", err.Error())
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	h := sha256.Sum256(b)
+	if actual := hex.EncodeToString(h[:]); actual != cv.Digest {
+		t.Errorf("Incorrect archive digest '%s'", actual)
+	}
+
+	if _, err = c.Latest(ctx, "bar"); KindOf(err) != KindNotFound {
+		t.Errorf("Expected not-found error, got %v", err)
+	}
+	if err = c.Health(ctx); err == nil {
+		t.Errorf("Expected error for unrecorded request")
+	}
+}
+
+func Test_Client_Bundle(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+	i, err := c.Index(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	p := filepath.Join(root, "bundle.tgz")
+	f, _ := os.Create(p)
+	w := bundle.NewWriter(f, "test", time.Now())
+	for _, cv := range i.Entries["foo"] {
+		rc, err := c.DownloadVersion(ctx, cv)
+		if err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		archive, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if err = w.Add(cv, archive, nil); err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+	}
+	w.Close()
+	f.Close()
+	s.Close()
+
+	// The bundle is served without the museum, and without a cache.
+	bc, err := NewClient(ctx, "bundle", &manifest.ChartMuseum{URL: "file://" + p}, Offline(true))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	defer bc.Close()
+	if err = bc.Health(ctx); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	ch, cv, err := bc.Load(ctx, "foo", "")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if cv.Version != "1.0.0" || ch.Metadata.Version != "1.0.0" {
+		t.Errorf("Incorrect chart version '%s'", cv.Version)
+	}
+	if _, err = bc.Get(ctx, "bar", "1.0.0"); KindOf(err) != KindNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if err = bc.Upload(ctx, "foo-2.0.0.tgz", []byte("archive"), nil); err == nil {
+		t.Errorf("Upload to a bundle succeeded")
+	}
+
+	if _, err = NewClient(ctx, "missing", &manifest.ChartMuseum{URL: "file://" + filepath.Join(root, "missing.tgz")}); err == nil {
+		t.Errorf("Missing bundle was opened")
+	}
+}
+
+func Test_Client_Raw(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum())
+	defer c.Close()
+
+	resp, err := c.Raw(ctx, http.MethodGet, "health?verbose=true")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.RawQuery != "verbose=true" {
+		t.Errorf("Incorrect response %d for '%s'", resp.StatusCode, resp.Request.URL)
+	}
+
+	resp, err = c.Raw(ctx, http.MethodGet, "api/charts/bar")
+	if KindOf(err) != KindNotFound {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(b) == 0 {
+		t.Errorf("Body of the unsuccessful response was not kept")
+	}
+
+	if _, err = c.Raw(ctx, http.MethodDelete, "api/charts/foo/1.0.0"); err == nil {
+		t.Errorf("DELETE request was performed")
+	}
+	if s.Digest("foo", "1.0.0") == "" {
+		t.Errorf("Chart was deleted")
+	}
+}
+
+func Test_Client_OfflineURL(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	ch, _ := cache.Open(root)
+
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"},
+		&churltest.Chart{Name: "foo", Version: "0.9.0"},
+	)
+	defer s.Close()
+
+	ctx := context.Background()
+	c, _ := NewClient(ctx, "test", s.Museum(), Cache(ch))
+	i, err := c.Index(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	for _, cv := range i.Entries["foo"] {
+		c.Provenance(ctx, cv)
+	}
+	c.Close()
+
+	// The museum is still up, but an offline client must not reach it.
+	requests := len(s.Requests())
+	c, err = NewClient(ctx, "test", s.Museum(), Cache(ch), Offline(true))
+	if err != nil {
+		t.Fatalf("Failed to create offline client:\n%s", err.Error())
+	}
+	defer c.Close()
+
+	if b, err := c.Provenance(ctx, i.Entries["foo"][0]); err != nil || string(b) != "signature" {
+		t.Errorf("Incorrect cached provenance '%s': %v", string(b), err)
+	}
+	if _, err = c.Provenance(ctx, i.Entries["foo"][1]); KindOf(err) != KindNotFound {
+		t.Errorf("Expected not found for an unsigned chart, got %v", err)
+	}
+	if err = c.Health(ctx); !isNotCached(err) {
+		t.Errorf("Expected not cached for the health check, got %v", err)
+	}
+	cv := *i.Entries["foo"][0]
+	cv.Digest = ""
+	if _, err = c.DownloadVersion(ctx, &cv); !isNotCached(err) {
+		t.Errorf("Expected not cached for a download without a digest, got %v", err)
+	}
+	if actual := len(s.Requests()); actual != requests {
+		t.Errorf("Offline client sent %d requests to the museum", actual-requests)
+	}
+}
+
+// isNotCached reports whether `err` is caused by a *cache.NotCachedError,
+// which the HTTP client wraps in a *url.Error
+func isNotCached(err error) bool {
+	for err != nil {
+		if _, ok := err.(*cache.NotCachedError); ok {
+			return true
+		}
+		switch t := err.(type) {
+		case interface{ Cause() error }:
+			err = t.Cause()
+		case interface{ Unwrap() error }:
+			err = t.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"github.com/object88/churl/cmd/cache/clear"
+	"github.com/object88/churl/cmd/cache/list"
+	"github.com/object88/churl/cmd/cache/prune"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+}
+
+// CreateCommand returns the intermediate 'cache' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "cache",
+			Short: "cache subcommands will manage the local cache of museum responses",
+			Long: `cache subcommands will manage the local cache of museum responses.
+
+Responses are cached under the user cache directory, or $CHURL_CACHE_DIR if
+set, and are revalidated with the museum on each request.  With --offline,
+requests are answered from the cache without opening a port forward.`,
+		},
+		CommonArgs: ca,
+	}
+
+	c.AddCommand(
+		clear.CreateCommand(ca),
+		list.CreateCommand(ca),
+		prune.CreateCommand(ca),
+	)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
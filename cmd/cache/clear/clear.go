@@ -0,0 +1,45 @@
+package clear
+
+import (
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	c *cache.Cache
+}
+
+// CreateCommand returns the 'clear' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "clear [MUSEUM...]",
+			Short: "removes the cached responses for the named museums, or everything in the cache",
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.c, err = c.OpenCache()
+	return err
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	return c.c.Clear(args...)
+}
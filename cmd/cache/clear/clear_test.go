@@ -0,0 +1,57 @@
+//+build test_integration
+
+package clear
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/object88/churl/cache"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Cache_Clear(t *testing.T) {
+	fixture := ctesting.Config(t)
+	defer fixture.Close()
+	dir := fixture.Cache
+
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	for _, museum := range []string{"dev", "prod"} {
+		if err = c.Put(&cache.Entry{Museum: museum, Path: "/index.yaml", Fetched: time.Now().UTC()}, []byte("body")); err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+	}
+	archive := []byte("archive")
+	h := sha256.Sum256(archive)
+	if err = c.PutArchive(hex.EncodeToString(h[:]), bytes.NewReader(archive)); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	// Clearing one museum keeps the others, and the archives.
+	if _, exitCode := ctesting.RunChurl(t, "cache", "clear", "dev"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	entries, _ := c.List()
+	if len(entries) != 1 || entries[0].Museum != "prod" {
+		t.Errorf("Incorrect entries after clearing 'dev': %#v", entries)
+	}
+	if archives, _ := c.Archives(); len(archives) != 1 {
+		t.Errorf("Archives were removed with museum 'dev': %#v", archives)
+	}
+
+	if _, exitCode := ctesting.RunChurl(t, "cache", "clear"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if entries, _ = c.List(); len(entries) != 0 {
+		t.Errorf("Incorrect entries after clear: %#v", entries)
+	}
+	if archives, _ := c.Archives(); len(archives) != 0 {
+		t.Errorf("Incorrect archives after clear: %#v", archives)
+	}
+}
@@ -0,0 +1,103 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	c      *cache.Cache
+	output flags.Output
+}
+
+type listing struct {
+	Entries  []*cache.Entry   `json:"entries"`
+	Archives []*cache.Archive `json:"archives"`
+}
+
+// CreateCommand returns the 'list' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "list",
+			Short: "lists the cached responses and chart archives",
+			Args:  cobra.NoArgs,
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.c, err = c.OpenCache()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	var l listing
+	var err error
+	l.Entries, err = c.c.List()
+	if err != nil {
+		return err
+	}
+	l.Archives, err = c.c.Archives()
+	if err != nil {
+		return err
+	}
+
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(os.Stdout)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err = enc.Encode(l); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode cache listing")
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "MUSEUM\tPATH\tSIZE\tFETCHED\tETAG")
+		for _, e := range l.Entries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", e.Museum, e.Path, e.Size, e.Fetched.Format(time.RFC3339), e.ETag)
+		}
+		for _, a := range l.Archives {
+			fmt.Fprintf(w, "-\t%s\t%d\t%s\t\n", a.Digest+cache.ArchiveExt, a.Size, a.Fetched.Format(time.RFC3339))
+		}
+		if err = w.Flush(); err != nil {
+			return errors.Wrapf(err, "Failed to write cache listing")
+		}
+	}
+
+	return nil
+}
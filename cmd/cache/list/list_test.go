@@ -0,0 +1,46 @@
+//+build test_integration
+
+package list
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Cache_List(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	if _, exitCode := ctesting.RunChurl(t, "get", "latest", "foo", "--config", config); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	out, exitCode := ctesting.RunChurl(t, "cache", "list", "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	var l listing
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&l); err != nil {
+		t.Fatalf("Failed to decode listing:\n%s", err.Error())
+	}
+	if len(l.Entries) != 1 || l.Entries[0].Museum != "dev" || l.Entries[0].Path != "/api/charts/foo" {
+		t.Errorf("Incorrect listing:\n%s", out)
+	}
+
+	if _, exitCode = ctesting.RunChurl(t, "cache", "clear"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	out, _ = ctesting.RunChurl(t, "cache", "list", "--output", "json")
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&l); err != nil || len(l.Entries) != 0 {
+		t.Errorf("Expected an empty cache after clear:\n%s", out)
+	}
+}
@@ -0,0 +1,61 @@
+package prune
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/spf13/cobra"
+)
+
+const defaultMaxAge = 30 * 24 * time.Hour
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	c      *cache.Cache
+	maxAge time.Duration
+}
+
+// CreateCommand returns the 'prune' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "prune",
+			Short: "removes the cached responses and archives that have not been fetched recently",
+			Args:  cobra.NoArgs,
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.DurationVar(&c.maxAge, "max-age", defaultMaxAge, "Remove entries last fetched longer ago than this")
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.c, err = c.OpenCache()
+	return err
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	count, err := c.c.Prune(time.Now().Add(-c.maxAge))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d cache entries\n", count)
+	return nil
+}
@@ -0,0 +1,76 @@
+//+build test_integration
+
+package prune
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/object88/churl/cache"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Cache_Prune(t *testing.T) {
+	fixture := ctesting.Config(t)
+	defer fixture.Close()
+	dir := fixture.Cache
+
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+	entries := []*cache.Entry{
+		{Museum: "dev", Path: "/index.yaml", Fetched: now},
+		{Museum: "dev", Path: "/api/charts/foo", Fetched: old},
+	}
+	for _, e := range entries {
+		if err = c.Put(e, []byte("body")); err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+	}
+
+	digests := []string{}
+	for _, archive := range []string{"new archive", "old archive"} {
+		h := sha256.Sum256([]byte(archive))
+		digest := hex.EncodeToString(h[:])
+		if err = c.PutArchive(digest, bytes.NewReader([]byte(archive))); err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		digests = append(digests, digest)
+	}
+	os.Chtimes(c.ArchivePath(digests[1]), old, old)
+
+	// Nothing is older than the default of 30 days.
+	out, exitCode := ctesting.RunChurl(t, "cache", "prune")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(out, "Removed 0 cache entries") {
+		t.Errorf("Incorrect output: %s", out)
+	}
+
+	out, exitCode = ctesting.RunChurl(t, "cache", "prune", "--max-age", "24h")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(out, "Removed 2 cache entries") {
+		t.Errorf("Incorrect output: %s", out)
+	}
+
+	remaining, _ := c.List()
+	if len(remaining) != 1 || remaining[0].Path != "/index.yaml" {
+		t.Errorf("Incorrect entries after prune: %#v", remaining)
+	}
+	archives, _ := c.Archives()
+	if len(archives) != 1 || archives[0].Digest != digests[0] {
+		t.Errorf("Incorrect archives after prune: %#v", archives)
+	}
+}
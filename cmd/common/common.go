@@ -1,14 +1,28 @@
 package common
 
 import (
+	"strings"
+	"sync"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/cassette"
 	cmdflags "github.com/object88/churl/cmd/flags"
 	"github.com/object88/churl/log"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type CommonArgs struct {
 	Logger *log.Log
+
+	// cassetteOnce opens the `--record` or `--replay` cassette, which is
+	// shared by every museum that the command connects to
+	cassetteOnce sync.Once
+	cassette     *cassette.Cassette
+	cassetteErr  error
 }
 
 func NewCommonArgs() *CommonArgs {
@@ -18,13 +32,118 @@ func NewCommonArgs() *CommonArgs {
 func (ca *CommonArgs) Setup(flags *pflag.FlagSet) {
 	flags.BoolP(cmdflags.VerboseKey, "v", false, "Emit debug messages")
 	viper.BindPFlag(cmdflags.VerboseKey, flags.Lookup(cmdflags.VerboseKey))
+
+	cmdflags.CreateConfigFlag(flags)
+	cmdflags.CreateMuseumFlag(flags)
+	cmdflags.CreateProjectFlags(flags)
+	cmdflags.CreateOfflineFlag(flags)
+	cmdflags.CreateOutputFlag(flags)
+	cmdflags.CreateRecordFlags(flags)
 }
 
 func (ca *CommonArgs) Evaluate() error {
 	verbose := viper.GetBool(cmdflags.VerboseKey)
 	if verbose {
+		// A new log only writes errors; -v is what asks for everything else.
 		ca.Logger = log.Stderr()
+		ca.Logger.SetLevel(log.Verbose)
 	}
 
 	return nil
 }
+
+// OpenCache opens the churl cache
+func (ca *CommonArgs) OpenCache() (*cache.Cache, error) {
+	d, err := cmdflags.ReadCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(d)
+}
+
+// OpenCassette returns the cassette named by `--record` or `--replay`, and
+// whether it is being replayed.  The cassette is nil if neither is set.
+func (ca *CommonArgs) OpenCassette() (*cassette.Cassette, bool, error) {
+	record, replay, err := cmdflags.ReadRecordFlags()
+	if err != nil {
+		return nil, false, err
+	}
+
+	ca.cassetteOnce.Do(func() {
+		switch {
+		case record != "":
+			ca.cassette, ca.cassetteErr = cassette.Create(record)
+		case replay != "":
+			ca.cassette, ca.cassetteErr = cassette.Load(replay)
+			if ca.cassetteErr != nil {
+				ca.cassetteErr = &churl.ConfigError{Err: ca.cassetteErr}
+			}
+		}
+	})
+
+	return ca.cassette, replay != "", ca.cassetteErr
+}
+
+// OpenManifest loads and merges the configuration files, and selects the
+// museum named by `--museum`, if provided.  An unknown museum is an error, as
+// is naming several museums.
+func (ca *CommonArgs) OpenManifest() (*manifest.Manifest, error) {
+	m, names, err := ca.OpenManifestWithMuseums(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) != 1 {
+		return nil, &churl.ConfigError{Err: errors.Errorf("This command accepts a single museum, but got '%s'", strings.Join(names, "', '"))}
+	}
+	return m, nil
+}
+
+// OpenManifestWithMuseums loads and merges the configuration files, and
+// returns the names of the selected museums: every museum if `all` is set,
+// the comma-separated museums named by `--museum` if provided, or the current
+// museum.  If a single museum is named, it becomes the current museum.  An
+// unknown museum is an error.
+func (ca *CommonArgs) OpenManifestWithMuseums(all bool) (*manifest.Manifest, []string, error) {
+	m, names, err := ca.openManifestWithMuseums(all)
+	if err != nil {
+		return nil, nil, &churl.ConfigError{Err: err}
+	}
+	return m, names, nil
+}
+
+func (ca *CommonArgs) openManifestWithMuseums(all bool) (*manifest.Manifest, []string, error) {
+	files, project, err := cmdflags.ReadConfigFlag()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := manifest.Load(files...)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Failed to open manifest file")
+	}
+	if project != "" && !viper.GetBool(cmdflags.TrustProjectKey) {
+		m.Distrust(project)
+	}
+
+	if all {
+		return m, m.Names(), nil
+	}
+
+	names := cmdflags.ReadMuseumFlag()
+	switch len(names) {
+	case 0:
+		return m, []string{m.CurrentName()}, nil
+	case 1:
+		if err = m.Use(names[0]); err != nil {
+			return nil, nil, err
+		}
+	default:
+		for _, name := range names {
+			if _, ok := m.Museums[name]; !ok {
+				return nil, nil, errors.Errorf("Museum '%s' is not defined; known museums are '%s'", name, strings.Join(m.Names(), "', '"))
+			}
+		}
+	}
+
+	return m, names, nil
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	cmdflags "github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/log"
+	"github.com/spf13/viper"
+)
+
+func Test_Common_Evaluate_Verbose(t *testing.T) {
+	tcs := []struct {
+		name     string
+		verbose  bool
+		expected log.Level
+	}{
+		{name: "default", verbose: false, expected: log.Error},
+		{name: "verbose", verbose: true, expected: log.Verbose},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set(cmdflags.VerboseKey, tc.verbose)
+			defer viper.Set(cmdflags.VerboseKey, false)
+
+			ca := NewCommonArgs()
+			if err := ca.Evaluate(); err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if actual := ca.Logger.Level(); actual != tc.expected {
+				t.Errorf("Incorrect log level; expected %d, actual %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Common_OpenManifest_UntrustedProject(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	xdg, hadXDG := os.LookupEnv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", path.Join(root, "home"))
+	defer func() {
+		if hadXDG {
+			os.Setenv("XDG_CONFIG_HOME", xdg)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+
+	user := path.Join(root, "home", "churl", "config.json")
+	os.MkdirAll(path.Dir(user), 0755)
+	if err := ioutil.WriteFile(user, []byte(`{"apiVersion": "v3", "museums": [{"name": "dev", "url": "http://localhost"}], "current": "dev"}`), 0644); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	repo := path.Join(root, "repo")
+	os.MkdirAll(path.Join(repo, ".git"), 0755)
+	if err := ioutil.WriteFile(path.Join(repo, ".churl.json"), []byte(`{"apiVersion": "v3", "museums": [{"name": "dev", "url": "http://localhost", "password": "exec:echo secret"}]}`), 0644); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(repo)
+	defer viper.Reset()
+
+	tcs := []struct {
+		name  string
+		trust bool
+	}{
+		{name: "untrusted", trust: false},
+		{name: "trusted", trust: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set(cmdflags.ConfigKey, user)
+			viper.Set(cmdflags.TrustProjectKey, tc.trust)
+
+			m, err := NewCommonArgs().OpenManifest()
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+
+			cm, err := m.Current().Resolve()
+			if !tc.trust {
+				if err == nil {
+					t.Errorf("Expected untrusted reference error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if cm.Password != "secret" {
+				t.Errorf("Incorrect password; expected 'secret', actual '%s'", cm.Password)
+			}
+		})
+	}
+}
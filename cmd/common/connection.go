@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/object88/churl"
+	cmdflags "github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/manifest"
+	"github.com/spf13/viper"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// Connect creates a client for the chart museum `cm`, opening a port forward
+// unless the museum has a URL.  The museum's kube context is used unless
+// `kubeFlags` sets one.  Responses are cached, and with `--offline` every
+// request is answered from the cache.  With `--record` or `--replay`, the
+// session is recorded to or replayed from a cassette.
+func (ca *CommonArgs) Connect(ctx context.Context, kubeFlags *genericclioptions.ConfigFlags, podTimeout time.Duration, name string, cm *manifest.ChartMuseum) (*churl.Client, error) {
+	options := []churl.ClientOption{
+		churl.KubeFlags(kubeFlags),
+		churl.PodTimeout(podTimeout),
+		churl.Out(os.Stderr),
+		churl.Err(os.Stderr),
+		churl.Offline(viper.GetBool(cmdflags.OfflineKey)),
+	}
+
+	cs, replay, err := ca.OpenCassette()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cs == nil:
+	case replay:
+		options = append(options, churl.Replay(cs))
+	default:
+		options = append(options, churl.Record(cs))
+	}
+
+	if ch, err := ca.OpenCache(); err != nil {
+		if viper.GetBool(cmdflags.OfflineKey) {
+			return nil, err
+		}
+		// The cache is an optimization; the museum can still be queried.
+		ca.Logger.Infof("Not caching responses from museum '%s': %s\n", name, err.Error())
+	} else {
+		options = append(options, churl.Cache(ch))
+	}
+
+	ca.Logger.Infof("Connecting to museum '%s'...\n", name)
+
+	c, err := churl.NewClient(ctx, name, cm, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	ca.Logger.Infof("Connected to museum '%s'\n", name)
+
+	return c, nil
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"github.com/object88/churl/cmd/common"
 	"github.com/object88/churl/cmd/config/current"
+	"github.com/object88/churl/cmd/config/list"
+	"github.com/object88/churl/cmd/config/migrate"
+	"github.com/object88/churl/cmd/config/schema"
 	"github.com/object88/churl/cmd/traverse"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +28,9 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 
 	c.AddCommand(
 		current.CreateCommand(ca),
+		list.CreateCommand(ca),
+		migrate.CreateCommand(ca),
+		schema.CreateCommand(ca),
 	)
 
 	return traverse.TraverseRunHooks(&c.Command)
@@ -1,15 +1,15 @@
 package current
 
 import (
+	"fmt"
 	"os"
+	"text/tabwriter"
 
 	"github.com/object88/churl/cmd/common"
-	"github.com/object88/churl/cmd/flags"
 	"github.com/object88/churl/cmd/traverse"
 	"github.com/object88/churl/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 type command struct {
@@ -17,6 +17,8 @@ type command struct {
 	*common.CommonArgs
 
 	m *manifest.Manifest
+
+	showOrigin bool
 }
 
 // CreateCommand returns the 'current' subcommand
@@ -37,26 +39,46 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 	}
 
 	flgs := c.Flags()
-
-	// Config flag
-	flags.CreateConfigFlag(flgs)
+	flgs.BoolVar(&c.showOrigin, "show-origin", false, "Show which configuration file supplied each museum")
 
 	return traverse.TraverseRunHooks(&c.Command)
 }
 
 func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
-	m, err := manifest.OpenFromFile(viper.GetString(flags.ConfigKey))
+	m, err := c.OpenManifest()
 	if err != nil {
-		return errors.Wrapf(err, "Failed to open manifest file")
+		return err
 	}
 	c.m = m
 	return nil
 }
 
 func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	if c.showOrigin {
+		return c.writeOrigins()
+	}
+
 	_, err := c.m.WriteTo(os.Stdout)
 	if err != nil {
 		return errors.Wrapf(err, "Failed to write to STDOUT")
 	}
 	return nil
 }
+
+func (c *command) writeOrigins() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MUSEUM\tORIGIN")
+	for _, name := range c.m.Names() {
+		fmt.Fprintf(w, "%s\t%s\n", name, c.m.Origin(name))
+	}
+	origin := c.m.CurrentOrigin()
+	if origin == "" {
+		origin = "(command line)"
+	}
+	fmt.Fprintf(w, "(current: %s)\t%s\n", c.m.CurrentName(), origin)
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrapf(err, "Failed to write to STDOUT")
+	}
+	return nil
+}
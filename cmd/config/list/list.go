@@ -0,0 +1,54 @@
+package list
+
+import (
+	"fmt"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+}
+
+// CreateCommand returns the 'list' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "list",
+			Short: "lists the names of the configured museums",
+			Args:  cobra.NoArgs,
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	m, err := c.OpenManifest()
+	if err != nil {
+		return err
+	}
+	c.m = m
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	for _, name := range c.m.Names() {
+		fmt.Println(name)
+	}
+	return nil
+}
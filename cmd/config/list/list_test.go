@@ -0,0 +1,22 @@
+//+build test_integration
+
+package list
+
+import (
+	"testing"
+
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Config_List(t *testing.T) {
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "prod", URL: "http://prod"}, ctesting.Museum{Name: "dev", URL: "http://dev"})
+	defer fixture.Close()
+
+	out, exitCode := ctesting.RunChurl(t, "config", "list", "--config", fixture.Config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if expected := "dev\nprod\n"; out != expected {
+		t.Errorf("Incorrect museums; expected '%s', actual '%s'", expected, out)
+	}
+}
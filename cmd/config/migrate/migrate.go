@@ -0,0 +1,87 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+}
+
+// CreateCommand returns the 'migrate' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "migrate",
+			Short: "rewrites each configuration file in the latest format, keeping a backup",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	files, _, err := flags.ReadConfigFlag()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := c.migrate(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrate rewrites `configFile` in the latest format.  The museum values are
+// carried over as written; their references are not resolved.
+func (c *command) migrate(configFile string) error {
+	original, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read manifest file '%s'", configFile)
+	}
+
+	m, err := manifest.OpenLayerFromFile(configFile)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open manifest file")
+	}
+	defer m.Close()
+
+	from := m.LoadedVersion()
+	if from == manifest.APIVersionLatest {
+		fmt.Printf("Manifest file '%s' is already at apiVersion '%s'\n", configFile, from)
+		return nil
+	}
+
+	backupFile := fmt.Sprintf("%s.%s.bak", configFile, from)
+	err = ioutil.WriteFile(backupFile, original, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write backup file '%s'", backupFile)
+	}
+
+	err = m.Save()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to save manifest file '%s'", configFile)
+	}
+
+	fmt.Printf("Migrated manifest file '%s' from apiVersion '%s' to '%s'; backup at '%s'\n", configFile, from, manifest.APIVersionLatest, backupFile)
+
+	return nil
+}
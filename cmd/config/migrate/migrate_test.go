@@ -0,0 +1,93 @@
+//+build test_integration
+
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	ctesting "github.com/object88/churl/internal/testing"
+	"github.com/object88/churl/manifest"
+)
+
+func Test_Cmd_Config_Migrate(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	config := path.Join(root, "config.json")
+	legacy := `{"museums": [{"name": "default", "kubeContext": "krobot", "serviceName": "cm-chartmuseum", "port": "8080"}], "current": "default"}`
+	ioutil.WriteFile(config, []byte(legacy), 0644)
+
+	stdout, exitCode := ctesting.RunChurl(t, "config", "migrate", "--config", config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(stdout, config+"."+manifest.APIVersionLegacy+".bak") {
+		t.Errorf("Backup was not reported:\n%s", stdout)
+	}
+
+	backup, err := ioutil.ReadFile(config + "." + manifest.APIVersionLegacy + ".bak")
+	if err != nil {
+		t.Fatalf("Failed to read backup:\n%s", err.Error())
+	}
+	if string(backup) != legacy {
+		t.Errorf("Incorrect backup:\n%s", backup)
+	}
+
+	m, err := manifest.OpenFromFile(config)
+	if err != nil {
+		t.Fatalf("Failed to open migrated manifest:\n%s", err.Error())
+	}
+	defer m.Close()
+	if m.LoadedVersion() != manifest.APIVersionLatest {
+		t.Errorf("Incorrect apiVersion; expected '%s', actual '%s'", manifest.APIVersionLatest, m.LoadedVersion())
+	}
+	if cm := m.Museums["default"]; cm == nil || cm.KubeContext != "krobot" || cm.ServiceName != "cm-chartmuseum" || cm.Port != "8080" {
+		t.Errorf("Museum did not survive migration: %#v", cm)
+	}
+
+	// A manifest at the latest version is left alone.
+	migrated, _ := ioutil.ReadFile(config)
+	os.Remove(config + "." + manifest.APIVersionLegacy + ".bak")
+	stdout, exitCode = ctesting.RunChurl(t, "config", "migrate", "--config", config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(stdout, "already at apiVersion") {
+		t.Errorf("Result was not reported:\n%s", stdout)
+	}
+	if again, _ := ioutil.ReadFile(config); string(again) != string(migrated) {
+		t.Errorf("Migrated manifest was rewritten:\n%s", again)
+	}
+	if backups, _ := filepath.Glob(path.Join(root, "*.bak")); len(backups) != 0 {
+		t.Errorf("Unexpected backups: %v", backups)
+	}
+}
+
+func Test_Cmd_Config_Migrate_References(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	os.Unsetenv("CHURL_TEST_UNSET")
+	marker := path.Join(root, "marker")
+	config := path.Join(root, "config.json")
+	v2 := `{"apiVersion": "v2", "museums": [{"name": "default", "kubeContext": "${CHURL_TEST_UNSET}", "serviceName": "cm-chartmuseum", "port": "8080", "password": "exec:touch ` + marker + `"}], "current": "default"}`
+	ioutil.WriteFile(config, []byte(v2), 0644)
+
+	if _, exitCode := ctesting.RunChurl(t, "config", "migrate", "--config", config); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("Referenced command was run")
+	}
+
+	migrated, _ := ioutil.ReadFile(config)
+	if !strings.Contains(string(migrated), "${CHURL_TEST_UNSET}") || !strings.Contains(string(migrated), "exec:touch") {
+		t.Errorf("References were not kept:\n%s", migrated)
+	}
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"os"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+}
+
+// CreateCommand returns the 'schema' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "schema",
+			Short: "writes the JSON Schema for the configuration file to STDOUT",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	b, err := manifest.Schema()
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write to STDOUT")
+	}
+	return nil
+}
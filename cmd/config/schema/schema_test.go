@@ -0,0 +1,34 @@
+//+build test_integration
+
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	ctesting "github.com/object88/churl/internal/testing"
+	"github.com/object88/churl/manifest"
+)
+
+func Test_Cmd_Config_Schema(t *testing.T) {
+	out, exitCode := ctesting.RunChurl(t, "config", "schema")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	expected, err := manifest.Schema()
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if out != string(expected) {
+		t.Errorf("Incorrect schema:\n%s", out)
+	}
+
+	doc := map[string]interface{}{}
+	if err = json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("Failed to decode schema:\n%s", err.Error())
+	}
+	if doc["$schema"] == nil || doc["properties"] == nil {
+		t.Errorf("Schema is missing '$schema' or 'properties':\n%s", out)
+	}
+}
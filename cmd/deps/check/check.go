@@ -0,0 +1,239 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/internal/resolve"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	update bool
+
+	chartpath string
+}
+
+// CreateCommand returns the 'check' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "check PATH",
+			Short: "check resolves the dependencies of a local chart",
+			Long: `check reads the dependencies of the chart at PATH, from requirements.yaml
+or an apiVersion v2 Chart.yaml, and resolves each version constraint against
+the dependency's repository.  It reports the version that would be picked, the
+newest version, and the version in the lock file.
+
+A dependency is outdated if its constraint excludes the newest version, and
+missing if no version satisfies its constraint.  If any dependency is missing,
+or its repository cannot be queried, check fails.
+
+With --update, the lock file is rewritten with the resolved versions.`,
+			Args: cobra.ExactArgs(1),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVar(&c.update, "update", false, "Rewrite the chart's lock file with the resolved versions")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartpath = strings.TrimSpace(args[0])
+
+	if c.update {
+		if fi, err := os.Stat(c.chartpath); err == nil && !fi.IsDir() {
+			return &churl.ConfigError{Err: errors.Errorf("Cannot update the lock file of '%s'; --update requires a chart directory", c.chartpath)}
+		}
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	reqs, err := readRequirements(c.chartpath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	resolver := resolve.New(c.m, c.connect)
+	defer resolver.Close()
+
+	resolutions := make([]*resolution, len(reqs.Dependencies))
+	for k, dep := range reqs.Dependencies {
+		r := &resolution{
+			Name:       dep.Name,
+			Repository: dep.Repository,
+			Constraint: dep.Version,
+			Locked:     reqs.locked(dep),
+		}
+		resolutions[k] = r
+
+		if resolve.IsLocal(dep.Repository) {
+			resolveLocal(r, dep, c.chartpath)
+			continue
+		}
+
+		name, index, err := resolver.Index(ctx, dep.Repository)
+		r.Museum = name
+		if err != nil {
+			r.setError(err)
+			continue
+		}
+
+		resolveIndex(r, dep, index)
+	}
+
+	if err = c.write(os.Stdout, resolutions); err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, r := range resolutions {
+		if !r.ok() {
+			failed = append(failed, r.Name)
+		}
+	}
+	if len(failed) != 0 {
+		return errors.Errorf("Failed to resolve %d of %d dependencies: '%s'", len(failed), len(resolutions), strings.Join(failed, "', '"))
+	}
+
+	if c.update {
+		p, err := writeLock(c.chartpath, reqs, resolutions, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %s\n", p)
+	}
+
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// connect creates a client for a dependency's repository
+func (c *command) connect(ctx context.Context, name string, cm *manifest.ChartMuseum) (*churl.Client, error) {
+	return c.Connect(ctx, c.cflags, c.podTimeout, name, cm)
+}
+
+func (c *command) write(w io.Writer, resolutions []*resolution) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(resolutions); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependencies")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(resolutions)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependencies")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCONSTRAINT\tLOCKED\tRESOLVED\tLATEST\tSTATUS\tMUSEUM")
+	for _, r := range resolutions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, orDash(r.Constraint), orDash(r.Locked), orDash(r.Resolved), orDash(r.Latest), r.Status, orDash(r.Museum))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, r := range resolutions {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", r.Name, r.Error)
+		}
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+//+build test_integration
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Deps_Check(t *testing.T) {
+	museum := churltest.NewServer(
+		&churltest.Chart{Name: "bar", Version: "1.0.0"},
+		&churltest.Chart{Name: "bar", Version: "1.2.0"},
+		&churltest.Chart{Name: "bar", Version: "2.0.0"},
+	)
+	defer museum.Close()
+	other := churltest.NewServer(&churltest.Chart{Name: "baz", Version: "0.1.0"})
+	defer other.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: museum.URL})
+	defer fixture.Close()
+	root, config := fixture.Root, fixture.Config
+
+	chartpath := path.Join(root, "foo")
+	writeChart(t, chartpath, map[string]string{
+		"Chart.yaml":        "name: foo\nversion: 1.0.0\n",
+		"requirements.yaml": fmt.Sprintf("dependencies:\n- name: bar\n  version: ^1.0.0\n  repository: %s\n- name: baz\n  version: ~0.1.0\n  repository: %s\n", museum.URL, other.URL),
+	})
+
+	out, exitCode := ctesting.RunChurl(t, "deps", "check", chartpath, "--config", config, "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	resolutions := []*resolution{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&resolutions); err != nil {
+		t.Fatalf("Failed to decode resolutions:\n%s", err.Error())
+	}
+	if len(resolutions) != 2 {
+		t.Fatalf("Incorrect resolutions:\n%s", out)
+	}
+	if r := resolutions[0]; r.Museum != "dev" || r.Resolved != "1.2.0" || r.Latest != "2.0.0" || r.Status != Outdated {
+		t.Errorf("Incorrect resolution of bar:\n%s", out)
+	}
+	if r := resolutions[1]; r.Museum != other.URL || r.Resolved != "0.1.0" || r.Status != Current {
+		t.Errorf("Incorrect resolution of baz:\n%s", out)
+	}
+
+	if _, exitCode = ctesting.RunChurl(t, "deps", "check", chartpath, "--config", config, "--update"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	b, err := ioutil.ReadFile(path.Join(chartpath, requirementsLockName))
+	if err != nil || !strings.Contains(string(b), "version: 1.2.0") {
+		t.Errorf("Incorrect lock file; read '%s', error %v", b, err)
+	}
+
+	writeChart(t, chartpath, map[string]string{
+		"requirements.yaml": "dependencies:\n- name: bar\n  version: ^3.0.0\n  repository: '@dev'\n",
+	})
+	out, exitCode = ctesting.RunChurl(t, "deps", "check", chartpath, "--config", config)
+	if exitCode != 1 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(out, "missing") {
+		t.Errorf("Output does not report the missing dependency:\n%s", out)
+	}
+}
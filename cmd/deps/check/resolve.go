@@ -0,0 +1,245 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/internal/resolve"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/repo"
+	"k8s.io/helm/pkg/resolver"
+)
+
+const (
+	requirementsLockName = "requirements.lock"
+	chartLockName        = "Chart.lock"
+	chartfileName        = "Chart.yaml"
+	apiVersionV2         = "v2"
+)
+
+// Status is the outcome of resolving a dependency
+type Status string
+
+const (
+	// Current is a dependency whose constraint allows the newest version
+	Current Status = "current"
+
+	// Outdated is a dependency whose constraint excludes the newest version
+	Outdated Status = "outdated"
+
+	// Missing is a dependency that the repository has no matching version of
+	Missing Status = "missing"
+
+	// Local is a dependency that is read from the file system
+	Local Status = "local"
+
+	// Failed is a dependency whose repository could not be queried
+	Failed Status = "failed"
+)
+
+// resolution is the version of a dependency that would be picked
+type resolution struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+	Constraint string `json:"constraint"`
+	Museum     string `json:"museum,omitempty"`
+	Locked     string `json:"locked,omitempty"`
+	Resolved   string `json:"resolved,omitempty"`
+	Latest     string `json:"latest,omitempty"`
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (r *resolution) ok() bool {
+	return r.Status != Missing && r.Status != Failed
+}
+
+func (r *resolution) setError(err error) {
+	r.Status = Failed
+	r.Error = err.Error()
+}
+
+// requirements is a local chart's dependencies, and its lock file
+type requirements struct {
+	*chartutil.Requirements
+
+	// lockName is the lock file that matches where the dependencies are
+	// declared; requirements.lock for requirements.yaml, and Chart.lock for
+	// the dependencies in an apiVersion v2 Chart.yaml
+	lockName string
+	lock     *chartutil.RequirementsLock
+}
+
+// readRequirements reads the dependencies and lock file of the chart at `p`,
+// which is a directory or an archive
+func readRequirements(p string) (*requirements, error) {
+	// chartutil predates apiVersion v2, which declares the dependencies in
+	// Chart.yaml, and refuses to load such a chart; it is read directly.
+	if reqs, err := readChartfileRequirements(p); reqs != nil || err != nil {
+		return reqs, err
+	}
+
+	ch, err := chartutil.Load(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to load chart at '%s'", p)
+	}
+
+	reqs := &requirements{lockName: requirementsLockName}
+	reqs.Requirements, err = chartutil.LoadRequirements(ch)
+	switch {
+	case err == chartutil.ErrRequirementsNotFound:
+		reqs.Requirements = &chartutil.Requirements{}
+	case err != nil:
+		return nil, errors.Wrapf(err, "Failed to read the requirements of chart '%s'", ch.Metadata.Name)
+	}
+
+	reqs.lock, err = chartutil.LoadRequirementsLock(ch)
+	switch {
+	case err == chartutil.ErrLockfileNotFound:
+		reqs.lock = nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "Failed to read '%s' of chart '%s'", reqs.lockName, ch.Metadata.Name)
+	}
+
+	return reqs, nil
+}
+
+// readChartfileRequirements reads the dependencies from the Chart.yaml of the
+// chart directory `p`, and its Chart.lock.  If `p` is not a directory of an
+// apiVersion v2 chart, it returns nil.
+func readChartfileRequirements(p string) (*requirements, error) {
+	if fi, err := os.Stat(p); err != nil || !fi.IsDir() {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(p, chartfileName))
+	if err != nil {
+		// chartutil reports the missing file.
+		return nil, nil
+	}
+	chartfile := &struct {
+		APIVersion string `json:"apiVersion"`
+		chartutil.Requirements
+	}{}
+	if err = yaml.Unmarshal(b, chartfile); err != nil {
+		return nil, errors.Wrapf(err, "Failed to read '%s' of chart at '%s'", chartfileName, p)
+	}
+	if chartfile.APIVersion != apiVersionV2 {
+		return nil, nil
+	}
+
+	reqs := &requirements{
+		Requirements: &chartfile.Requirements,
+		lockName:     chartLockName,
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(p, chartLockName))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, errors.Wrapf(err, "Failed to read '%s' of chart at '%s'", chartLockName, p)
+	default:
+		reqs.lock = &chartutil.RequirementsLock{}
+		if err = yaml.Unmarshal(b, reqs.lock); err != nil {
+			return nil, errors.Wrapf(err, "Failed to read '%s' of chart at '%s'", chartLockName, p)
+		}
+	}
+
+	return reqs, nil
+}
+
+// locked returns the version of the dependency in the lock file, if any
+func (reqs *requirements) locked(dep *chartutil.Dependency) string {
+	if reqs.lock == nil {
+		return ""
+	}
+	for _, l := range reqs.lock.Dependencies {
+		if l.Name == dep.Name {
+			return l.Version
+		}
+	}
+	return ""
+}
+
+// resolveLocal reads the version of a dependency on the file system, relative
+// to the chart at `chartpath`
+func resolveLocal(r *resolution, dep *chartutil.Dependency, chartpath string) {
+	r.Status = Local
+	if dep.Repository == "" {
+		// The chart is vendored under charts/, and is not resolved.
+		return
+	}
+
+	p, err := resolver.GetLocalPath(dep.Repository, chartpath)
+	if err != nil {
+		r.setError(err)
+		return
+	}
+	ch, err := chartutil.Load(p)
+	if err != nil {
+		r.setError(errors.Wrapf(err, "Failed to load dependency '%s'", dep.Name))
+		return
+	}
+	r.Resolved = ch.Metadata.Version
+	r.Latest = ch.Metadata.Version
+}
+
+// resolveIndex picks the newest version of the dependency in the index that
+// satisfies its constraint
+func resolveIndex(r *resolution, dep *chartutil.Dependency, index *repo.IndexFile) {
+	var err error
+	r.Resolved, r.Latest, err = resolve.Newest(index, dep.Name, dep.Version)
+	if err != nil {
+		r.setError(err)
+		return
+	}
+
+	switch {
+	case r.Resolved == "":
+		r.Status = Missing
+	case r.Latest == "" || r.Resolved == r.Latest:
+		r.Status = Current
+	default:
+		r.Status = Outdated
+	}
+}
+
+// writeLock rewrites the chart's lock file with the resolved versions
+func writeLock(chartpath string, reqs *requirements, resolutions []*resolution, generated time.Time) (string, error) {
+	digest, err := resolver.HashReq(reqs.Requirements)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to hash the requirements of chart at '%s'", chartpath)
+	}
+
+	lock := &chartutil.RequirementsLock{
+		Generated:    generated,
+		Digest:       digest,
+		Dependencies: make([]*chartutil.Dependency, len(resolutions)),
+	}
+	for k, r := range resolutions {
+		version := r.Resolved
+		if version == "" {
+			version = r.Constraint
+		}
+		lock.Dependencies[k] = &chartutil.Dependency{
+			Name:       r.Name,
+			Version:    version,
+			Repository: r.Repository,
+		}
+	}
+
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return "", errors.Wrapf(err, "Internal error: failed to encode '%s'", reqs.lockName)
+	}
+
+	p := filepath.Join(chartpath, reqs.lockName)
+	if err = ioutil.WriteFile(p, b, 0644); err != nil {
+		return "", errors.Wrapf(err, "Failed to write '%s'", p)
+	}
+	return p, nil
+}
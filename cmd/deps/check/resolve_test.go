@@ -0,0 +1,165 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/uuid"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func testIndex(versions ...string) *repo.IndexFile {
+	i := repo.NewIndexFile()
+	for _, v := range versions {
+		i.Entries["bar"] = append(i.Entries["bar"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "bar", Version: v}})
+	}
+	i.SortEntries()
+	return i
+}
+
+func writeChart(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory:\n%s", err.Error())
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write '%s':\n%s", p, err.Error())
+		}
+	}
+}
+
+func Test_Check_Resolve(t *testing.T) {
+	index := testIndex("1.0.0", "1.2.0", "2.0.0", "2.1.0-rc.1")
+
+	tcs := []struct {
+		name     string
+		dep      *chartutil.Dependency
+		resolved string
+		latest   string
+		status   Status
+	}{
+		{name: "current", dep: &chartutil.Dependency{Name: "bar", Version: "^2.0.0"}, resolved: "2.0.0", latest: "2.0.0", status: Current},
+		{name: "outdated", dep: &chartutil.Dependency{Name: "bar", Version: "~1.0.0 || ^1.1.0"}, resolved: "1.2.0", latest: "2.0.0", status: Outdated},
+		{name: "any", dep: &chartutil.Dependency{Name: "bar"}, resolved: "2.0.0", latest: "2.0.0", status: Current},
+		{name: "prerelease", dep: &chartutil.Dependency{Name: "bar", Version: ">=2.1.0-0"}, resolved: "2.1.0-rc.1", latest: "2.0.0", status: Outdated},
+		{name: "no matching version", dep: &chartutil.Dependency{Name: "bar", Version: "^3.0.0"}, latest: "2.0.0", status: Missing},
+		{name: "unknown chart", dep: &chartutil.Dependency{Name: "baz", Version: "^1.0.0"}, status: Missing},
+		{name: "invalid constraint", dep: &chartutil.Dependency{Name: "bar", Version: "not a version"}, status: Failed},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &resolution{Name: tc.dep.Name}
+			resolveIndex(r, tc.dep, index)
+			if r.Resolved != tc.resolved || r.Latest != tc.latest || r.Status != tc.status {
+				t.Errorf("Incorrect resolution; expected %s/%s/%s, actual %s/%s/%s", tc.resolved, tc.latest, tc.status, r.Resolved, r.Latest, r.Status)
+			}
+		})
+	}
+}
+
+func Test_Check_ReadRequirements(t *testing.T) {
+	tcs := []struct {
+		name     string
+		files    map[string]string
+		lockName string
+		locked   string
+	}{
+		{
+			name: "requirements",
+			files: map[string]string{
+				"Chart.yaml":        "apiVersion: v1\nname: foo\nversion: 1.0.0\n",
+				"requirements.yaml": "dependencies:\n- name: bar\n  version: ^1.0.0\n  repository: https://charts.example.com\n",
+				"requirements.lock": "dependencies:\n- name: bar\n  version: 1.0.0\n  repository: https://charts.example.com\n",
+			},
+			lockName: requirementsLockName,
+			locked:   "1.0.0",
+		},
+		{
+			name: "chartfile",
+			files: map[string]string{
+				"Chart.yaml": "apiVersion: v2\nname: foo\nversion: 1.0.0\ndependencies:\n- name: bar\n  version: ^1.0.0\n  repository: https://charts.example.com\n",
+			},
+			lockName: chartLockName,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			root, _ := ioutil.TempDir("", uuid.New().String())
+			defer os.RemoveAll(root)
+			writeChart(t, root, tc.files)
+
+			reqs, err := readRequirements(root)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if len(reqs.Dependencies) != 1 || reqs.Dependencies[0].Name != "bar" || reqs.Dependencies[0].Version != "^1.0.0" {
+				t.Fatalf("Incorrect dependencies: %#v", reqs.Dependencies)
+			}
+			if reqs.lockName != tc.lockName {
+				t.Errorf("Incorrect lock file; expected '%s', actual '%s'", tc.lockName, reqs.lockName)
+			}
+			if locked := reqs.locked(reqs.Dependencies[0]); locked != tc.locked {
+				t.Errorf("Incorrect locked version; expected '%s', actual '%s'", tc.locked, locked)
+			}
+		})
+	}
+}
+
+func Test_Check_ResolveLocal(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	writeChart(t, root, map[string]string{
+		"foo/Chart.yaml": "name: foo\nversion: 1.0.0\n",
+		"bar/Chart.yaml": "name: bar\nversion: 0.3.0\n",
+	})
+
+	r := &resolution{Name: "bar"}
+	resolveLocal(r, &chartutil.Dependency{Name: "bar", Repository: "file://../bar"}, filepath.Join(root, "foo"))
+	if r.Status != Local || r.Resolved != "0.3.0" {
+		t.Errorf("Incorrect resolution; expected 0.3.0/local, actual %s/%s", r.Resolved, r.Status)
+	}
+}
+
+func Test_Check_WriteLock(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	reqs := &requirements{
+		Requirements: &chartutil.Requirements{
+			Dependencies: []*chartutil.Dependency{{Name: "bar", Version: "^1.0.0", Repository: "https://charts.example.com"}},
+		},
+		lockName: requirementsLockName,
+	}
+	resolutions := []*resolution{{Name: "bar", Constraint: "^1.0.0", Repository: "https://charts.example.com", Resolved: "1.2.0"}}
+	generated := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	p, err := writeLock(root, reqs, resolutions, generated)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatalf("Failed to read lock file:\n%s", err.Error())
+	}
+	lock := &chartutil.RequirementsLock{}
+	if err = yaml.Unmarshal(b, lock); err != nil {
+		t.Fatalf("Failed to decode lock file:\n%s", err.Error())
+	}
+	if !lock.Generated.Equal(generated) || len(lock.Dependencies) != 1 || lock.Dependencies[0].Version != "1.2.0" {
+		t.Errorf("Incorrect lock file:\n%s", b)
+	}
+	// The digest is the one that `helm dependency build` checks.
+	if lock.Digest == "" || lock.Digest[:7] != "sha256:" {
+		t.Errorf("Incorrect digest '%s'", lock.Digest)
+	}
+}
@@ -0,0 +1,35 @@
+package deps
+
+import (
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/deps/check"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+}
+
+// CreateCommand returns the intermediate 'deps' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+	c = &command{
+		Command: cobra.Command{
+			Use:   "deps",
+			Short: "deps subcommands will inspect the dependencies of a local chart",
+			Long: `deps subcommands will inspect the dependencies of a local chart.
+
+A dependency whose repository is a museum in the manifest, either by URL, by
+in-cluster service address, or as '@NAME', is queried through that museum.`,
+		},
+		CommonArgs: ca,
+	}
+
+	c.AddCommand(
+		check.CreateCommand(ca),
+	)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
@@ -0,0 +1,321 @@
+package describe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	showAll    = "all"
+	showChart  = "chart"
+	showReadme = "readme"
+	showValues = "values"
+)
+
+// readmeNames are the names of a chart's README, in the order that helm
+// looks for them
+var readmeNames = []string{"readme.md", "readme.txt", "readme"}
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+
+	output flags.Output
+	show   string
+
+	chartname string
+	version   string
+}
+
+// description is the parts of a chart archive that describe how to use it
+type description struct {
+	Chart        *chart.Metadata         `json:"chart,omitempty"`
+	Dependencies []*chartutil.Dependency `json:"dependencies,omitempty"`
+	Readme       string                  `json:"readme,omitempty"`
+	Values       string                  `json:"values,omitempty"`
+}
+
+// CreateCommand returns the 'describe' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "describe CHART [VERSION]",
+			Short: "describe shows a chart's README, default values, and dependencies",
+			Long: `describe downloads a chart's archive, and shows its Chart.yaml, dependencies,
+maintainers, README, and default values.  If VERSION is not provided, the
+newest version is described.`,
+			Args: cobra.RangeArgs(1, 2),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringVar(&c.show, "show", showAll, fmt.Sprintf("Part of the chart to show; one of %s", strings.Join([]string{showReadme, showValues, showChart, showAll}, ", ")))
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartname = strings.TrimSpace(args[0])
+	if len(args) == 2 {
+		c.version = strings.TrimSpace(args[1])
+	}
+
+	switch c.show {
+	case showAll, showChart, showReadme, showValues:
+	default:
+		return &churl.ConfigError{Err: errors.Errorf("Value '%s' is not a valid --show; expected one of %s, %s, %s, or %s", c.show, showReadme, showValues, showChart, showAll)}
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ch, _, err := client.Load(ctx, c.chartname, c.version)
+	if err != nil {
+		return err
+	}
+
+	d, err := c.describe(ch)
+	if err != nil {
+		return err
+	}
+
+	return c.write(os.Stdout, d)
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// describe collects the parts of the chart selected by `--show`
+func (c *command) describe(ch *chart.Chart) (*description, error) {
+	d := &description{}
+
+	if c.show == showAll || c.show == showChart {
+		d.Chart = ch.Metadata
+
+		reqs, err := chartutil.LoadRequirements(ch)
+		switch {
+		case err == chartutil.ErrRequirementsNotFound:
+		case err != nil:
+			return nil, errors.Wrapf(err, "Failed to read the requirements of chart '%s'", ch.Metadata.Name)
+		default:
+			d.Dependencies = reqs.Dependencies
+		}
+	}
+
+	if c.show == showAll || c.show == showReadme {
+		d.Readme = readme(ch)
+	}
+
+	if (c.show == showAll || c.show == showValues) && ch.Values != nil {
+		d.Values = ch.Values.Raw
+	}
+
+	return d, nil
+}
+
+func (c *command) write(w io.Writer, d *description) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(d); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode chart description")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(d)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode chart description")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	// A single part is written as-is, so that it can be redirected to a file,
+	// i.e., `churl describe foo --show values > values.yaml`.
+	switch c.show {
+	case showReadme:
+		_, err := io.WriteString(w, d.Readme)
+		return err
+	case showValues:
+		_, err := io.WriteString(w, d.Values)
+		return err
+	case showChart:
+		return writeChart(w, d)
+	}
+
+	if err := writeChart(w, d); err != nil {
+		return err
+	}
+	if d.Readme != "" {
+		fmt.Fprintf(w, "\n--- README ---\n%s", ensureNewline(d.Readme))
+	}
+	if d.Values != "" {
+		fmt.Fprintf(w, "\n--- values.yaml ---\n%s", ensureNewline(d.Values))
+	}
+	return nil
+}
+
+func writeChart(w io.Writer, d *description) error {
+	md := d.Chart
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", md.Name)
+	fmt.Fprintf(tw, "Version:\t%s\n", md.Version)
+	if md.AppVersion != "" {
+		fmt.Fprintf(tw, "App version:\t%s\n", md.AppVersion)
+	}
+	if md.Description != "" {
+		fmt.Fprintf(tw, "Description:\t%s\n", md.Description)
+	}
+	if md.Home != "" {
+		fmt.Fprintf(tw, "Home:\t%s\n", md.Home)
+	}
+	if len(md.Sources) != 0 {
+		fmt.Fprintf(tw, "Sources:\t%s\n", strings.Join(md.Sources, ", "))
+	}
+	if len(md.Keywords) != 0 {
+		fmt.Fprintf(tw, "Keywords:\t%s\n", strings.Join(md.Keywords, ", "))
+	}
+	if md.Deprecated {
+		fmt.Fprintf(tw, "Deprecated:\ttrue\n")
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrapf(err, "Failed to write chart description")
+	}
+
+	if len(md.Maintainers) != 0 {
+		fmt.Fprintf(w, "\nMaintainers:\n")
+		for _, m := range md.Maintainers {
+			s := m.Name
+			if m.Email != "" {
+				s += fmt.Sprintf(" <%s>", m.Email)
+			}
+			if m.Url != "" {
+				s += fmt.Sprintf(" (%s)", m.Url)
+			}
+			fmt.Fprintf(w, "  %s\n", s)
+		}
+	}
+
+	if len(d.Dependencies) != 0 {
+		fmt.Fprintf(w, "\nDependencies:\n")
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  NAME\tVERSION\tREPOSITORY\tCONDITION")
+		for _, dep := range d.Dependencies {
+			name := dep.Name
+			if dep.Alias != "" {
+				name = fmt.Sprintf("%s (%s)", dep.Alias, dep.Name)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, dep.Version, dep.Repository, dep.Condition)
+		}
+		if err := tw.Flush(); err != nil {
+			return errors.Wrapf(err, "Failed to write chart description")
+		}
+	}
+
+	return nil
+}
+
+// readme returns the contents of the chart's README, or an empty string
+func readme(ch *chart.Chart) string {
+	for _, name := range readmeNames {
+		for _, f := range ch.Files {
+			if strings.ToLower(f.TypeUrl) == name {
+				return string(f.Value)
+			}
+		}
+	}
+	return ""
+}
+
+func ensureNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
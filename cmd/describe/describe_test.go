@@ -0,0 +1,80 @@
+//+build test_integration
+
+package describe
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+const (
+	testReadme = "# foo\n\nfoo does things.\n"
+	testValues = "# replicas is the number of pods\nreplicas: 1\n"
+)
+
+func Test_Cmd_Describe(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0"},
+		&churltest.Chart{
+			Name:        "foo",
+			Version:     "1.1.0",
+			Description: "foo does things",
+			Files: map[string]string{
+				"README.md":         testReadme,
+				"values.yaml":       testValues,
+				"requirements.yaml": "dependencies:\n- name: bar\n  version: ^2.0.0\n  repository: https://charts.example.com\n",
+			},
+		},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	tcs := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "readme", args: []string{"--show", "readme"}, expected: testReadme},
+		{name: "values", args: []string{"--show", "values"}, expected: testValues},
+		{name: "older version", args: []string{"1.0.0", "--show", "values"}, expected: ""},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"describe", "foo", "--config", config}, tc.args...)
+			out, exitCode := ctesting.RunChurl(t, args...)
+			if exitCode != 0 {
+				t.Fatalf("Unexpected exit code %d", exitCode)
+			}
+			if out != tc.expected {
+				t.Errorf("Incorrect output; expected:\n%s\nactual:\n%s", tc.expected, out)
+			}
+		})
+	}
+
+	out, exitCode := ctesting.RunChurl(t, "describe", "foo", "--config", config, "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	var d description
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&d); err != nil {
+		t.Fatalf("Failed to decode description:\n%s", err.Error())
+	}
+	if d.Chart.Version != "1.1.0" || len(d.Dependencies) != 1 || d.Dependencies[0].Name != "bar" || d.Readme != testReadme || d.Values != testValues {
+		t.Errorf("Incorrect description:\n%s", out)
+	}
+
+	if _, exitCode = ctesting.RunChurl(t, "describe", "foo", "--config", config, "--show", "templates"); exitCode != 3 {
+		t.Errorf("Incorrect exit code for invalid --show; expected 3, actual %d", exitCode)
+	}
+	if _, exitCode = ctesting.RunChurl(t, "describe", "foo", "2.0.0", "--config", config); exitCode != 4 {
+		t.Errorf("Incorrect exit code for missing version; expected 4, actual %d", exitCode)
+	}
+}
@@ -0,0 +1,226 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+
+	output     flags.Output
+	summary    bool
+	valuesOnly bool
+
+	chartname string
+	from      string
+	to        string
+}
+
+// CreateCommand returns the 'diff' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "diff CHART V1 V2",
+			Short: "diff shows what changed between two versions of a chart",
+			Long: `diff downloads the archives of two versions of a chart, and shows unified
+diffs of their templates, values.yaml, Chart.yaml, and other files.
+
+With --values-only, the default values are compared key by key instead.  With
+--summary, only the names of the added, removed, and modified files are
+listed.`,
+			Args: cobra.ExactArgs(3),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVar(&c.summary, "summary", false, "List the added, removed, and modified files")
+	flgs.BoolVar(&c.valuesOnly, "values-only", false, "Compare the default values key by key")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartname = strings.TrimSpace(args[0])
+	c.from = strings.TrimSpace(args[1])
+	c.to = strings.TrimSpace(args[2])
+
+	if c.summary && c.valuesOnly {
+		return cmdutil.UsageErrorf(cmd, "--summary and --values-only cannot be used together")
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	from, _, err := client.Load(ctx, c.chartname, c.from)
+	if err != nil {
+		return err
+	}
+	to, _, err := client.Load(ctx, c.chartname, c.to)
+	if err != nil {
+		return err
+	}
+
+	if c.valuesOnly {
+		var fromRaw, toRaw string
+		if from.Values != nil {
+			fromRaw = from.Values.Raw
+		}
+		if to.Values != nil {
+			toRaw = to.Values.Raw
+		}
+		changes, err := diffValues(fromRaw, toRaw)
+		if err != nil {
+			return err
+		}
+		return c.write(os.Stdout, changes, func(w io.Writer) error {
+			return writeValueChanges(w, changes)
+		})
+	}
+
+	fromFiles, err := files(from)
+	if err != nil {
+		return err
+	}
+	toFiles, err := files(to)
+	if err != nil {
+		return err
+	}
+
+	fromPrefix := fmt.Sprintf("%s-%s", c.chartname, from.Metadata.Version)
+	toPrefix := fmt.Sprintf("%s-%s", c.chartname, to.Metadata.Version)
+	changes, err := diffFiles(fromFiles, toFiles, fromPrefix, toPrefix)
+	if err != nil {
+		return err
+	}
+
+	if c.summary {
+		for _, fc := range changes {
+			fc.Diff = ""
+		}
+		return c.write(os.Stdout, changes, func(w io.Writer) error {
+			for _, fc := range changes {
+				fmt.Fprintf(w, "%s  %s\n", fc.Status.letter(), fc.Path)
+			}
+			return nil
+		})
+	}
+
+	return c.write(os.Stdout, changes, func(w io.Writer) error {
+		for _, fc := range changes {
+			if _, err := io.WriteString(w, fc.Diff); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// write encodes `v` in the structured output formats, or calls `text`
+func (c *command) write(w io.Writer, v interface{}, text func(w io.Writer) error) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(v); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode diff")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode diff")
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return text(w)
+	}
+}
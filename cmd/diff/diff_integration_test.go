@@ -0,0 +1,70 @@
+//+build test_integration
+
+package diff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Diff(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{
+			Name:    "foo",
+			Version: "1.0.1",
+			Files: map[string]string{
+				"values.yaml":               "replicas: 1\n",
+				"templates/deployment.yaml": "kind: Deployment\n",
+			},
+		},
+		&churltest.Chart{
+			Name:    "foo",
+			Version: "1.1.0",
+			Files: map[string]string{
+				"values.yaml":               "replicas: 2\n",
+				"templates/deployment.yaml": "kind: Deployment\n",
+				"templates/service.yaml":    "kind: Service\n",
+			},
+		},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "diff", "foo", "1.0.1", "1.1.0", "--config", config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	for _, expected := range []string{"+++ foo-1.1.0/templates/service.yaml", "-replicas: 1", "+replicas: 2"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Diff does not contain '%s':\n%s", expected, out)
+		}
+	}
+
+	out, exitCode = ctesting.RunChurl(t, "diff", "foo", "1.0.1", "1.1.0", "--config", config, "--summary", "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	changes := []*fileChange{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&changes); err != nil {
+		t.Fatalf("Failed to decode summary:\n%s", err.Error())
+	}
+	// Chart.yaml, values.yaml, and the service template differ.
+	if len(changes) != 3 || changes[1].Path != "templates/service.yaml" || changes[1].Status != Added {
+		t.Errorf("Incorrect summary:\n%s", out)
+	}
+
+	out, exitCode = ctesting.RunChurl(t, "diff", "foo", "1.0.1", "1.1.0", "--config", config, "--values-only")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if out != "~ replicas: 1 -> 2\n" {
+		t.Errorf("Incorrect values diff:\n%s", out)
+	}
+}
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Diff_Values(t *testing.T) {
+	from := `
+replicas: 1
+image:
+  repository: foo
+  tag: "1.0"
+ingress:
+  enabled: false
+  hosts: [a]
+`
+	to := `
+replicas: 2
+image:
+  repository: foo
+  tag: "1.1"
+  pullPolicy: Always
+ingress:
+  hosts: [a, b]
+`
+
+	changes, err := diffValues(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	expected := []struct {
+		key    string
+		status Status
+	}{
+		{key: "image.pullPolicy", status: Added},
+		{key: "image.tag", status: Modified},
+		{key: "ingress.enabled", status: Removed},
+		{key: "ingress.hosts", status: Modified},
+		{key: "replicas", status: Modified},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Incorrect number of changes; expected %d, actual %d", len(expected), len(changes))
+	}
+	for k, e := range expected {
+		if changes[k].Key != e.key || changes[k].Status != e.status {
+			t.Errorf("Incorrect change %d; expected %s %s, actual %s %s", k, e.status, e.key, changes[k].Status, changes[k].Key)
+		}
+	}
+}
+
+func Test_Diff_Files(t *testing.T) {
+	from := map[string]string{
+		"Chart.yaml":                "name: foo\nversion: 1.0.0\n",
+		"templates/service.yaml":    "kind: Service\n",
+		"templates/deployment.yaml": "kind: Deployment\nreplicas: 1\n",
+	}
+	to := map[string]string{
+		"Chart.yaml":                "name: foo\nversion: 1.1.0\n",
+		"templates/ingress.yaml":    "kind: Ingress\n",
+		"templates/deployment.yaml": "kind: Deployment\nreplicas: 1\n",
+	}
+
+	changes, err := diffFiles(from, to, "foo-1.0.0", "foo-1.1.0")
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	tcs := []struct {
+		path     string
+		status   Status
+		contains string
+	}{
+		{path: "Chart.yaml", status: Modified, contains: "+version: 1.1.0"},
+		{path: "templates/ingress.yaml", status: Added, contains: "--- /dev/null"},
+		{path: "templates/service.yaml", status: Removed, contains: "-kind: Service"},
+	}
+	if len(changes) != len(tcs) {
+		t.Fatalf("Incorrect number of changes; expected %d, actual %d", len(tcs), len(changes))
+	}
+	for k, tc := range tcs {
+		t.Run(tc.path, func(t *testing.T) {
+			fc := changes[k]
+			if fc.Path != tc.path || fc.Status != tc.status {
+				t.Errorf("Incorrect change; expected %s %s, actual %s %s", tc.status, tc.path, fc.Status, fc.Path)
+			}
+			if !strings.Contains(fc.Diff, tc.contains) {
+				t.Errorf("Diff does not contain '%s':\n%s", tc.contains, fc.Diff)
+			}
+		})
+	}
+}
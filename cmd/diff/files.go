@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"path"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Status describes how a file changed between two versions
+type Status string
+
+const (
+	// Added files are only in the newer version
+	Added Status = "added"
+
+	// Removed files are only in the older version
+	Removed Status = "removed"
+
+	// Modified files are in both versions, with different contents
+	Modified Status = "modified"
+)
+
+func (s Status) letter() string {
+	switch s {
+	case Added:
+		return "A"
+	case Removed:
+		return "D"
+	default:
+		return "M"
+	}
+}
+
+// fileChange is a file that differs between two versions of a chart
+type fileChange struct {
+	Path   string `json:"path"`
+	Status Status `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// files returns the contents of the chart's files, keyed by their path in the
+// archive.  Chart.yaml is regenerated from the chart's metadata, and the
+// files of subcharts are under `charts/NAME/`.
+func files(ch *chart.Chart) (map[string]string, error) {
+	fs := map[string]string{}
+	if err := addFiles(fs, "", ch); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func addFiles(fs map[string]string, prefix string, ch *chart.Chart) error {
+	if ch.Metadata != nil {
+		b, err := yaml.Marshal(ch.Metadata)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to encode Chart.yaml for '%s'", ch.Metadata.Name)
+		}
+		fs[path.Join(prefix, "Chart.yaml")] = string(b)
+	}
+	if ch.Values != nil && ch.Values.Raw != "" {
+		fs[path.Join(prefix, "values.yaml")] = ch.Values.Raw
+	}
+	for _, t := range ch.Templates {
+		fs[path.Join(prefix, t.Name)] = string(t.Data)
+	}
+	for _, f := range ch.Files {
+		fs[path.Join(prefix, f.TypeUrl)] = string(f.Value)
+	}
+	for _, dep := range ch.Dependencies {
+		if err := addFiles(fs, path.Join(prefix, "charts", dep.Metadata.Name), dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffFiles compares two sets of files, and returns the files that differ,
+// sorted by path, with their unified diffs
+func diffFiles(from, to map[string]string, fromPrefix, toPrefix string) ([]*fileChange, error) {
+	paths := []string{}
+	for p := range from {
+		paths = append(paths, p)
+	}
+	for p := range to {
+		if _, ok := from[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := []*fileChange{}
+	for _, p := range paths {
+		a, inFrom := from[p]
+		b, inTo := to[p]
+
+		fromFile := path.Join(fromPrefix, p)
+		toFile := path.Join(toPrefix, p)
+
+		fc := &fileChange{Path: p}
+		switch {
+		case !inFrom:
+			fc.Status = Added
+			fromFile = "/dev/null"
+		case !inTo:
+			fc.Status = Removed
+			toFile = "/dev/null"
+		case a != b:
+			fc.Status = Modified
+		default:
+			continue
+		}
+
+		ud := difflib.UnifiedDiff{
+			A:        difflib.SplitLines(a),
+			B:        difflib.SplitLines(b),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		}
+		if a == "" {
+			ud.A = nil
+		}
+		if b == "" {
+			ud.B = nil
+		}
+		d, err := difflib.GetUnifiedDiffString(ud)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to compare '%s'", p)
+		}
+		fc.Diff = d
+		changes = append(changes, fc)
+	}
+
+	return changes, nil
+}
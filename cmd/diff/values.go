@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// valueChange is a default value that differs between two versions of a
+// chart.  Key is the dotted path to the value, i.e., `image.tag`; lists are
+// compared as a whole.
+type valueChange struct {
+	Key    string      `json:"key"`
+	Status Status      `json:"status"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+// diffValues compares two values.yaml documents key by key, and returns the
+// changes sorted by key
+func diffValues(from, to string) ([]*valueChange, error) {
+	fromValues, err := chartutil.ReadValues([]byte(from))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the older version's values")
+	}
+	toValues, err := chartutil.ReadValues([]byte(to))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the newer version's values")
+	}
+
+	a := map[string]interface{}{}
+	flatten(a, "", fromValues)
+	b := map[string]interface{}{}
+	flatten(b, "", toValues)
+
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []*valueChange{}
+	for _, k := range keys {
+		av, inFrom := a[k]
+		bv, inTo := b[k]
+		switch {
+		case !inFrom:
+			changes = append(changes, &valueChange{Key: k, Status: Added, To: bv})
+		case !inTo:
+			changes = append(changes, &valueChange{Key: k, Status: Removed, From: av})
+		case !reflect.DeepEqual(av, bv):
+			changes = append(changes, &valueChange{Key: k, Status: Modified, From: av, To: bv})
+		}
+	}
+
+	return changes, nil
+}
+
+// flatten adds the leaves of `v` to `dst`, keyed by their dotted path.  An
+// empty map is a leaf, so that adding or removing it is reported.
+func flatten(dst map[string]interface{}, prefix string, v map[string]interface{}) {
+	for k, child := range v {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := child.(map[string]interface{}); ok && len(m) != 0 {
+			flatten(dst, key, m)
+			continue
+		}
+		dst[key] = child
+	}
+}
+
+func writeValueChanges(w io.Writer, changes []*valueChange) error {
+	for _, vc := range changes {
+		var line string
+		switch vc.Status {
+		case Added:
+			line = fmt.Sprintf("+ %s: %s", vc.Key, render(vc.To))
+		case Removed:
+			line = fmt.Sprintf("- %s: %s", vc.Key, render(vc.From))
+		default:
+			line = fmt.Sprintf("~ %s: %s -> %s", vc.Key, render(vc.From), render(vc.To))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render formats a value as compact JSON, which distinguishes strings from
+// numbers and booleans
+func render(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return strings.TrimSpace(fmt.Sprintf("%v", v))
+	}
+	return string(b)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/flags"
+)
+
+// Exit codes; these are documented in the README, and must not be changed
+const (
+	// ExitOK is success
+	ExitOK = 0
+
+	// ExitFailure is any failure that is not otherwise classified
+	ExitFailure = 1
+
+	// ExitUsage is an unknown flag or an invalid flag value
+	ExitUsage = 2
+
+	// ExitInvalidConfig is a manifest or option that cannot be used
+	ExitInvalidConfig = 3
+
+	// ExitNotFound is a chart or version that the museum does not have
+	ExitNotFound = 4
+
+	// ExitUnauthorized is a request rejected for its credentials
+	ExitUnauthorized = 5
+
+	// ExitServerError is any other unsuccessful response from the museum
+	ExitServerError = 6
+
+	// ExitForwardFailed is a port forward that could not be opened
+	ExitForwardFailed = 7
+
+	// ExitPodNotReady is a museum pod that was not running before the timeout
+	ExitPodNotReady = 8
+
+	// ExitOutdated is `outdated --exit-code` finding releases behind the
+	// museum; the check itself succeeded
+	ExitOutdated = 9
+)
+
+// ExitCoder is implemented by the errors that choose their own exit code,
+// rather than the one for their kind, i.e., a subcommand's check that found a
+// problem
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// usageError marks the errors that cobra reports while parsing flags
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+// ExitCode returns the exit code for `err`
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if _, ok := err.(*usageError); ok {
+		return ExitUsage
+	}
+	if e := asExitCoder(err); e != nil {
+		return e.ExitCode()
+	}
+
+	switch churl.KindOf(err) {
+	case churl.KindInvalidConfig:
+		return ExitInvalidConfig
+	case churl.KindNotFound:
+		return ExitNotFound
+	case churl.KindUnauthorized:
+		return ExitUnauthorized
+	case churl.KindServerError:
+		return ExitServerError
+	case churl.KindForwardFailed:
+		return ExitForwardFailed
+	case churl.KindPodNotReady:
+		return ExitPodNotReady
+	default:
+		return ExitFailure
+	}
+}
+
+// ReportError writes `err` to `w`, as JSON if `--output` requests it, and
+// returns the exit code
+func ReportError(w io.Writer, err error) int {
+	code := ExitCode(err)
+
+	output, oerr := flags.ReadOutputFlag()
+	if oerr != nil || (output != flags.JSON && output != flags.JSONCompact) {
+		fmt.Fprintf(w, "Error: %s\n", err.Error())
+		return code
+	}
+
+	x := struct {
+		Error    string          `json:"error"`
+		Kind     churl.ErrorKind `json:"kind"`
+		ExitCode int             `json:"exitCode"`
+		Status   int             `json:"status,omitempty"`
+		URL      string          `json:"url,omitempty"`
+	}{
+		Error:    err.Error(),
+		Kind:     churl.KindOf(err),
+		ExitCode: code,
+	}
+	if code == ExitUsage {
+		x.Kind = churl.KindInvalidConfig
+	}
+	if herr, ok := churl.AsError(err).(churl.HTTPError); ok {
+		x.Status = herr.StatusCode()
+		x.URL = herr.RequestURL()
+	}
+
+	enc := json.NewEncoder(w)
+	if output == flags.JSON {
+		enc.SetIndent("", "  ")
+	}
+	if eerr := enc.Encode(x); eerr != nil {
+		fmt.Fprintf(w, "Error: %s\n", err.Error())
+	}
+
+	return code
+}
+
+// asExitCoder returns the first ExitCoder in the chain of causes of `err`, or
+// nil
+func asExitCoder(err error) ExitCoder {
+	for err != nil {
+		if e, ok := err.(ExitCoder); ok {
+			return e
+		}
+		switch t := err.(type) {
+		case interface{ Cause() error }:
+			err = t.Cause()
+		case interface{ Unwrap() error }:
+			err = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+func Test_Cmd_ExitCode(t *testing.T) {
+	tcs := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "nil", err: nil, expected: ExitOK},
+		{name: "plain", err: errors.Errorf("boom"), expected: ExitFailure},
+		{name: "usage", err: &usageError{err: errors.Errorf("unknown flag")}, expected: ExitUsage},
+		{name: "config", err: &churl.ConfigError{Err: errors.Errorf("bad")}, expected: ExitInvalidConfig},
+		{name: "not found", err: &churl.NotFoundError{Chart: "foo"}, expected: ExitNotFound},
+		{name: "wrapped not found", err: errors.Wrapf(&churl.NotFoundError{Chart: "foo"}, "Could not get chart"), expected: ExitNotFound},
+		{name: "unauthorized", err: &churl.UnauthorizedError{Status: 401}, expected: ExitUnauthorized},
+		{name: "server", err: &churl.ServerError{Status: 500}, expected: ExitServerError},
+		{name: "forward", err: &churl.ForwardError{Err: errors.Errorf("refused")}, expected: ExitForwardFailed},
+		{name: "pod", err: &churl.PodNotReadyError{Err: errors.Errorf("timeout")}, expected: ExitPodNotReady},
+		{name: "exit coder", err: &checkError{code: ExitOutdated}, expected: ExitOutdated},
+		{name: "wrapped exit coder", err: errors.Wrapf(&checkError{code: ExitOutdated}, "Check failed"), expected: ExitOutdated},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ExitCode(tc.err); actual != tc.expected {
+				t.Errorf("Incorrect exit code; expected %d, actual %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Cmd_ReportError_JSON(t *testing.T) {
+	viper.Set(flags.OutputKey, "json")
+	defer viper.Set(flags.OutputKey, "text")
+
+	err := errors.Wrapf(&churl.NotFoundError{Museum: "dev", Chart: "foo", Status: 404, URL: "http://localhost/api/charts/foo"}, "Could not get chart")
+
+	var buf bytes.Buffer
+	code := ReportError(&buf, err)
+	if code != ExitNotFound {
+		t.Errorf("Incorrect exit code; expected %d, actual %d", ExitNotFound, code)
+	}
+
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("Failed to decode error report '%s':\n%s", buf.String(), err.Error())
+	}
+	if actual["kind"] != string(churl.KindNotFound) || actual["status"] != float64(404) || actual["url"] != "http://localhost/api/charts/foo" {
+		t.Errorf("Incorrect error report: %s", buf.String())
+	}
+}
+
+func Test_Cmd_ReportError_ExitCoder(t *testing.T) {
+	viper.Set(flags.OutputKey, "json")
+	defer viper.Set(flags.OutputKey, "text")
+
+	var buf bytes.Buffer
+	code := ReportError(&buf, &checkError{code: ExitOutdated, kind: "outdated"})
+	if code != ExitOutdated {
+		t.Errorf("Incorrect exit code; expected %d, actual %d", ExitOutdated, code)
+	}
+
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("Failed to decode error report '%s':\n%s", buf.String(), err.Error())
+	}
+	if actual["kind"] != "outdated" || actual["exitCode"] != float64(ExitOutdated) {
+		t.Errorf("Incorrect error report: %s", buf.String())
+	}
+}
+
+// checkError is an ExitCoder, as a subcommand's check returns
+type checkError struct {
+	code int
+	kind churl.ErrorKind
+}
+
+func (e *checkError) Error() string {
+	return "check found a problem"
+}
+
+func (e *checkError) ExitCode() int {
+	return e.code
+}
+
+func (e *checkError) Kind() churl.ErrorKind {
+	return e.kind
+}
@@ -0,0 +1,281 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/bundle"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/helm/pkg/repo"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// sourceDateEpoch is the reproducible-builds variable that sets the
+	// timestamp if --timestamp is not set
+	sourceDateEpoch = "SOURCE_DATE_EPOCH"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	to               string
+	constraintString string
+	timestampString  string
+
+	charts     []string
+	constraint *semver.Constraints
+	timestamp  time.Time
+}
+
+// CreateCommand returns the 'export' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "export --to FILE [CHART...]",
+			Short: "export writes the charts of a museum to a reproducible bundle",
+			Long: `export writes every version of CHART, or of every chart, in the current
+museum to a single gzipped tarball: the archives and their provenance files, an
+index.yaml that refers to them, and a manifest.json that records the digest of
+every file.  --constraint limits the versions that are exported.  Archives are
+verified against their digests before they are written.
+
+Every file in the bundle has the --timestamp, which defaults to
+$SOURCE_DATE_EPOCH, or to the current time.  Exporting the same charts with the
+same timestamp writes the same bytes, so that bundles can be compared by their
+checksum.
+
+A bundle is checked with 'churl verify-bundle', and is used as a museum by
+setting the museum's URL to a file URL, i.e., 'file:///srv/bundle.tgz'.
+
+The bundle is named with --to, not --output: every churl command already uses
+--output for the format of what it reports, and export reports the charts that
+it wrote in that format.`,
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringVar(&c.to, "to", "", "File to write the bundle to")
+	flgs.StringVar(&c.constraintString, "constraint", "", "Version constraint that exported versions must satisfy, i.e., '>=1.0.0'")
+	flgs.StringVar(&c.timestampString, "timestamp", "", "Timestamp of the bundle, in RFC 3339 format; $SOURCE_DATE_EPOCH or the current time if not set")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.charts = []string{}
+	for _, arg := range args {
+		c.charts = append(c.charts, strings.TrimSpace(arg))
+	}
+
+	if c.to == "" {
+		return cmdutil.UsageErrorf(cmd, "--to is required")
+	}
+
+	var err error
+	if c.constraintString != "" {
+		c.constraint, err = semver.NewConstraint(c.constraintString)
+		if err != nil {
+			return &churl.ConfigError{Err: errors.Wrapf(err, "Invalid version constraint '%s'", c.constraintString)}
+		}
+	}
+
+	c.timestamp, err = readTimestamp(c.timestampString, os.Getenv(sourceDateEpoch), time.Now())
+	if err != nil {
+		return &churl.ConfigError{Err: err}
+	}
+
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := c.m.CurrentName()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	index, err := client.Index(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "Could not get the index of museum '%s'", name)
+	}
+	cvs, err := selectVersions(index, name, c.charts, c.constraint)
+	if err != nil {
+		return err
+	}
+
+	m, err := c.export(ctx, client, cvs)
+	if err != nil {
+		return err
+	}
+
+	return c.write(os.Stdout, m)
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// export writes the bundle to a temporary file beside --to, and renames it
+// once it is complete, so that a failed export does not leave a partial
+// bundle.
+func (c *command) export(ctx context.Context, client *churl.Client, cvs []*repo.ChartVersion) (*bundle.Manifest, error) {
+	f, err := ioutil.TempFile(filepath.Dir(c.to), ".churl-export-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create bundle '%s'", c.to)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bundle.NewWriter(f, client.Name(), c.timestamp)
+	for _, cv := range cvs {
+		if err = add(ctx, client, w, cv); err != nil {
+			return nil, err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err = f.Chmod(0644); err != nil {
+		return nil, errors.Wrapf(err, "Failed to write bundle '%s'", c.to)
+	}
+	if err = f.Close(); err != nil {
+		return nil, errors.Wrapf(err, "Failed to write bundle '%s'", c.to)
+	}
+	if err = os.Rename(f.Name(), c.to); err != nil {
+		return nil, errors.Wrapf(err, "Failed to write bundle '%s'", c.to)
+	}
+	return w.Manifest(), nil
+}
+
+func add(ctx context.Context, client *churl.Client, w *bundle.Writer, cv *repo.ChartVersion) error {
+	rc, err := client.DownloadVersion(ctx, cv)
+	if err != nil {
+		return err
+	}
+	archive, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to download chart '%s' version '%s'", cv.Name, cv.Version)
+	}
+
+	prov, err := client.Provenance(ctx, cv)
+	if churl.KindOf(err) == churl.KindNotFound {
+		prov, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return w.Add(cv, archive, prov)
+}
+
+func (c *command) write(w io.Writer, m *bundle.Manifest) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(m); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode bundle manifest")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(m)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode bundle manifest")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHART\tVERSION\tDIGEST\tPROVENANCE")
+	for _, ch := range m.Charts {
+		prov := "no"
+		if ch.Provenance != "" {
+			prov = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ch.Name, ch.Version, ch.Digest, prov)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Exported %d chart versions from museum '%s' to '%s' at %s\n", len(m.Charts), m.Museum, c.to, m.Timestamp.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,96 @@
+//+build test_integration
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/bundle"
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Export(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"},
+		&churltest.Chart{Name: "foo", Version: "1.1.0"},
+		&churltest.Chart{Name: "bar", Version: "0.1.0"},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t)
+	defer fixture.Close()
+	root, config := fixture.Root, fixture.Config
+
+	first := path.Join(root, "first.tgz")
+	second := path.Join(root, "second.tgz")
+	fixture.WriteConfig(t, ctesting.Museum{Name: "prod", URL: s.URL}, ctesting.Museum{Name: "snapshot", URL: "file://" + first})
+
+	export := func(to string, args ...string) (*bundle.Manifest, int) {
+		args = append([]string{"export", "--config", config, "--output", "json", "--to", to, "--timestamp", "2020-04-01T12:00:00Z"}, args...)
+		out, exitCode := ctesting.RunChurl(t, args...)
+		m := &bundle.Manifest{}
+		if exitCode == 0 {
+			if err := json.NewDecoder(strings.NewReader(out)).Decode(m); err != nil {
+				t.Fatalf("Failed to decode manifest:\n%s", err.Error())
+			}
+		}
+		return m, exitCode
+	}
+
+	m, exitCode := export(first, "foo")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if m.Museum != "prod" || len(m.Charts) != 2 || m.Charts[1].Provenance == "" {
+		t.Errorf("Incorrect manifest: %+v", m)
+	}
+	if _, exitCode = export(second, "foo"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	a, _ := ioutil.ReadFile(first)
+	b, _ := ioutil.ReadFile(second)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Exports with the same timestamp differ")
+	}
+	if _, exitCode = export(second, "baz"); exitCode != 4 {
+		t.Errorf("Unexpected exit code %d for a missing chart", exitCode)
+	}
+
+	if _, exitCode = ctesting.RunChurl(t, "verify-bundle", first); exitCode != 0 {
+		t.Errorf("Unexpected exit code %d verifying the bundle", exitCode)
+	}
+	ioutil.WriteFile(second, a[:len(a)/2], 0644)
+	if _, exitCode = ctesting.RunChurl(t, "verify-bundle", second); exitCode != 1 {
+		t.Errorf("Unexpected exit code %d verifying a truncated bundle", exitCode)
+	}
+
+	// The first export warmed the cache, so the same export works offline,
+	// and writes the same bundle.
+	offline := path.Join(root, "offline.tgz")
+	requests := len(s.Requests())
+	if _, exitCode = export(offline, "foo", "--offline"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d exporting offline", exitCode)
+	}
+	if b, _ = ioutil.ReadFile(offline); !bytes.Equal(a, b) {
+		t.Errorf("Offline export differs")
+	}
+	if _, exitCode = export(offline, "bar", "--offline"); exitCode == 0 {
+		t.Errorf("Offline export of an uncached chart succeeded")
+	}
+	if actual := len(s.Requests()); actual != requests {
+		t.Errorf("Offline export sent %d requests to the museum", actual-requests)
+	}
+
+	// The bundle is a museum, without the museum it was exported from.
+	s.Close()
+	out, exitCode := ctesting.RunChurl(t, "describe", "--config", config, "--museum", "snapshot", "foo", "1.0.0")
+	if exitCode != 0 {
+		t.Errorf("Unexpected exit code %d describing a chart in the bundle:\n%s", exitCode, out)
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/object88/churl"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// selectVersions returns the versions of `charts`, or of every chart, that
+// satisfy the constraint, ordered by chart name, and newest first.  A chart
+// that the museum does not have is an error.
+func selectVersions(index *repo.IndexFile, museum string, charts []string, constraint *semver.Constraints) ([]*repo.ChartVersion, error) {
+	names := charts
+	if len(names) == 0 {
+		for name := range index.Entries {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	cvs := []*repo.ChartVersion{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		entries, ok := index.Entries[name]
+		if !ok {
+			return nil, &churl.NotFoundError{Museum: museum, Chart: name}
+		}
+		for _, cv := range entries {
+			if constraint != nil {
+				v, err := semver.NewVersion(cv.Version)
+				if err != nil || !constraint.Check(v) {
+					continue
+				}
+			}
+			cvs = append(cvs, cv)
+		}
+	}
+	return cvs, nil
+}
+
+// readTimestamp returns the bundle's timestamp: `flag` if it is set, else the
+// `epoch` in seconds if it is set, else `now`
+func readTimestamp(flag, epoch string, now time.Time) (time.Time, error) {
+	switch {
+	case flag != "":
+		t, err := time.Parse(time.RFC3339, flag)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "Invalid timestamp '%s'", flag)
+		}
+		return t, nil
+	case epoch != "":
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "Invalid $%s '%s'", sourceDateEpoch, epoch)
+		}
+		return time.Unix(secs, 0), nil
+	}
+	return now, nil
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Export_SelectVersions(t *testing.T) {
+	i := repo.NewIndexFile()
+	for _, v := range []string{"foo 1.0.0", "foo 1.1.0", "foo 2.0.0", "bar 0.1.0"} {
+		var name, version string
+		fmt.Sscanf(v, "%s %s", &name, &version)
+		i.Add(&chart.Metadata{Name: name, Version: version}, fmt.Sprintf("charts/%s-%s.tgz", name, version), "", "")
+	}
+	i.SortEntries()
+
+	tcs := []struct {
+		name       string
+		charts     []string
+		constraint string
+		expected   []string
+		err        bool
+	}{
+		{name: "everything", expected: []string{"bar-0.1.0", "foo-2.0.0", "foo-1.1.0", "foo-1.0.0"}},
+		{name: "charts", charts: []string{"foo", "bar", "foo"}, expected: []string{"bar-0.1.0", "foo-2.0.0", "foo-1.1.0", "foo-1.0.0"}},
+		{name: "constraint", charts: []string{"foo"}, constraint: "^1.0.0", expected: []string{"foo-1.1.0", "foo-1.0.0"}},
+		{name: "missing chart", charts: []string{"baz"}, err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var constraint *semver.Constraints
+			if tc.constraint != "" {
+				constraint, _ = semver.NewConstraint(tc.constraint)
+			}
+
+			cvs, err := selectVersions(i, "test", tc.charts, constraint)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if len(cvs) != len(tc.expected) {
+				t.Fatalf("Incorrect number of versions; expected %d, actual %d", len(tc.expected), len(cvs))
+			}
+			for k, cv := range cvs {
+				if actual := cv.Name + "-" + cv.Version; actual != tc.expected[k] {
+					t.Errorf("Incorrect version %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+			}
+		})
+	}
+}
+
+func Test_Export_ReadTimestamp(t *testing.T) {
+	now := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name     string
+		flag     string
+		epoch    string
+		expected time.Time
+		err      bool
+	}{
+		{name: "default", expected: now},
+		{name: "flag", flag: "2019-01-02T03:04:05Z", epoch: "0", expected: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "epoch", epoch: "1546398245", expected: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "bad flag", flag: "yesterday", err: true},
+		{name: "bad epoch", epoch: "yesterday", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := readTimestamp(tc.flag, tc.epoch, now)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if !actual.Equal(tc.expected) {
+				t.Errorf("Incorrect timestamp; expected %s, actual %s", tc.expected, actual)
+			}
+		})
+	}
+}
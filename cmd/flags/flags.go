@@ -1,9 +1,16 @@
 package flags
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
+	"github.com/object88/churl"
+	"github.com/object88/churl/cache"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -13,31 +20,229 @@ const (
 	// ConfigKey is used to specify where a churl config file can be found
 	ConfigKey string = "config"
 
+	// CacheDirKey overrides the location of the churl cache; it is read from
+	// `$CHURL_CACHE_DIR` only, as kubectl already defines `--cache-dir`
+	CacheDirKey = "cache-dir"
+
+	// AllMuseumsKey selects every chart museum
+	AllMuseumsKey = "all-museums"
+
+	// MuseumKey selects the chart museum for a single invocation
+	MuseumKey = "museum"
+
+	// NoProjectKey skips the search for a project manifest
+	NoProjectKey = "no-project"
+
+	// OfflineKey answers requests from the cache without contacting a museum
+	OfflineKey = "offline"
+
 	// OutputKey determines the output format
 	OutputKey = "output"
 
+	// TrustProjectKey allows the project manifest to reference files and
+	// commands
+	TrustProjectKey = "trust-project"
+
+	// RecordKey names a cassette file that records the session with the
+	// museums
+	RecordKey = "record"
+
+	// ReplayKey names a cassette file that answers requests instead of the
+	// museums
+	ReplayKey = "replay"
+
 	// VerboseKey turns on verbose output to STDERR
 	VerboseKey = "verbose"
 )
 
 // CreateConfigFlag adds the `--config` flag to the flagset, with an
-// OS-specific default location
+// OS-specific default location.  The default is the first of `config.json`,
+// `config.yaml`, and `config.yml` that exists, or `config.json` if none do.
 func CreateConfigFlag(flgs *pflag.FlagSet) {
+	annotations := make(map[string][]string)
+	annotations[cobra.BashCompFilenameExt] = manifest.Extensions()
+
+	usage := fmt.Sprintf("Path to configuration file; several files may be separated by '%c', and later files take precedence", os.PathListSeparator)
+	flgs.String(ConfigKey, defaultConfigFile(), usage)
+	flg := flgs.Lookup(ConfigKey)
+	flg.Annotations = annotations
+	viper.BindPFlag(ConfigKey, flg)
+	viper.BindEnv(ConfigKey)
+}
+
+// CreateProjectFlags adds the `--no-project` and `--trust-project` flags to
+// the flagset
+func CreateProjectFlags(flgs *pflag.FlagSet) {
+	flgs.Bool(NoProjectKey, false, "Do not merge the project manifest found above the working directory")
+	viper.BindPFlag(NoProjectKey, flgs.Lookup(NoProjectKey))
+	viper.BindEnv(NoProjectKey)
+
+	flgs.Bool(TrustProjectKey, false, "Allow the project manifest to use file: and exec: references")
+	viper.BindPFlag(TrustProjectKey, flgs.Lookup(TrustProjectKey))
+	viper.BindEnv(TrustProjectKey)
+}
+
+// ReadConfigFlag returns the manifest files to merge, lowest precedence
+// first.  If `--config` or `$CHURL_CONFIG` names files, those are the only
+// files; otherwise the default user manifest, if it exists, is followed by the
+// project manifest found by searching upward from the working directory,
+// unless `--no-project` is set.  The project manifest is also returned on its
+// own, or an empty string if there is none.
+func ReadConfigFlag() ([]string, string, error) {
+	raw := viper.GetString(ConfigKey)
+	explicit := raw != defaultConfigFile()
+
+	files := []string{}
+	for _, f := range filepath.SplitList(raw) {
+		if f == "" {
+			continue
+		}
+		if f == defaultConfigFile() {
+			if _, err := os.Stat(f); os.IsNotExist(err) {
+				continue
+			}
+		}
+		files = append(files, f)
+	}
+
+	project := ""
+	if !explicit && !viper.GetBool(NoProjectKey) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "Failed to get working directory")
+		}
+		p, ok, err := manifest.FindProjectFile(wd)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			project = p
+			files = append(files, project)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, "", errors.Errorf("No configuration file found; create one with `churl init` or provide one with --config")
+	}
+
+	return files, project, nil
+}
+
+// ReadConfigFile returns the single manifest file named by `--config` or
+// `$CHURL_CONFIG`, for commands that create or rewrite a file
+func ReadConfigFile() (string, error) {
+	raw := viper.GetString(ConfigKey)
+	files := filepath.SplitList(raw)
+	if len(files) != 1 {
+		return "", errors.Errorf("Expected a single configuration file, but got '%s'", strings.Join(files, "', '"))
+	}
+	return files[0], nil
+}
+
+func defaultConfigFile() string {
 	d, err := os.UserConfigDir()
 	if err != nil {
 		panic(err)
 	}
-	configFile := path.Join(d, "churl", "config.json")
 
-	exts := []string{"*.json"}
-	annotations := make(map[string][]string)
-	annotations[cobra.BashCompFilenameExt] = exts
+	exts := manifest.Extensions()
+	for _, ext := range exts {
+		candidate := path.Join(d, "churl", "config."+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path.Join(d, "churl", "config."+exts[0])
+}
 
-	flgs.String(ConfigKey, configFile, "Path to configuration file")
-	flg := flgs.Lookup(ConfigKey)
+// CreateMuseumFlag adds the `--museum` flag to the flagset.  Completion
+// offers the museums defined in the configuration.
+func CreateMuseumFlag(flgs *pflag.FlagSet) {
+	annotations := map[string][]string{
+		cobra.BashCompCustom: []string{"__churl_get_museums"},
+	}
+
+	flgs.String(MuseumKey, "", "Name of the museum to use instead of the configuration's current museum; commands that query several museums accept a comma-separated list")
+	flg := flgs.Lookup(MuseumKey)
 	flg.Annotations = annotations
-	viper.BindPFlag(ConfigKey, flg)
-	viper.BindEnv(ConfigKey)
+	viper.BindPFlag(MuseumKey, flg)
+	viper.BindEnv(MuseumKey)
+}
+
+// ReadMuseumFlag returns the museums named by `--museum`, or an empty slice
+func ReadMuseumFlag() []string {
+	names := []string{}
+	for _, name := range strings.Split(viper.GetString(MuseumKey), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CreateAllMuseumsFlag adds the `--all-museums` flag to the flagset.  The
+// flag is added to each command that can query several museums, and viper can
+// only bind a key to one flag, so it is read with ReadAllMuseumsFlag.
+func CreateAllMuseumsFlag(flgs *pflag.FlagSet) {
+	flgs.Bool(AllMuseumsKey, false, "Query every configured museum")
+	viper.BindEnv(AllMuseumsKey)
+}
+
+// ReadAllMuseumsFlag returns whether `--all-museums` is set on the flagset of
+// the running command, or else in the environment
+func ReadAllMuseumsFlag(flgs *pflag.FlagSet) bool {
+	if flg := flgs.Lookup(AllMuseumsKey); flg != nil && flg.Changed {
+		all, _ := flgs.GetBool(AllMuseumsKey)
+		return all
+	}
+	return viper.GetBool(AllMuseumsKey)
+}
+
+// CreateOfflineFlag adds the `--offline` flag to the flagset
+func CreateOfflineFlag(flgs *pflag.FlagSet) {
+	flgs.Bool(OfflineKey, false, "Answer from the local cache without opening a port forward; requests that are not cached fail")
+	viper.BindPFlag(OfflineKey, flgs.Lookup(OfflineKey))
+	viper.BindEnv(OfflineKey)
+}
+
+// CreateRecordFlags adds the `--record` and `--replay` flags to the flagset
+func CreateRecordFlags(flgs *pflag.FlagSet) {
+	annotations := map[string][]string{
+		cobra.BashCompFilenameExt: []string{"json"},
+	}
+
+	flgs.String(RecordKey, "", "Record the requests to each museum, and their responses, to a cassette file; credentials are not recorded")
+	flg := flgs.Lookup(RecordKey)
+	flg.Annotations = annotations
+	viper.BindPFlag(RecordKey, flg)
+	viper.BindEnv(RecordKey)
+
+	flgs.String(ReplayKey, "", "Answer requests from a cassette file written by --record, without opening a port forward")
+	flg = flgs.Lookup(ReplayKey)
+	flg.Annotations = annotations
+	viper.BindPFlag(ReplayKey, flg)
+	viper.BindEnv(ReplayKey)
+}
+
+// ReadRecordFlags returns the cassette files named by `--record` and
+// `--replay`; at most one may be set
+func ReadRecordFlags() (string, string, error) {
+	record := viper.GetString(RecordKey)
+	replay := viper.GetString(ReplayKey)
+	if record != "" && replay != "" {
+		return "", "", &churl.ConfigError{Err: errors.Errorf("--record and --replay cannot be used together")}
+	}
+	return record, replay, nil
+}
+
+// ReadCacheDir returns the cache directory from `$CHURL_CACHE_DIR`, or the
+// OS-specific default
+func ReadCacheDir() (string, error) {
+	viper.BindEnv(CacheDirKey)
+	if d := viper.GetString(CacheDirKey); d != "" {
+		return d, nil
+	}
+	return cache.DefaultDir()
 }
 
 // CreateOutputFlag adds the `--output` flag to the flagset
@@ -60,7 +265,7 @@ func ReadOutputFlag() (Output, error) {
 	raw := viper.GetString(OutputKey)
 	var o Output
 	if err := o.UnmarshalText([]byte(raw)); err != nil {
-		return Unknown, err
+		return Unknown, &churl.ConfigError{Err: err}
 	}
 	return o, nil
 }
@@ -0,0 +1,78 @@
+package flags
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func Test_Flags_ReadConfigFlag_Project(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, ".git"), 0755)
+	project := path.Join(root, ".churl.json")
+	user := path.Join(root, "config.json")
+	for _, f := range []string{project, user} {
+		if err := ioutil.WriteFile(f, []byte(`{"apiVersion": "v3", "museums": []}`), 0644); err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	defer viper.Reset()
+
+	tcs := []struct {
+		name      string
+		config    string
+		noProject bool
+		expected  bool
+	}{
+		{
+			name:     "default config",
+			config:   defaultConfigFile(),
+			expected: true,
+		},
+		{
+			name:      "no project",
+			config:    defaultConfigFile(),
+			noProject: true,
+			expected:  false,
+		},
+		{
+			name:     "explicit config",
+			config:   user,
+			expected: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set(ConfigKey, tc.config)
+			viper.Set(NoProjectKey, tc.noProject)
+
+			files, _, err := ReadConfigFlag()
+			if tc.expected && err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+
+			found := false
+			for _, f := range files {
+				if path.Base(f) == path.Base(project) {
+					found = true
+				}
+			}
+			if found != tc.expected {
+				t.Errorf("Incorrect project discovery; expected %t, got files '%v'", tc.expected, files)
+			}
+		})
+	}
+}
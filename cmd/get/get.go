@@ -2,6 +2,7 @@ package get
 
 import (
 	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
 	"github.com/object88/churl/cmd/get/latest"
 	"github.com/object88/churl/cmd/traverse"
 	"github.com/spf13/cobra"
@@ -14,6 +15,8 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 		Short: "get subcommands will return metadata about one or more charts",
 	}
 
+	flags.CreateAllMuseumsFlag(c.PersistentFlags())
+
 	c.AddCommand(
 		latest.CreateCommand(ca),
 	)
@@ -0,0 +1,114 @@
+package latest
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+const (
+	statusBehind        = "behind"
+	statusDigestDiffers = "digest differs"
+	statusError         = "error"
+	statusMissing       = "missing"
+	statusNewest        = "newest"
+)
+
+// compare returns the status of each result, relative to the newest version
+// found in any museum, and whether all the museums agree
+func compare(results []*result) ([]string, bool) {
+	var newest *semver.Version
+	newestRaw := ""
+	digests := map[string]map[string]struct{}{}
+	for _, r := range results {
+		if r.Chart == nil {
+			continue
+		}
+		if _, ok := digests[r.Chart.Version]; !ok {
+			digests[r.Chart.Version] = map[string]struct{}{}
+		}
+		digests[r.Chart.Version][r.Chart.Digest] = struct{}{}
+
+		v, err := semver.NewVersion(r.Chart.Version)
+		if err != nil {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+			newestRaw = r.Chart.Version
+		}
+	}
+
+	statuses := make([]string, len(results))
+	agree := true
+	for k, r := range results {
+		switch {
+		case r.err != nil:
+			statuses[k] = statusError
+		case r.Chart == nil:
+			statuses[k] = statusMissing
+		case r.Chart.Version != newestRaw:
+			statuses[k] = statusBehind
+		case len(digests[r.Chart.Version]) != 1:
+			statuses[k] = statusDigestDiffers
+		default:
+			statuses[k] = statusNewest
+		}
+		if statuses[k] != statusNewest {
+			agree = false
+		}
+	}
+
+	return statuses, agree
+}
+
+// writeComparison writes a table of each museum's newest version of the
+// chart, showing which museums differ
+func writeComparison(w io.Writer, results []*result) error {
+	statuses, agree := compare(results)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MUSEUM\tVERSION\tAPP VERSION\tDIGEST\tSTATUS")
+	for k, r := range results {
+		version, appVersion, digest := "-", "-", "-"
+		if r.Chart != nil {
+			version, appVersion, digest = r.Chart.Version, r.Chart.AppVersion, shortDigest(r.Chart.Digest)
+		}
+		status := statuses[k]
+		if r.err != nil {
+			status = fmt.Sprintf("%s: %s", status, r.err.Error())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Museum, version, appVersion, digest, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrapf(err, "Failed to write to STDOUT")
+	}
+
+	found := 0
+	for _, r := range results {
+		if r.Chart != nil {
+			found++
+		}
+	}
+
+	switch {
+	case found == 0:
+		fmt.Fprintln(w, "\nNo museum has the chart.")
+	case agree:
+		fmt.Fprintln(w, "\nAll museums have the same version.")
+	default:
+		fmt.Fprintln(w, "\nVersions differ between museums.")
+	}
+
+	return nil
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
@@ -0,0 +1,86 @@
+package latest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Cmd_Get_Latest_Compare(t *testing.T) {
+	chart := func(version, digest string) *repo.ChartVersion {
+		return &repo.ChartVersion{
+			Metadata: &chart.Metadata{Version: version},
+			Digest:   digest,
+		}
+	}
+
+	tcs := []struct {
+		name     string
+		results  []*result
+		expected []string
+		agree    bool
+	}{
+		{
+			name: "agree",
+			results: []*result{
+				{Museum: "a", Chart: chart("1.1.0", "abc")},
+				{Museum: "b", Chart: chart("1.1.0", "abc")},
+			},
+			expected: []string{statusNewest, statusNewest},
+			agree:    true,
+		},
+		{
+			name: "behind",
+			results: []*result{
+				{Museum: "a", Chart: chart("1.0.10", "abc")},
+				{Museum: "b", Chart: chart("1.0.9", "def")},
+			},
+			expected: []string{statusNewest, statusBehind},
+		},
+		{
+			name: "digest differs",
+			results: []*result{
+				{Museum: "a", Chart: chart("1.1.0", "abc")},
+				{Museum: "b", Chart: chart("1.1.0", "def")},
+			},
+			expected: []string{statusDigestDiffers, statusDigestDiffers},
+		},
+		{
+			name: "missing and error",
+			results: []*result{
+				{Museum: "a", Chart: chart("1.1.0", "abc")},
+				{Museum: "b"},
+				{Museum: "c", err: errors.New("boom")},
+			},
+			expected: []string{statusNewest, statusMissing, statusError},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, agree := compare(tc.results)
+			if agree != tc.agree {
+				t.Errorf("Incorrect agreement; expected %t, actual %t", tc.agree, agree)
+			}
+			for k := range tc.expected {
+				if actual[k] != tc.expected[k] {
+					t.Errorf("Incorrect status for museum '%s'; expected '%s', actual '%s'", tc.results[k].Museum, tc.expected[k], actual[k])
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := writeComparison(&buf, tc.results); err != nil {
+				t.Fatalf("Failed to write comparison:\n%s", err.Error())
+			}
+			for _, r := range tc.results {
+				if !strings.Contains(buf.String(), r.Museum) {
+					t.Errorf("Comparison does not include museum '%s':\n%s", r.Museum, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -1,21 +1,21 @@
 package latest
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/object88/churl"
 	"github.com/object88/churl/cmd/common"
 	"github.com/object88/churl/cmd/flags"
 	"github.com/object88/churl/cmd/traverse"
-	"github.com/object88/churl/forwarder"
 	"github.com/object88/churl/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/helm/pkg/repo"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
@@ -28,15 +28,15 @@ type command struct {
 	cobra.Command
 	*common.CommonArgs
 
-	m *manifest.Manifest
+	m       *manifest.Manifest
+	museums []string
 
-	cflags *genericclioptions.ConfigFlags
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
 
-	f     *forwarder.Forwarder
-	ready chan struct{}
+	compare bool
 
 	chartpath string
-	meta      *churl.MetadataReader
 }
 
 func CreateCommand(ca *common.CommonArgs) *cobra.Command {
@@ -46,7 +46,12 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 		Command: cobra.Command{
 			Use:   "latest CHARTNAME",
 			Short: "latest will return the metadata for the newest version of a chart",
-			Args:  cobra.MinimumNArgs(1),
+			Long: `latest will return the metadata for the newest version of a chart.
+
+When several museums are selected with --museum a,b,c or --all-museums, each
+museum is queried concurrently, and the results are tagged with the museum
+name.  A museum that fails is reported alongside the results of the others.`,
+			Args: cobra.MinimumNArgs(1),
 			PreRunE: func(cmd *cobra.Command, args []string) error {
 				return c.Preexecute(cmd, args)
 			},
@@ -58,13 +63,11 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 			},
 		},
 		CommonArgs: ca,
-		ready:      make(chan struct{}),
 	}
 
 	flgs := c.Flags()
 
-	// Config flag
-	flags.CreateConfigFlag(flgs)
+	flgs.BoolVar(&c.compare, "compare", false, "Show a table comparing the museums' versions")
 
 	c.cflags = genericclioptions.NewConfigFlags(false)
 	c.cflags.Namespace = nil
@@ -82,84 +85,124 @@ func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
 	c.chartpath = strings.Join(args, "/")
 
 	// Open the manifest file
-	m, err := manifest.OpenFromFile(viper.GetString(flags.ConfigKey))
+	m, museums, err := c.OpenManifestWithMuseums(flags.ReadAllMuseumsFlag(cmd.Flags()))
 	if err != nil {
-		return errors.Wrapf(err, "Failed to open manifest file")
+		return err
 	}
 	c.m = m
+	c.museums = museums
 
-	config, err := c.cflags.ToRESTConfig()
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to get REST config")
+		return cmdutil.UsageErrorf(cmd, err.Error())
 	}
 
-	f := cmdutil.NewFactory(c.cflags)
+	return nil
+}
 
-	// Get timeout from cobra.Command
-	podTimeout, err := cmdutil.GetPodRunningTimeoutFlag(cmd)
-	if err != nil {
-		return cmdutil.UsageErrorf(cmd, err.Error())
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	results := make([]*result, len(c.museums))
+
+	var wg sync.WaitGroup
+	wg.Add(len(c.museums))
+	for k, name := range c.museums {
+		go func(k int, name string) {
+			defer wg.Done()
+			results[k] = c.query(name)
+		}(k, name)
 	}
+	wg.Wait()
 
-	// Set up options and open port forward
-	options := []forwarder.Option{
-		forwarder.Out(os.Stderr),
-		forwarder.Err(os.Stderr),
-		forwarder.PodTimeout(podTimeout),
-		forwarder.Ready(c.ready),
+	if len(results) == 1 && !c.compare {
+		// A single museum keeps the original, untagged output.
+		if results[0].err != nil {
+			return results[0].err
+		}
+		return encode(results[0].Chart)
+	}
+
+	var err error
+	if c.compare {
+		err = writeComparison(os.Stdout, results)
+	} else {
+		err = encode(results)
 	}
-	c.f, err = forwarder.Open(f, config, c.m, options...)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to open forwarder")
+		return err
 	}
 
-	go func() {
-		err = c.f.ForwardPorts()
-		if err != nil {
-			c.Logger.Errorf("Failed to forward port: %s\n", err.Error())
+	failed := []string{}
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r.Museum)
 		}
-	}()
+	}
+	if len(failed) != 0 {
+		return errors.Errorf("Failed to query %d of %d museums: '%s'", len(failed), len(results), strings.Join(failed, "', '"))
+	}
 
-	c.Logger.Infof("Waiting for port forward to be ready...\n")
+	return nil
+}
 
-	// Set up requester into cm pod
-	c.meta, err = churl.NewMetadataReader(c.f.SourcePort)
-	if err != nil {
-		return errors.Wrapf(err, "Internal error: failed to create metadata reader")
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
 	}
 
 	return nil
 }
 
-func (c *command) Execute(cmd *cobra.Command, args []string) error {
-	// Wait until the forwarder is ready
-	<-c.ready
+// query connects to the museum, and gets the newest version of
+// the chart
+func (c *command) query(name string) *result {
+	r := &result{
+		Museum: name,
+	}
 
-	c.Logger.Infof("Ready\n")
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		r.setError(err)
+		return r
+	}
+	defer client.Close()
 
-	result, err := c.meta.Do(c.chartpath)
+	cv, err := client.Latest(ctx, c.chartpath)
 	if err != nil {
-		return errors.Wrapf(err, "Could not get get chart at '%s'", c.chartpath)
+		r.setError(errors.Wrapf(err, "Could not get get chart at '%s' from museum '%s'", c.chartpath, name))
+		return r
 	}
+	r.Chart = cv
 
+	return r
+}
+
+func encode(v interface{}) error {
 	enc := json.NewEncoder(os.Stdout)
-	err = enc.Encode(result)
+	err := enc.Encode(v)
 	if err != nil {
 		return errors.Wrapf(err, "Internal error: failed to encode returned chart")
 	}
-
 	return nil
 }
 
-func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
-	if c == nil {
-		return nil
-	}
+// result is the newest version of a chart in a museum, or the reason that it
+// could not be found
+type result struct {
+	Museum string             `json:"museum"`
+	Chart  *repo.ChartVersion `json:"chart,omitempty"`
+	Error  string             `json:"error,omitempty"`
 
-	if c.m != nil {
-		c.m.Close()
-		c.m = nil
-	}
+	err error
+}
 
-	return nil
+func (r *result) setError(err error) {
+	r.err = err
+	r.Error = err.Error()
 }
@@ -0,0 +1,133 @@
+//+build test_integration
+
+package latest
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Get_Latest(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0"},
+		&churltest.Chart{Name: "foo", Version: "1.1.0"},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "get", "latest", "foo", "--config", config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	actual := struct {
+		Version string `json:"version"`
+	}{}
+	json.NewDecoder(strings.NewReader(out)).Decode(&actual)
+	if actual.Version != "1.1.0" {
+		t.Errorf("Incorrect version; expected '1.1.0', actual '%s'", actual.Version)
+	}
+
+	// The response is now cached, and can be answered offline.
+	s.Close()
+	_, exitCode = ctesting.RunChurl(t, "get", "latest", "foo", "--config", config, "--offline")
+	if exitCode != 0 {
+		t.Errorf("Unexpected exit code %d from offline query", exitCode)
+	}
+}
+
+func Test_Cmd_Get_Latest_Errors(t *testing.T) {
+	tcs := []struct {
+		name     string
+		chart    string
+		fault    *churltest.Fault
+		expected int
+	}{
+		{name: "not found", chart: "bar", expected: 4},
+		{name: "server error", chart: "foo", fault: &churltest.Fault{Path: "/api/", Status: 500, Message: "storage unavailable"}, expected: 6},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+			defer s.Close()
+			if tc.fault != nil {
+				s.Inject(*tc.fault)
+			}
+
+			fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+			defer fixture.Close()
+			config := fixture.Config
+
+			_, exitCode := ctesting.RunChurl(t, "get", "latest", tc.chart, "--config", config)
+			if exitCode != tc.expected {
+				t.Errorf("Incorrect exit code; expected %d, actual %d", tc.expected, exitCode)
+			}
+		})
+	}
+}
+
+func Test_Cmd_Get_Latest_AllMuseums(t *testing.T) {
+	dev := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.1.0"})
+	defer dev.Close()
+	prod := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	defer prod.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: dev.URL}, ctesting.Museum{Name: "prod", URL: prod.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "get", "latest", "foo", "--config", config, "--all-museums")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	results := []*result{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode results:\n%s", err.Error())
+	}
+	if len(results) != 2 || results[0].Museum != "dev" || results[0].Chart.Version != "1.1.0" || results[1].Chart.Version != "1.0.0" {
+		t.Errorf("Incorrect results:\n%s", out)
+	}
+}
+
+func Test_Cmd_Get_Latest_RecordReplay(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.1.0"})
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+	cassette := path.Join(fixture.Root, "session.json")
+
+	_, exitCode := ctesting.RunChurl(t, "get", "latest", "foo", "--config", config, "--record", cassette)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+
+	// The cache is cleared, so that only the cassette can answer.
+	s.Close()
+	os.RemoveAll(fixture.Cache)
+
+	out, exitCode := ctesting.RunChurl(t, "get", "latest", "foo", "--config", config, "--replay", cassette)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d from replay", exitCode)
+	}
+	if !strings.Contains(out, `"1.1.0"`) {
+		t.Errorf("Incorrect replayed output:\n%s", out)
+	}
+
+	_, exitCode = ctesting.RunChurl(t, "get", "latest", "foo", "--config", config, "--record", cassette, "--replay", cassette)
+	if exitCode != 3 {
+		t.Errorf("Incorrect exit code for --record with --replay; expected 3, actual %d", exitCode)
+	}
+}
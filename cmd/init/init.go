@@ -10,7 +10,6 @@ import (
 	"github.com/object88/churl/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 type command struct {
@@ -32,17 +31,16 @@ func CreateCommand(ca *common.CommonArgs) *cobra.Command {
 		CommonArgs: ca,
 	}
 
-	flgs := c.Flags()
-
-	flags.CreateConfigFlag(flgs)
-
 	return traverse.TraverseRunHooks(&c.Command)
 }
 
 func (c *command) Execute(cmd *cobra.Command, args []string) error {
-	configFile := viper.GetString(flags.ConfigKey)
-	configDir := path.Base(configFile)
-	err := os.MkdirAll(configDir, 0755)
+	configFile, err := flags.ReadConfigFile()
+	if err != nil {
+		return err
+	}
+	configDir := path.Dir(configFile)
+	err = os.MkdirAll(configDir, 0755)
 	if err != nil {
 		return errors.Wrapf(err, "cannot create config directory '%s'", configDir)
 	}
@@ -58,7 +56,7 @@ func (c *command) Execute(cmd *cobra.Command, args []string) error {
 
 	defer m.Close()
 
-	c.Logger.Infof("Created config file at '%s'", configFile)
+	c.Logger.Infof("Created config file at '%s'\n", configFile)
 
 	return nil
 }
@@ -0,0 +1,63 @@
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+
+	"github.com/object88/churl"
+	"github.com/pkg/errors"
+)
+
+// copyItems copies each item whose action is Copy from the client's museum to
+// the target.  An item that fails to copy records its error, and the others
+// are still copied; the number of failures is returned.
+func copyItems(ctx context.Context, client *churl.Client, t target, items []*item) int {
+	failed := 0
+	for _, it := range items {
+		if it.Action != Copy {
+			continue
+		}
+		if err := copyItem(ctx, client, t, it); err != nil {
+			it.Error = err.Error()
+			failed++
+			continue
+		}
+		it.Copied = true
+	}
+	return failed
+}
+
+func copyItem(ctx context.Context, client *churl.Client, t target, it *item) error {
+	rc, err := client.DownloadVersion(ctx, it.cv)
+	if err != nil {
+		return err
+	}
+	archive, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to download chart '%s' version '%s'", it.Chart, it.Version)
+	}
+
+	// The digest is verified here as well as by the cache, so that an archive
+	// that is not cached is not mirrored unverified.
+	h := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(h[:]); it.Digest != "" && actual != it.Digest {
+		return errors.Errorf("Digest mismatch for chart '%s' version '%s': expected '%s', actual '%s'", it.Chart, it.Version, it.Digest, actual)
+	}
+
+	prov, err := client.Provenance(ctx, it.cv)
+	if churl.KindOf(err) == churl.KindNotFound {
+		prov, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = t.put(ctx, it.cv, archive, prov); err != nil {
+		return err
+	}
+	it.Provenance = len(prov) != 0
+	return nil
+}
@@ -0,0 +1,312 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	from             string
+	to               string
+	constraintString string
+	dryRun           bool
+	upload           bool
+
+	charts     map[string]bool
+	constraint *semver.Constraints
+
+	// toMuseum is set if the target is a museum; otherwise, `to` is a
+	// directory
+	toMuseum bool
+}
+
+// CreateCommand returns the 'mirror' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "mirror --to DIR|MUSEUM [CHART...]",
+			Short: "mirror copies charts from a museum to a directory or another museum",
+			Long: `mirror compares the index of the --from museum, or the current museum,
+against the target's, and copies each version of CHART, or of every chart,
+that the target does not have.  --constraint limits the versions that are
+copied.  Archives are verified against their digests, and provenance files are
+copied with them.
+
+If --to is a museum in the manifest, the charts are uploaded to it; because
+this modifies the museum, --upload is required.  Otherwise, --to is a
+directory, which is written as a helm repository: the archives, their
+provenance files, and an index.yaml.  A new directory that could be mistaken
+for a museum name must be written as a path, i.e., './backup'.
+
+A version that the target has with a different digest is a conflict; it is
+not overwritten, and mirror fails once the other versions are copied.  With
+--dry-run, the plan is reported, and nothing is copied.`,
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringVar(&c.from, "from", "", "Museum to copy charts from; the current museum if not set")
+	flgs.StringVar(&c.to, "to", "", "Directory or museum to copy charts to")
+	flgs.StringVar(&c.constraintString, "constraint", "", "Version constraint that copied versions must satisfy, i.e., '>=1.0.0'")
+	flgs.BoolVar(&c.dryRun, "dry-run", false, "Report the plan without copying anything")
+	flgs.BoolVar(&c.upload, "upload", false, "Allow uploading charts to a museum")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.charts = map[string]bool{}
+	for _, arg := range args {
+		c.charts[strings.TrimSpace(arg)] = true
+	}
+
+	if c.to == "" {
+		return cmdutil.UsageErrorf(cmd, "--to is required")
+	}
+
+	var err error
+	if c.constraintString != "" {
+		c.constraint, err = semver.NewConstraint(c.constraintString)
+		if err != nil {
+			return &churl.ConfigError{Err: errors.Wrapf(err, "Invalid version constraint '%s'", c.constraintString)}
+		}
+	}
+
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	if c.from == "" {
+		c.from = c.m.CurrentName()
+	} else if _, ok := c.m.Museums[c.from]; !ok {
+		return &churl.ConfigError{Err: errors.Errorf("Museum '%s' is not in the manifest; known museums are '%s'", c.from, strings.Join(c.m.Names(), "', '"))}
+	}
+
+	if err = c.resolveTarget(); err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+// resolveTarget decides whether --to is a museum or a directory.  A museum in
+// the manifest is a museum, unless it is written as a path.  Anything else is
+// a directory if it is written as a path or already exists, so that a
+// mistyped museum name does not create a directory.
+func (c *command) resolveTarget() error {
+	_, museum := c.m.Museums[c.to]
+	path := strings.ContainsRune(c.to, filepath.Separator) || strings.ContainsRune(c.to, '/')
+	_, statErr := os.Stat(c.to)
+
+	switch {
+	case museum && !path:
+		c.toMuseum = true
+		if c.to == c.from {
+			return &churl.ConfigError{Err: errors.Errorf("Cannot mirror museum '%s' to itself", c.to)}
+		}
+		if !c.upload && !c.dryRun {
+			return &churl.ConfigError{Err: errors.Errorf("Mirroring to museum '%s' uploads charts to it; pass --upload to allow this", c.to)}
+		}
+	case path, statErr == nil:
+	default:
+		return &churl.ConfigError{Err: errors.Errorf("'%s' is neither a museum in the manifest nor a directory; known museums are '%s', and a new directory must be written as a path, i.e., './%s'", c.to, strings.Join(c.m.Names(), "', '"), c.to)}
+	}
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, c.from, c.m.Museums[c.from])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	index, err := client.Index(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "Could not get the index of museum '%s'", c.from)
+	}
+
+	var t target
+	if c.toMuseum {
+		tc, err := c.Connect(ctx, c.cflags, c.podTimeout, c.to, c.m.Museums[c.to])
+		if err != nil {
+			return err
+		}
+		defer tc.Close()
+		t = &museumTarget{client: tc}
+	} else {
+		if !c.dryRun {
+			if err = os.MkdirAll(c.to, 0755); err != nil {
+				return errors.Wrapf(err, "Failed to create directory '%s'", c.to)
+			}
+		}
+		t = &dirTarget{dir: c.to}
+	}
+
+	existing, err := t.index(ctx)
+	if err != nil {
+		return err
+	}
+	items := plan(index, existing, c.charts, c.constraint)
+
+	failed := 0
+	if !c.dryRun {
+		failed = copyItems(ctx, client, t, items)
+		if err = t.finish(); err != nil {
+			return err
+		}
+	}
+
+	if err = c.write(os.Stdout, items); err != nil {
+		return err
+	}
+
+	conflicts := 0
+	for _, it := range items {
+		if it.Action == Conflict {
+			conflicts++
+		}
+	}
+	switch {
+	case failed != 0:
+		return errors.Errorf("Failed to copy %d chart versions from museum '%s' to '%s'", failed, c.from, c.to)
+	case conflicts != 0:
+		return errors.Errorf("%d chart versions in '%s' differ from museum '%s', and were not copied", conflicts, c.to, c.from)
+	}
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+func (c *command) write(w io.Writer, items []*item) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(items); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode mirror plan")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(items)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode mirror plan")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if c.dryRun {
+		fmt.Fprintln(tw, "CHART\tVERSION\tACTION")
+	} else {
+		fmt.Fprintln(tw, "CHART\tVERSION\tACTION\tRESULT")
+	}
+	counts := map[Action]int{}
+	for _, it := range items {
+		counts[it.Action]++
+		if c.dryRun {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", it.Chart, it.Version, it.Action)
+			continue
+		}
+		result := ""
+		switch {
+		case it.Error != "":
+			result = "failed"
+		case it.Copied && it.Provenance:
+			result = "copied, with provenance"
+		case it.Copied:
+			result = "copied"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", it.Chart, it.Version, it.Action, result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		if it.Error != "" {
+			fmt.Fprintf(w, "%s-%s: %s\n", it.Chart, it.Version, it.Error)
+		}
+	}
+	fmt.Fprintf(w, "%d to copy, %d present, %d conflicting\n", counts[Copy], counts[Present], counts[Conflict])
+	return nil
+}
@@ -0,0 +1,96 @@
+//+build test_integration
+
+package mirror
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Cmd_Mirror(t *testing.T) {
+	prod := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"},
+		&churltest.Chart{Name: "foo", Version: "1.1.0"},
+		&churltest.Chart{Name: "bar", Version: "0.1.0"},
+	)
+	defer prod.Close()
+	airgap := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"})
+	defer airgap.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "prod", URL: prod.URL}, ctesting.Museum{Name: "airgap", URL: airgap.URL})
+	defer fixture.Close()
+	root, config := fixture.Root, fixture.Config
+
+	mirror := func(args ...string) ([]*item, int) {
+		args = append([]string{"mirror", "--config", config, "--output", "json"}, args...)
+		out, exitCode := ctesting.RunChurl(t, args...)
+		items := []*item{}
+		if exitCode == 0 {
+			if err := json.NewDecoder(strings.NewReader(out)).Decode(&items); err != nil {
+				t.Fatalf("Failed to decode plan:\n%s", err.Error())
+			}
+		}
+		return items, exitCode
+	}
+
+	// To a directory
+	dir := path.Join(root, "snapshot")
+	if items, exitCode := mirror("--to", dir, "--dry-run"); exitCode != 0 || len(items) != 3 {
+		t.Fatalf("Incorrect dry run; exit code %d, %d items", exitCode, len(items))
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Dry run created the directory")
+	}
+	if _, exitCode := mirror("--to", dir, "foo"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	i, err := repo.LoadIndexFile(path.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load mirrored index:\n%s", err.Error())
+	}
+	if !i.Has("foo", "1.0.0") || !i.Has("foo", "1.1.0") || i.Has("bar", "0.1.0") {
+		t.Errorf("Incorrect mirrored index")
+	}
+	if b, _ := ioutil.ReadFile(path.Join(dir, "foo-1.0.0.tgz.prov")); string(b) != "signature" {
+		t.Errorf("Incorrect provenance file '%s'", string(b))
+	}
+	items, exitCode := mirror("--to", dir)
+	if exitCode != 0 || len(items) != 3 || items[0].Action != Copy || items[1].Action != Present {
+		t.Errorf("Incorrect second mirror; exit code %d, items %v", exitCode, items)
+	}
+
+	// To a museum
+	if _, exitCode = mirror("--to", "airgap"); exitCode != 3 {
+		t.Errorf("Unexpected exit code %d without --upload", exitCode)
+	}
+	if _, exitCode = mirror("--to", "typo"); exitCode != 3 {
+		t.Errorf("Unexpected exit code %d for an unknown target", exitCode)
+	}
+	items, exitCode = mirror("--to", "airgap", "--upload", "--constraint", "<2.0.0")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	for _, it := range items {
+		if it.Action == Copy && !it.Copied {
+			t.Errorf("'%s-%s' was not copied: %s", it.Chart, it.Version, it.Error)
+		}
+	}
+	if airgap.Digest("foo", "1.1.0") != prod.Digest("foo", "1.1.0") || airgap.Digest("bar", "0.1.0") == "" {
+		t.Errorf("Charts were not uploaded")
+	}
+
+	// A version that differs is not overwritten, and fails the mirror.
+	airgap.RemoveChart("bar", "0.1.0")
+	airgap.AddChart(&churltest.Chart{Name: "bar", Version: "0.1.0", Description: "changed"})
+	if _, exitCode = mirror("--to", "airgap", "--upload"); exitCode != 1 {
+		t.Errorf("Unexpected exit code %d for a conflict", exitCode)
+	}
+}
@@ -0,0 +1,85 @@
+package mirror
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Action is what mirror does with a chart version
+type Action string
+
+const (
+	// Copy is a chart version that the target does not have
+	Copy Action = "copy"
+
+	// Present is a chart version that the target has, with the same digest
+	Present Action = "present"
+
+	// Conflict is a chart version that the target has, with a different
+	// digest; it is not overwritten
+	Conflict Action = "conflict"
+)
+
+// item is a chart version in the source, and what is done with it
+type item struct {
+	Chart      string `json:"chart"`
+	Version    string `json:"version"`
+	Digest     string `json:"digest"`
+	Action     Action `json:"action"`
+	Copied     bool   `json:"copied,omitempty"`
+	Provenance bool   `json:"provenance,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	cv *repo.ChartVersion
+}
+
+// plan compares the source index against the target's, and returns an item
+// for each version in the source of a chart in `charts`, or of every chart if
+// `charts` is empty, that satisfies `constraint`, if set.  Items are sorted by
+// chart, and newest version first.
+func plan(source, target *repo.IndexFile, charts map[string]bool, constraint *semver.Constraints) []*item {
+	names := make([]string, 0, len(source.Entries))
+	for name := range source.Entries {
+		if len(charts) == 0 || charts[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := []*item{}
+	for _, name := range names {
+		// The index entries are sorted, newest first.
+		for _, cv := range source.Entries[name] {
+			if constraint != nil {
+				v, err := semver.NewVersion(cv.Version)
+				if err != nil || !constraint.Check(v) {
+					continue
+				}
+			}
+
+			it := &item{Chart: name, Version: cv.Version, Digest: cv.Digest, Action: Copy, cv: cv}
+			if existing := find(target, name, cv.Version); existing != nil {
+				if existing.Digest == cv.Digest {
+					it.Action = Present
+				} else {
+					it.Action = Conflict
+				}
+			}
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// find returns the entry for exactly `version` of the chart; unlike
+// IndexFile.Get, a version is not treated as a constraint
+func find(index *repo.IndexFile, name, version string) *repo.ChartVersion {
+	for _, cv := range index.Entries[name] {
+		if cv.Version == version {
+			return cv
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mirror
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func testIndex(versions ...string) *repo.IndexFile {
+	i := repo.NewIndexFile()
+	for _, v := range versions {
+		var name, version, digest string
+		fmt.Sscanf(v, "%s %s %s", &name, &version, &digest)
+		i.Add(&chart.Metadata{Name: name, Version: version}, fmt.Sprintf("charts/%s-%s.tgz", name, version), "", digest)
+	}
+	i.SortEntries()
+	return i
+}
+
+func Test_Mirror_Plan(t *testing.T) {
+	source := testIndex("foo 1.0.0 a", "foo 1.1.0 b", "foo 2.0.0-rc.1 c", "bar 0.1.0 d")
+	target := testIndex("foo 1.0.0 a", "bar 0.1.0 x")
+
+	tcs := []struct {
+		name       string
+		charts     []string
+		constraint string
+		expected   []string
+	}{
+		{name: "everything", expected: []string{"bar-0.1.0 conflict", "foo-2.0.0-rc.1 copy", "foo-1.1.0 copy", "foo-1.0.0 present"}},
+		{name: "chart", charts: []string{"foo"}, expected: []string{"foo-2.0.0-rc.1 copy", "foo-1.1.0 copy", "foo-1.0.0 present"}},
+		{name: "constraint", charts: []string{"foo"}, constraint: ">1.0.0", expected: []string{"foo-1.1.0 copy"}},
+		{name: "no match", charts: []string{"baz"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			charts := map[string]bool{}
+			for _, c := range tc.charts {
+				charts[c] = true
+			}
+			var constraint *semver.Constraints
+			if tc.constraint != "" {
+				constraint, _ = semver.NewConstraint(tc.constraint)
+			}
+
+			items := plan(source, target, charts, constraint)
+			if len(items) != len(tc.expected) {
+				t.Fatalf("Incorrect number of items; expected %d, actual %d", len(tc.expected), len(items))
+			}
+			for k, it := range items {
+				if actual := fmt.Sprintf("%s-%s %s", it.Chart, it.Version, it.Action); actual != tc.expected[k] {
+					t.Errorf("Incorrect item %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+			}
+		})
+	}
+}
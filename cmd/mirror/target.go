@@ -0,0 +1,178 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/object88/churl"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+const (
+	indexName     = "index.yaml"
+	provenanceExt = ".prov"
+)
+
+// target is where chart versions are mirrored to
+type target interface {
+	// index returns the chart versions that the target has
+	index(ctx context.Context) (*repo.IndexFile, error)
+
+	// put adds the archive and provenance file, if any, of a chart version
+	put(ctx context.Context, cv *repo.ChartVersion, archive, prov []byte) error
+
+	// finish completes the mirror, once every chart version has been put
+	finish() error
+}
+
+// archiveName returns the file name of a chart version's archive, as the
+// museum names it
+func archiveName(cv *repo.ChartVersion) string {
+	if len(cv.URLs) != 0 {
+		p := cv.URLs[0]
+		if u, err := url.Parse(p); err == nil {
+			p = u.Path
+		}
+		if name := path.Base(p); name != "." && name != "/" {
+			return name
+		}
+	}
+	return cv.Name + "-" + cv.Version + ".tgz"
+}
+
+// dirTarget mirrors to a directory, which is laid out as a helm repository:
+// the archives, their provenance files, and an index.yaml that refers to them
+type dirTarget struct {
+	dir string
+
+	idx     *repo.IndexFile
+	changed bool
+}
+
+func (t *dirTarget) index(ctx context.Context) (*repo.IndexFile, error) {
+	if t.idx != nil {
+		return t.idx, nil
+	}
+
+	p := filepath.Join(t.dir, indexName)
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		t.idx = repo.NewIndexFile()
+		t.changed = true
+		return t.idx, nil
+	}
+	idx, err := repo.LoadIndexFile(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read index '%s'", p)
+	}
+
+	// An entry whose archive is missing is mirrored again.
+	for name, cvs := range idx.Entries {
+		kept := make(repo.ChartVersions, 0, len(cvs))
+		for _, cv := range cvs {
+			if _, err := os.Stat(filepath.Join(t.dir, archiveName(cv))); err == nil {
+				kept = append(kept, cv)
+			}
+		}
+		if len(kept) != len(cvs) {
+			t.changed = true
+		}
+		if len(kept) == 0 {
+			delete(idx.Entries, name)
+		} else {
+			idx.Entries[name] = kept
+		}
+	}
+
+	t.idx = idx
+	return t.idx, nil
+}
+
+func (t *dirTarget) put(ctx context.Context, cv *repo.ChartVersion, archive, prov []byte) error {
+	name := archiveName(cv)
+	if err := writeFile(filepath.Join(t.dir, name), archive); err != nil {
+		return err
+	}
+	if len(prov) != 0 {
+		if err := writeFile(filepath.Join(t.dir, name+provenanceExt), prov); err != nil {
+			return err
+		}
+	}
+
+	// The entry refers to the archive relative to the index, so that the
+	// directory can be served, or copied, as it is.
+	entry := &repo.ChartVersion{
+		Metadata: cv.Metadata,
+		URLs:     []string{name},
+		Created:  cv.Created,
+		Digest:   cv.Digest,
+	}
+	if entry.Created.IsZero() {
+		entry.Created = time.Now()
+	}
+	t.idx.Entries[cv.Name] = append(t.idx.Entries[cv.Name], entry)
+	t.changed = true
+	return nil
+}
+
+func (t *dirTarget) finish() error {
+	if !t.changed {
+		return nil
+	}
+	t.idx.SortEntries()
+	t.idx.Generated = time.Now()
+
+	p := filepath.Join(t.dir, indexName)
+	if err := t.idx.WriteFile(p, 0644); err != nil {
+		return errors.Wrapf(err, "Failed to write index '%s'", p)
+	}
+	return nil
+}
+
+// writeFile writes to a temporary file and renames it, so that an interrupted
+// mirror does not leave a partial archive
+func writeFile(target string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create '%s'", target)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), target)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write '%s'", target)
+	}
+	return nil
+}
+
+// museumTarget mirrors to a museum, by uploading each chart version
+type museumTarget struct {
+	client *churl.Client
+}
+
+func (t *museumTarget) index(ctx context.Context) (*repo.IndexFile, error) {
+	idx, err := t.client.Index(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not get the index of museum '%s'", t.client.Name())
+	}
+	return idx, nil
+}
+
+func (t *museumTarget) put(ctx context.Context, cv *repo.ChartVersion, archive, prov []byte) error {
+	return t.client.Upload(ctx, archiveName(cv), archive, prov)
+}
+
+func (t *museumTarget) finish() error {
+	return nil
+}
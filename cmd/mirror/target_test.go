@@ -0,0 +1,62 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Mirror_DirTarget(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	ctx := context.Background()
+	cv := &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"},
+		URLs:     []string{"http://museum.example.com/charts/foo-1.0.0.tgz"},
+		Digest:   "abc",
+	}
+
+	d := &dirTarget{dir: root}
+	if _, err := d.index(ctx); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err := d.put(ctx, cv, []byte("archive"), []byte("signature")); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err := d.finish(); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	for _, name := range []string{"foo-1.0.0.tgz", "foo-1.0.0.tgz.prov", "index.yaml"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("Missing '%s':\n%s", name, err.Error())
+		}
+	}
+
+	i, err := repo.LoadIndexFile(filepath.Join(root, "index.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	entry := find(i, "foo", "1.0.0")
+	if entry == nil || entry.Digest != "abc" || len(entry.URLs) != 1 || entry.URLs[0] != "foo-1.0.0.tgz" {
+		t.Errorf("Incorrect index entry: %+v", entry)
+	}
+
+	// An entry whose archive was removed is not present.
+	os.Remove(filepath.Join(root, "foo-1.0.0.tgz"))
+	d = &dirTarget{dir: root}
+	i, err = d.index(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if find(i, "foo", "1.0.0") != nil {
+		t.Errorf("Entry without an archive is present")
+	}
+}
@@ -0,0 +1,171 @@
+package outdated
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/object88/churl/internal/resolve"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Status is the outcome of comparing a release against the museum
+type Status string
+
+const (
+	// Current is a release of the newest version that the constraint allows
+	Current Status = "current"
+
+	// Outdated is a release of an older version than the constraint allows
+	Outdated Status = "outdated"
+
+	// Missing is a release of a chart that the museum has no allowed
+	// version of
+	Missing Status = "missing"
+
+	// Unknown is a release whose version could not be compared
+	Unknown Status = "unknown"
+)
+
+// Levels of the newest version's difference from the release's version
+const (
+	levelMajor = "major"
+	levelMinor = "minor"
+	levelPatch = "patch"
+)
+
+// comparison is a release, and the newest version of its chart
+type comparison struct {
+	Namespace  string `json:"namespace"`
+	Release    string `json:"release"`
+	Revision   int    `json:"revision"`
+	Helm       int    `json:"helm"`
+	Chart      string `json:"chart"`
+	Current    string `json:"current"`
+	Latest     string `json:"latest,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Behind     int    `json:"behind"`
+	Level      string `json:"level,omitempty"`
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// constraints are the version constraints that the newest version must
+// satisfy; by chart, or for every chart
+type constraints struct {
+	all     string
+	byChart map[string]string
+}
+
+// parseConstraints parses each of `values` as either 'CONSTRAINT', for every
+// chart, or 'CHART=CONSTRAINT'
+func parseConstraints(values []string) (*constraints, error) {
+	cs := &constraints{byChart: map[string]string{}}
+	for _, v := range values {
+		chart, constraint := "", strings.TrimSpace(v)
+		if k := strings.Index(v, "="); k > 0 && !strings.ContainsAny(v[:k], "<>!=~^ ") {
+			chart, constraint = strings.TrimSpace(v[:k]), strings.TrimSpace(v[k+1:])
+		}
+		if _, err := semver.NewConstraint(constraint); err != nil {
+			return nil, errors.Wrapf(err, "Invalid version constraint '%s'", v)
+		}
+		if chart == "" {
+			cs.all = constraint
+		} else {
+			cs.byChart[chart] = constraint
+		}
+	}
+	return cs, nil
+}
+
+func (cs *constraints) forChart(chart string) string {
+	if c, ok := cs.byChart[chart]; ok {
+		return c
+	}
+	return cs.all
+}
+
+// compare compares a release against the versions of its chart in the index
+func compare(index *repo.IndexFile, r *release, cs *constraints) *comparison {
+	c := &comparison{
+		Namespace:  r.Namespace,
+		Release:    r.Name,
+		Revision:   r.Revision,
+		Helm:       r.Helm,
+		Chart:      r.Chart,
+		Current:    r.Version,
+		Constraint: cs.forChart(r.Chart),
+	}
+
+	if r.Error != "" {
+		c.Status = Unknown
+		c.Error = r.Error
+		return c
+	}
+
+	current, err := semver.NewVersion(r.Version)
+	if err != nil {
+		c.Status = Unknown
+		c.Error = errors.Wrapf(err, "Release has an invalid chart version '%s'", r.Version).Error()
+		return c
+	}
+
+	c.Latest, _, err = resolve.Newest(index, r.Chart, c.Constraint)
+	if err != nil {
+		c.Status = Unknown
+		c.Error = err.Error()
+		return c
+	}
+	if c.Latest == "" {
+		c.Status = Missing
+		return c
+	}
+	latest, err := semver.NewVersion(c.Latest)
+	if err != nil {
+		c.Status = Unknown
+		c.Error = err.Error()
+		return c
+	}
+	if !current.LessThan(latest) {
+		c.Status = Current
+		return c
+	}
+
+	c.Status = Outdated
+	c.Level = level(current, latest)
+
+	// Count the allowed versions that were published since the release's
+	// version; i.e., the upgrades that the release has missed.
+	constraint, _ := semver.NewConstraint(orAny(c.Constraint))
+	seen := map[string]bool{}
+	for _, cv := range index.Entries[r.Chart] {
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil || seen[v.String()] {
+			continue
+		}
+		if v.GreaterThan(current) && !v.GreaterThan(latest) && constraint.Check(v) {
+			seen[v.String()] = true
+			c.Behind++
+		}
+	}
+	return c
+}
+
+// level returns the most significant part of the version that differs
+func level(current, latest *semver.Version) string {
+	switch {
+	case current.Major() != latest.Major():
+		return levelMajor
+	case current.Minor() != latest.Minor():
+		return levelMinor
+	default:
+		return levelPatch
+	}
+}
+
+func orAny(constraint string) string {
+	if constraint == "" {
+		return "*"
+	}
+	return constraint
+}
@@ -0,0 +1,76 @@
+package outdated
+
+import (
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func testIndex() *repo.IndexFile {
+	i := repo.NewIndexFile()
+	for _, v := range []string{"8.0.0", "8.0.1", "8.1.0", "9.0.0", "9.1.0-rc.1"} {
+		i.Entries["postgresql"] = append(i.Entries["postgresql"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "postgresql", Version: v}})
+	}
+	i.SortEntries()
+	return i
+}
+
+func Test_Outdated_Compare(t *testing.T) {
+	tcs := []struct {
+		name        string
+		chart       string
+		version     string
+		constraints []string
+		status      Status
+		latest      string
+		behind      int
+		level       string
+	}{
+		{name: "current", chart: "postgresql", version: "9.0.0", status: Current, latest: "9.0.0"},
+		{name: "newer than museum", chart: "postgresql", version: "9.2.0", status: Current, latest: "9.0.0"},
+		{name: "major", chart: "postgresql", version: "8.0.0", status: Outdated, latest: "9.0.0", behind: 3, level: "major"},
+		{name: "minor", chart: "postgresql", version: "8.0.1", constraints: []string{"<9.0.0"}, status: Outdated, latest: "8.1.0", behind: 1, level: "minor"},
+		{name: "patch", chart: "postgresql", version: "8.0.0", constraints: []string{"postgresql=~8.0"}, status: Outdated, latest: "8.0.1", behind: 1, level: "patch"},
+		{name: "chart constraint wins", chart: "postgresql", version: "8.1.0", constraints: []string{"postgresql=^8.0.0", "<8.1.0"}, status: Current, latest: "8.1.0"},
+		{name: "missing", chart: "redis", version: "1.0.0", status: Missing},
+		{name: "no allowed version", chart: "postgresql", version: "8.0.0", constraints: []string{">=10.0.0"}, status: Missing},
+		{name: "invalid version", chart: "postgresql", version: "latest", status: Unknown},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cs, err := parseConstraints(tc.constraints)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			c := compare(testIndex(), &release{Name: "db", Namespace: "prod", Chart: tc.chart, Version: tc.version}, cs)
+			if c.Status != tc.status {
+				t.Errorf("Incorrect status; expected '%s', actual '%s'", tc.status, c.Status)
+			}
+			if c.Latest != tc.latest {
+				t.Errorf("Incorrect latest version; expected '%s', actual '%s'", tc.latest, c.Latest)
+			}
+			if c.Behind != tc.behind || c.Level != tc.level {
+				t.Errorf("Incorrect distance; expected %d (%s), actual %d (%s)", tc.behind, tc.level, c.Behind, c.Level)
+			}
+		})
+	}
+}
+
+func Test_Outdated_ParseConstraints(t *testing.T) {
+	cs, err := parseConstraints([]string{">=1.0.0, <2.0.0", "web=~1.2", "=1.5.0"})
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if cs.forChart("web") != "~1.2" {
+		t.Errorf("Incorrect constraint for 'web': '%s'", cs.forChart("web"))
+	}
+	if cs.forChart("db") != "=1.5.0" {
+		t.Errorf("Incorrect constraint for 'db': '%s'", cs.forChart("db"))
+	}
+
+	if _, err := parseConstraints([]string{"web=not a constraint"}); err == nil {
+		t.Errorf("Expected error")
+	}
+}
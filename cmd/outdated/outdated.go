@@ -0,0 +1,266 @@
+package outdated
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// Namespace that Tiller stores Helm 2 releases in, unless told otherwise
+	defaultTillerNamespace = "kube-system"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	allNamespaces   bool
+	tillerNamespace string
+	constraintArgs  []string
+	exitCode        bool
+
+	namespace   string
+	constraints *constraints
+}
+
+// CreateCommand returns the 'outdated' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "outdated",
+			Short: "outdated lists the helm releases in the cluster that are behind the museum",
+			Long: `outdated lists the helm releases in the cluster, and compares the version of
+each release's chart against the newest version in the museum.
+
+Helm 3 releases are read from their secrets, and Helm 2 releases from Tiller's
+config maps in --tiller-namespace.  Releases are listed in the namespace, or,
+with --all-namespaces, in every namespace.  The cluster is the current kube
+context, or --context.
+
+The newest version of each chart can be limited with --constraint, either for
+every chart, i.e., '--constraint "<2.0.0"', or for one chart, i.e.,
+'--constraint "postgresql=~8.1"'.  The flag may be repeated.
+
+Each release is reported with the newest version, and how far behind it is:
+the number of newer versions, and whether the newest is a major, minor, or
+patch upgrade.  With --exit-code, outdated exits 9 if any release is behind, so
+that a CI gate can tell outdated releases from a check that failed.`,
+			Args: cobra.NoArgs,
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVarP(&c.allNamespaces, "all-namespaces", "A", false, "List the releases in every namespace")
+	flgs.StringVar(&c.tillerNamespace, "tiller-namespace", defaultTillerNamespace, "Namespace that Tiller stores Helm 2 releases in")
+	flgs.StringArrayVar(&c.constraintArgs, "constraint", []string{}, "Version constraint that the newest version must satisfy, as 'CONSTRAINT' or 'CHART=CONSTRAINT'")
+	flgs.BoolVar(&c.exitCode, "exit-code", false, "Fail if any release is behind the museum")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.constraints, err = parseConstraints(c.constraintArgs)
+	if err != nil {
+		return &churl.ConfigError{Err: err}
+	}
+
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	if !c.allNamespaces {
+		c.namespace, _, err = c.cflags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return &churl.ConfigError{Err: errors.Wrapf(err, "Failed to get the namespace from the kube config")}
+		}
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	config, err := c.cflags.ToRESTConfig()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get REST config")
+	}
+	cs, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create kubernetes client")
+	}
+
+	releases, err := listReleases(cs, c.namespace, c.tillerNamespace)
+	if err != nil {
+		return err
+	}
+
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	index, err := client.Index(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "Could not get the index of museum '%s'", name)
+	}
+
+	comparisons := make([]*comparison, 0, len(releases))
+	outdated := 0
+	for _, r := range releases {
+		cmp := compare(index, r, c.constraints)
+		if cmp.Status == Outdated {
+			outdated++
+		}
+		comparisons = append(comparisons, cmp)
+	}
+
+	if err := c.write(os.Stdout, comparisons); err != nil {
+		return err
+	}
+
+	if c.exitCode && outdated != 0 {
+		return &BehindError{Museum: name, Outdated: outdated, Total: len(comparisons)}
+	}
+
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+func (c *command) write(w io.Writer, comparisons []*comparison) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(comparisons); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode releases")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(comparisons)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode releases")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tRELEASE\tCHART\tCURRENT\tLATEST\tBEHIND\tSTATUS")
+	for _, cmp := range comparisons {
+		behind := ""
+		if cmp.Status == Outdated {
+			behind = fmt.Sprintf("%d (%s)", cmp.Behind, cmp.Level)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", cmp.Namespace, cmp.Release, cmp.Chart, cmp.Current, cmp.Latest, behind, cmp.Status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, cmp := range comparisons {
+		if cmp.Error != "" {
+			fmt.Fprintf(w, "%s/%s: %s\n", cmp.Namespace, cmp.Release, cmp.Error)
+		}
+	}
+	return nil
+}
+
+// KindOutdated is the kind of BehindError; it is not a failure of the library,
+// so churl does not define it
+const KindOutdated churl.ErrorKind = "outdated"
+
+// BehindError is returned with --exit-code when releases are behind the museum
+type BehindError struct {
+	Museum   string
+	Outdated int
+	Total    int
+}
+
+func (e *BehindError) Error() string {
+	return fmt.Sprintf("%d of %d releases are behind museum '%s'", e.Outdated, e.Total, e.Museum)
+}
+
+// ExitCode is cmd.ExitOutdated, so that CI can tell outdated releases from a
+// check that could not run
+func (e *BehindError) ExitCode() int {
+	return 9
+}
+
+// Kind is KindOutdated
+func (e *BehindError) Kind() churl.ErrorKind {
+	return KindOutdated
+}
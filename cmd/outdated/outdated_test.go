@@ -0,0 +1,20 @@
+package outdated
+
+import (
+	"testing"
+
+	"github.com/object88/churl"
+	"github.com/pkg/errors"
+)
+
+func Test_Outdated_BehindError(t *testing.T) {
+	err := errors.Wrapf(&BehindError{Museum: "dev", Outdated: 1, Total: 2}, "Check failed")
+
+	if actual := churl.KindOf(err); actual != KindOutdated {
+		t.Errorf("Incorrect kind; expected '%s', actual '%s'", KindOutdated, actual)
+	}
+	e, ok := errors.Cause(err).(interface{ ExitCode() int })
+	if !ok || e.ExitCode() != 9 {
+		t.Errorf("Incorrect exit code for %#v", errors.Cause(err))
+	}
+}
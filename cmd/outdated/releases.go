@@ -0,0 +1,246 @@
+package outdated
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	rspb "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+const (
+	// Helm 3 stores each revision of a release in a secret in the release's
+	// namespace, labelled with the release's name, revision, and status
+	helm3Selector = "owner=helm"
+	helm3Deleted  = "uninstalled"
+
+	// Helm 2 stores each revision of a release in a config map in Tiller's
+	// namespace, labelled the same way, in upper case
+	helm2Selector = "OWNER=TILLER"
+	helm2Deleted  = "DELETED"
+
+	releaseKey = "release"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// release is the newest revision of a deployed helm release
+type release struct {
+	Name       string
+	Namespace  string
+	Revision   int
+	Helm       int
+	Chart      string
+	Version    string
+	AppVersion string
+
+	// Error is why the release could not be decoded; the chart and its
+	// version are not known
+	Error string
+}
+
+// helm3Release is the part of a Helm 3 release that churl reads
+type helm3Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Chart     struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// stored is a revision of a release, as labelled by its secret or config map,
+// and its encoded release
+type stored struct {
+	name      string
+	namespace string
+	revision  int
+	status    string
+	data      []byte
+}
+
+// listReleases returns the newest revision of each release in `namespace`, or
+// in every namespace if it is empty.  Helm 3 releases are read from their
+// secrets, and Helm 2 releases from the config maps in `tillerNamespace`.  A
+// release whose newest revision was deleted is not returned.  A release that
+// cannot be decoded is returned with its error, rather than failing the list.
+func listReleases(cs kubernetes.Interface, namespace, tillerNamespace string) ([]*release, error) {
+	releases := []*release{}
+
+	secrets, err := cs.CoreV1().Secrets(namespace).List(metav1.ListOptions{LabelSelector: helm3Selector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list Helm 3 release secrets")
+	}
+	revisions := make([]*stored, 0, len(secrets.Items))
+	for _, s := range secrets.Items {
+		revisions = append(revisions, labelled(s.Labels["name"], s.Namespace, s.Labels["version"], s.Labels["status"], s.Data[releaseKey]))
+	}
+	for _, s := range newest(revisions, helm3Deleted) {
+		r, err := decodeHelm3(s.data)
+		if err != nil {
+			r = undecoded(s, 3, err)
+		}
+		releases = append(releases, r)
+	}
+
+	cms, err := cs.CoreV1().ConfigMaps(tillerNamespace).List(metav1.ListOptions{LabelSelector: helm2Selector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list Helm 2 release config maps in namespace '%s'", tillerNamespace)
+	}
+	revisions = make([]*stored, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		// Tiller's config maps do not say which namespace the release is
+		// in, so that is not known until the release is decoded.
+		revisions = append(revisions, labelled(cm.Labels["NAME"], "", cm.Labels["VERSION"], cm.Labels["STATUS"], []byte(cm.Data[releaseKey])))
+	}
+	for _, s := range newest(revisions, helm2Deleted) {
+		r, err := decodeHelm2(s.data)
+		if err != nil {
+			// The release's namespace is in the part that could not be
+			// decoded, so it is reported in every namespace.
+			releases = append(releases, undecoded(s, 2, err))
+			continue
+		}
+		if namespace != "" && r.Namespace != namespace {
+			continue
+		}
+		releases = append(releases, r)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return releases, nil
+}
+
+func labelled(name, namespace, revision, status string, data []byte) *stored {
+	// A revision label that is not a number sorts before every other
+	// revision.
+	rev, _ := strconv.Atoi(revision)
+	return &stored{name: name, namespace: namespace, revision: rev, status: status, data: data}
+}
+
+// undecoded returns the release for a revision that could not be decoded
+func undecoded(s *stored, helm int, err error) *release {
+	return &release{
+		Name:      s.name,
+		Namespace: s.namespace,
+		Revision:  s.revision,
+		Helm:      helm,
+		Error:     err.Error(),
+	}
+}
+
+// newest returns the newest revision of each release, unless that revision
+// has the status `deleted`
+func newest(revisions []*stored, deleted string) []*stored {
+	type key struct{ name, namespace string }
+	latest := map[key]*stored{}
+	keys := []key{}
+	for _, s := range revisions {
+		k := key{s.name, s.namespace}
+		prev, ok := latest[k]
+		if !ok {
+			keys = append(keys, k)
+		}
+		if !ok || s.revision > prev.revision {
+			latest[k] = s
+		}
+	}
+
+	result := []*stored{}
+	for _, k := range keys {
+		if s := latest[k]; s.status != deleted {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// decodeHelm3 decodes a release as Helm 3 stores it: gzipped JSON, base64
+// encoded
+func decodeHelm3(data []byte) (*release, error) {
+	b, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	rls := &helm3Release{}
+	if err := json.Unmarshal(b, rls); err != nil {
+		return nil, errors.Wrapf(err, "Failed to unmarshal release")
+	}
+	md := rls.Chart.Metadata
+	return &release{
+		Name:       rls.Name,
+		Namespace:  rls.Namespace,
+		Revision:   rls.Version,
+		Helm:       3,
+		Chart:      md.Name,
+		Version:    md.Version,
+		AppVersion: md.AppVersion,
+	}, nil
+}
+
+// decodeHelm2 decodes a release as Tiller stores it: a gzipped protocol
+// buffer, base64 encoded
+func decodeHelm2(data []byte) (*release, error) {
+	b, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	rls := &rspb.Release{}
+	if err := proto.Unmarshal(b, rls); err != nil {
+		return nil, errors.Wrapf(err, "Failed to unmarshal release")
+	}
+	r := &release{
+		Name:      rls.Name,
+		Namespace: rls.Namespace,
+		Revision:  int(rls.Version),
+		Helm:      2,
+	}
+	if md := rls.GetChart().GetMetadata(); md != nil {
+		r.Chart = md.Name
+		r.Version = md.Version
+		r.AppVersion = md.AppVersion
+	}
+	return r, nil
+}
+
+// decode base64 decodes `data`, and decompresses it if it is gzipped
+func decode(data []byte) ([]byte, error) {
+	b := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(b, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode release")
+	}
+	b = b[:n]
+
+	if !bytes.HasPrefix(b, gzipMagic) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to decompress release")
+	}
+	defer r.Close()
+	b, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to decompress release")
+	}
+	return b, nil
+}
@@ -0,0 +1,155 @@
+package outdated
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	rspb "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+func encode(t *testing.T, b []byte) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatalf("Failed to compress release:\n%s", err.Error())
+	}
+	w.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func helm3Secret(t *testing.T, name, namespace string, revision int, status, chartname, version string) *v1.Secret {
+	b := []byte(fmt.Sprintf(`{"name": %q, "namespace": %q, "version": %d, "chart": {"metadata": {"name": %q, "version": %q}}}`, name, namespace, revision, chartname, version))
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, revision),
+			Namespace: namespace,
+			Labels:    map[string]string{"owner": "helm", "name": name, "version": strconv.Itoa(revision), "status": status},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": []byte(encode(t, b))},
+	}
+}
+
+func helm2ConfigMap(t *testing.T, name, namespace string, revision int, status, chartname, version string) *v1.ConfigMap {
+	rls := &rspb.Release{
+		Name:      name,
+		Namespace: namespace,
+		Version:   int32(revision),
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: chartname, Version: version}},
+	}
+	b, err := proto.Marshal(rls)
+	if err != nil {
+		t.Fatalf("Failed to marshal release:\n%s", err.Error())
+	}
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.v%d", name, revision),
+			Namespace: defaultTillerNamespace,
+			Labels:    map[string]string{"OWNER": "TILLER", "NAME": name, "VERSION": strconv.Itoa(revision), "STATUS": status},
+		},
+		Data: map[string]string{"release": encode(t, b)},
+	}
+}
+
+func Test_Outdated_ListReleases(t *testing.T) {
+	objects := []runtime.Object{
+		helm3Secret(t, "db", "prod", 1, "superseded", "postgresql", "8.0.0"),
+		helm3Secret(t, "db", "prod", 2, "deployed", "postgresql", "8.1.0"),
+		helm3Secret(t, "db", "dev", 1, "deployed", "postgresql", "8.2.0"),
+		helm3Secret(t, "gone", "prod", 1, "uninstalled", "redis", "10.0.0"),
+		helm2ConfigMap(t, "web", "prod", 3, "DEPLOYED", "web", "1.2.0"),
+		helm2ConfigMap(t, "old", "dev", 1, "DELETED", "web", "1.0.0"),
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "prod"}},
+	}
+
+	tcs := []struct {
+		name      string
+		namespace string
+		expected  []string
+	}{
+		{name: "all namespaces", expected: []string{"dev/db:postgresql-8.2.0", "prod/db:postgresql-8.1.0", "prod/web:web-1.2.0"}},
+		{name: "namespace", namespace: "prod", expected: []string{"prod/db:postgresql-8.1.0", "prod/web:web-1.2.0"}},
+		{name: "empty namespace", namespace: "staging", expected: []string{}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(objects...)
+			releases, err := listReleases(cs, tc.namespace, defaultTillerNamespace)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if len(releases) != len(tc.expected) {
+				t.Fatalf("Incorrect number of releases; expected %d, actual %d", len(tc.expected), len(releases))
+			}
+			for k, r := range releases {
+				if actual := fmt.Sprintf("%s/%s:%s-%s", r.Namespace, r.Name, r.Chart, r.Version); actual != tc.expected[k] {
+					t.Errorf("Incorrect release %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+			}
+		})
+	}
+}
+
+func Test_Outdated_ListReleases_Undecodable(t *testing.T) {
+	s := helm3Secret(t, "db", "prod", 2, "deployed", "postgresql", "8.1.0")
+	s.Data["release"] = []byte("not base64!")
+	cm := helm2ConfigMap(t, "web", "prod", 3, "DEPLOYED", "web", "1.2.0")
+	cm.Data["release"] = base64.StdEncoding.EncodeToString([]byte("not a release"))
+
+	cs := fake.NewSimpleClientset(s, cm, helm3Secret(t, "cache", "prod", 1, "deployed", "redis", "10.0.0"))
+	releases, err := listReleases(cs, "prod", defaultTillerNamespace)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if len(releases) != 3 {
+		t.Fatalf("Incorrect number of releases; expected 3, actual %d", len(releases))
+	}
+
+	cmps := map[string]*comparison{}
+	for _, r := range releases {
+		cmps[r.Name] = compare(testIndex(), r, &constraints{})
+	}
+	for _, name := range []string{"db", "web"} {
+		if c := cmps[name]; c.Status != Unknown || c.Error == "" {
+			t.Errorf("Expected undecodable release '%s' to be unknown with an error: %+v", name, c)
+		}
+	}
+	if c := cmps["cache"]; c.Status != Missing {
+		t.Errorf("Incorrect status for decodable release; expected '%s', actual '%s'", Missing, c.Status)
+	}
+}
+
+func Test_Outdated_Decode(t *testing.T) {
+	s := helm3Secret(t, "db", "prod", 2, "deployed", "postgresql", "8.1.0")
+	r, err := decodeHelm3(s.Data["release"])
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if r.Helm != 3 || r.Revision != 2 || r.Chart != "postgresql" || r.Version != "8.1.0" {
+		t.Errorf("Incorrect Helm 3 release: %+v", r)
+	}
+
+	cm := helm2ConfigMap(t, "web", "prod", 3, "DEPLOYED", "web", "1.2.0")
+	r, err = decodeHelm2([]byte(cm.Data["release"]))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if r.Helm != 2 || r.Revision != 3 || r.Namespace != "prod" || r.Chart != "web" || r.Version != "1.2.0" {
+		t.Errorf("Incorrect Helm 2 release: %+v", r)
+	}
+
+	if _, err := decodeHelm3([]byte("not base64!")); err == nil {
+		t.Errorf("Expected error")
+	}
+}
@@ -0,0 +1,178 @@
+package raw
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+
+	head    bool
+	include bool
+	to      string
+}
+
+// CreateCommand returns the 'raw' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "raw PATH",
+			Short: "raw requests a path from the museum, and writes the response",
+			Long: `raw sends a GET request for PATH, relative to the current museum, and
+writes the response body to stdout, or saves it to the --to file.  PATH may
+include a query string, i.e., 'api/charts?offset=10'.  This reaches ChartMuseum
+endpoints that churl does not otherwise wrap.
+
+With --head, a HEAD request is sent instead; no other methods are sent, so the
+museum is not modified.  With --include, the status line and headers are
+written to stdout before the body.  The response is not cached.  A response
+other than 2xx is still written, and fails with the exit code for its status.`,
+			Args: cobra.ExactArgs(1),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVarP(&c.head, "head", "I", false, "Send a HEAD request rather than a GET request")
+	flgs.BoolVarP(&c.include, "include", "i", false, "Write the status line and headers to stdout before the body")
+	flgs.StringVarP(&c.to, "to", "o", "", "File to save the response body to, rather than writing it to stdout")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := c.m.CurrentName()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	method := http.MethodGet
+	if c.head {
+		method = http.MethodHead
+	}
+	resp, rerr := client.Raw(ctx, method, strings.TrimPrefix(args[0], "/"))
+	if resp == nil {
+		return rerr
+	}
+	defer resp.Body.Close()
+
+	if c.include {
+		if err = writeHead(os.Stdout, resp); err != nil {
+			return errors.Wrapf(err, "Failed to write response")
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if c.to != "" {
+		f, err := os.Create(c.to)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create '%s'", c.to)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return errors.Wrapf(err, "Failed to write response")
+	}
+
+	// A response other than 2xx is written like any other, and then reported.
+	return rerr
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// writeHead writes the status line and headers of the response, with the
+// headers sorted by name
+func writeHead(w io.Writer, resp *http.Response) error {
+	if _, err := fmt.Fprintf(w, "%s %s\r\n", resp.Proto, resp.Status); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range resp.Header[k] {
+			if _, err := fmt.Fprintf(w, "%s: %s\r\n", k, v); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
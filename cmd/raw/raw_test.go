@@ -0,0 +1,61 @@
+//+build test_integration
+
+package raw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Raw(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"})
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	root, config := fixture.Root, fixture.Config
+
+	tcs := []struct {
+		name     string
+		args     []string
+		exitCode int
+		prefix   string
+		contains string
+		suffix   string
+	}{
+		{name: "get", args: []string{"health"}, prefix: `{"healthy":true}`},
+		{name: "leading slash", args: []string{"/charts/foo-1.0.0.tgz.prov"}, prefix: "signature"},
+		{name: "include", args: []string{"-i", "charts/foo-1.0.0.tgz.prov"}, prefix: "HTTP/1.1 200 OK\r\n", contains: "\r\n\r\nsignature"},
+		{name: "head", args: []string{"-I", "-i", "api/charts/foo"}, prefix: "HTTP/1.1 200 OK\r\n", suffix: "\r\n\r\n"},
+		{name: "not found", args: []string{"api/charts/bar"}, exitCode: 4, contains: "error"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"raw", "--config", config}, tc.args...)
+			out, exitCode := ctesting.RunChurl(t, args...)
+			if exitCode != tc.exitCode {
+				t.Fatalf("Unexpected exit code; expected %d, actual %d", tc.exitCode, exitCode)
+			}
+			if !strings.HasPrefix(out, tc.prefix) || !strings.Contains(out, tc.contains) || !strings.HasSuffix(out, tc.suffix) {
+				t.Errorf("Incorrect output '%s'", out)
+			}
+		})
+	}
+
+	saved := path.Join(root, "foo-1.0.0.tgz")
+	if out, exitCode := ctesting.RunChurl(t, "raw", "--config", config, "-o", saved, "charts/foo-1.0.0.tgz"); exitCode != 0 || out != "" {
+		t.Fatalf("Unexpected exit code %d, output '%s'", exitCode, out)
+	}
+	b, _ := ioutil.ReadFile(saved)
+	if h := sha256.Sum256(b); hex.EncodeToString(h[:]) != s.Digest("foo", "1.0.0") {
+		t.Errorf("Saved archive does not match its digest")
+	}
+}
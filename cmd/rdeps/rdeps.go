@@ -0,0 +1,247 @@
+package rdeps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// Number of archives that are downloaded at once
+	defaultConcurrency = 8
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	version       string
+	allVersions   bool
+	anyRepository bool
+	concurrency   int
+
+	chartname string
+}
+
+// result is the dependents of a chart, and the version that their
+// constraints are checked against
+type result struct {
+	Chart      string       `json:"chart"`
+	Version    string       `json:"version,omitempty"`
+	Dependents []*dependent `json:"dependents"`
+}
+
+// CreateCommand returns the 'rdeps' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "rdeps CHART",
+			Short: "rdeps lists the charts in the museum that depend on a chart",
+			Long: `rdeps reads the museum's index, opens the archive of the newest version of
+every other chart, or of every version with --all-versions, and lists the
+charts whose requirements depend on CHART, with their constraints.
+
+Only dependencies whose repository is this museum are listed: '@NAME' or
+'alias:NAME', the museum's URL, or its in-cluster service address.  With
+--any-repository, a dependency on a chart named CHART from any repository,
+including one vendored in the chart, is listed with its repository.
+
+Each constraint is checked against --version, or the newest version of CHART
+in the museum, to show which dependents would pick it up.
+
+Archives are downloaded concurrently over one port forward, and are cached by
+digest, so that a repeated lookup only revalidates the index.`,
+			Args: cobra.ExactArgs(1),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringVar(&c.version, "version", "", "Version of CHART to check the constraints against; the newest version in the museum if not set")
+	flgs.BoolVar(&c.allVersions, "all-versions", false, "Open every version of every chart, rather than the newest")
+	flgs.BoolVar(&c.anyRepository, "any-repository", false, "List dependencies on a chart named CHART from any repository, not only this museum")
+	flgs.IntVar(&c.concurrency, "concurrency", defaultConcurrency, "Number of archives to download at once")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartname = strings.TrimSpace(args[0])
+
+	if c.concurrency < 1 {
+		return cmdutil.UsageErrorf(cmd, "--concurrency must be at least 1; got %d", c.concurrency)
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	index, err := client.Index(ctx)
+	if err != nil {
+		return err
+	}
+
+	r := &result{
+		Chart:   c.chartname,
+		Version: c.version,
+	}
+	if r.Version == "" {
+		// The chart may not be in this museum; its dependents are still listed.
+		if entries := index.Entries[c.chartname]; len(entries) != 0 {
+			r.Version = entries[0].Version
+		}
+	}
+
+	cvs := candidates(index, c.chartname, c.allVersions)
+	c.Logger.Infof("Reading %d archives from museum '%s'...\n", len(cvs), name)
+
+	match := func(repository string) bool {
+		museum, ok := c.m.MuseumForRepository(repository)
+		return c.anyRepository || (ok && museum == name)
+	}
+
+	var failures []error
+	r.Dependents, failures = scan(ctx, client.LoadVersion, match, cvs, c.chartname, r.Version, c.concurrency)
+
+	if err = c.write(os.Stdout, r); err != nil {
+		return err
+	}
+
+	if len(failures) != 0 {
+		for _, err := range failures {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+		return errors.Errorf("Failed to read %d of %d archives from museum '%s'", len(failures), len(cvs), name)
+	}
+
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+func (c *command) write(w io.Writer, r *result) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(r); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependents")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(r)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependents")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	admits := "ADMITS"
+	if r.Version != "" {
+		admits = fmt.Sprintf("ADMITS %s", r.Version)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "CHART\tVERSION\tCONSTRAINT\t%s\tREPOSITORY\n", admits)
+	for _, d := range r.Dependents {
+		a := "-"
+		if d.Admits != nil {
+			a = "no"
+			if *d.Admits {
+				a = "yes"
+			}
+		}
+		constraint := d.Constraint
+		if constraint == "" {
+			constraint = "*"
+		}
+		repository := d.Repository
+		if repository == "" {
+			repository = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Chart, d.Version, constraint, a, repository)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,77 @@
+//+build test_integration
+
+package rdeps
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Rdeps(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "base", Version: "1.0.0"},
+		&churltest.Chart{Name: "base", Version: "2.0.0"},
+		&churltest.Chart{Name: "app", Version: "1.0.0", Files: map[string]string{
+			"requirements.yaml": "dependencies:\n- name: base\n  version: ^1.0.0\n  repository: '@dev'\n",
+		}},
+		&churltest.Chart{Name: "web", Version: "1.0.0", Files: map[string]string{
+			"requirements.yaml": "dependencies:\n- name: base\n  version: '>=1.0.0'\n  repository: '@dev'\n",
+		}},
+		&churltest.Chart{Name: "fork", Version: "1.0.0", Files: map[string]string{
+			"requirements.yaml": "dependencies:\n- name: base\n  version: ^1.0.0\n  repository: https://charts.example.com\n",
+		}},
+		&churltest.Chart{Name: "other", Version: "1.0.0"},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "rdeps", "base", "--config", config, "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	r := &result{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(r); err != nil {
+		t.Fatalf("Failed to decode dependents:\n%s", err.Error())
+	}
+	if r.Version != "2.0.0" || len(r.Dependents) != 2 {
+		t.Fatalf("Incorrect dependents:\n%s", out)
+	}
+	if d := r.Dependents[0]; d.Chart != "app" || d.Admits == nil || *d.Admits {
+		t.Errorf("Incorrect dependent 'app':\n%s", out)
+	}
+	if d := r.Dependents[1]; d.Chart != "web" || d.Admits == nil || !*d.Admits {
+		t.Errorf("Incorrect dependent 'web':\n%s", out)
+	}
+
+	// The archives are cached; a repeated lookup does not download them.
+	before := len(s.Requests())
+	if downloads := strings.Count(strings.Join(s.Requests(), "\n"), ".tgz"); downloads != 4 {
+		t.Errorf("Downloaded %d archives; expected 4", downloads)
+	}
+	if out, exitCode = ctesting.RunChurl(t, "rdeps", "base", "--config", config, "--version", "1.5.0"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	for _, p := range s.Requests()[before:] {
+		if strings.HasSuffix(p, ".tgz") {
+			t.Errorf("Archive '%s' was downloaded again", p)
+		}
+	}
+	if !strings.Contains(out, "ADMITS 1.5.0") || strings.Count(out, "yes") != 2 {
+		t.Errorf("Incorrect table:\n%s", out)
+	}
+
+	// 'fork' depends on a chart named 'base' from another repository.
+	if out, exitCode = ctesting.RunChurl(t, "rdeps", "base", "--config", config, "--any-repository"); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(out, "fork") || !strings.Contains(out, "https://charts.example.com") {
+		t.Errorf("Dependent from another repository is not listed:\n%s", out)
+	}
+}
@@ -0,0 +1,134 @@
+package rdeps
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/object88/churl/internal/resolve"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// loadFunc loads the archive of a chart version from the museum
+type loadFunc func(ctx context.Context, cv *repo.ChartVersion) (*chart.Chart, error)
+
+// matchFunc reports whether a dependency's repository is the museum
+type matchFunc func(repository string) bool
+
+// dependent is a chart version that depends on the chart
+type dependent struct {
+	Chart      string `json:"chart"`
+	Version    string `json:"version"`
+	Constraint string `json:"constraint"`
+	Repository string `json:"repository,omitempty"`
+
+	// Admits is whether the constraint allows the chart's version; it is
+	// omitted if there is no version to check, or the constraint is invalid
+	Admits *bool `json:"admits,omitempty"`
+}
+
+// candidates returns the chart versions in the index that may depend on
+// `chartname`; the newest version of every other chart, or, with `all`, every
+// version.  They are sorted by name, and then newest first.
+func candidates(index *repo.IndexFile, chartname string, all bool) []*repo.ChartVersion {
+	names := make([]string, 0, len(index.Entries))
+	for name := range index.Entries {
+		if name != chartname {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	cvs := []*repo.ChartVersion{}
+	for _, name := range names {
+		entries := index.Entries[name]
+		if len(entries) == 0 {
+			continue
+		}
+		if !all {
+			entries = entries[:1]
+		}
+		cvs = append(cvs, entries...)
+	}
+	return cvs
+}
+
+// scan loads the candidates' archives, `concurrency` at a time, and returns
+// the dependents of `chartname` from a repository that `match` accepts, in
+// the order of the candidates.  If `version` is set, each dependent reports
+// whether its constraint admits it.  The archives that could not be read are
+// returned as errors.
+func scan(ctx context.Context, load loadFunc, match matchFunc, cvs []*repo.ChartVersion, chartname, version string, concurrency int) ([]*dependent, []error) {
+	found := make([][]*dependent, len(cvs))
+	errs := make([]error, len(cvs))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for k := range work {
+				found[k], errs[k] = inspect(ctx, load, match, cvs[k], chartname, version)
+			}
+		}()
+	}
+	for k := range cvs {
+		work <- k
+	}
+	close(work)
+	wg.Wait()
+
+	dependents := []*dependent{}
+	for _, f := range found {
+		dependents = append(dependents, f...)
+	}
+	failures := []error{}
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return dependents, failures
+}
+
+// inspect returns the dependencies of the chart version `cv` on `chartname`,
+// from a repository that `match` accepts; a chart of the same name from
+// another repository is a different chart
+func inspect(ctx context.Context, load loadFunc, match matchFunc, cv *repo.ChartVersion, chartname, version string) ([]*dependent, error) {
+	ch, err := load(ctx, cv)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs, err := chartutil.LoadRequirements(ch)
+	switch {
+	case err == chartutil.ErrRequirementsNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "Failed to read the requirements of chart '%s' version '%s'", cv.Name, cv.Version)
+	}
+
+	found := []*dependent{}
+	for _, dep := range reqs.Dependencies {
+		if dep.Name != chartname || !match(dep.Repository) {
+			continue
+		}
+		d := &dependent{
+			Chart:      cv.Name,
+			Version:    cv.Version,
+			Constraint: dep.Version,
+			Repository: dep.Repository,
+		}
+		if version != "" {
+			if ok, err := resolve.Satisfies(dep.Version, version); err == nil {
+				d.Admits = &ok
+			}
+		}
+		found = append(found, d)
+	}
+	return found, nil
+}
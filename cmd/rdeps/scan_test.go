@@ -0,0 +1,109 @@
+package rdeps
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func testIndex() *repo.IndexFile {
+	i := repo.NewIndexFile()
+	for _, cv := range [][2]string{{"app", "1.0.0"}, {"app", "2.0.0"}, {"base", "1.0.0"}, {"base", "1.1.0"}, {"other", "1.0.0"}, {"broken", "1.0.0"}} {
+		i.Entries[cv[0]] = append(i.Entries[cv[0]], &repo.ChartVersion{Metadata: &chart.Metadata{Name: cv[0], Version: cv[1]}})
+	}
+	i.SortEntries()
+	return i
+}
+
+// testRequirements are the requirements of each chart version
+var testRequirements = map[string]string{
+	"app-1.0.0":   "dependencies:\n- name: base\n  version: ~1.0.0\n  repository: '@dev'\n",
+	"app-2.0.0":   "dependencies:\n- name: base\n  version: ^1.0.0\n  repository: '@dev'\n",
+	"other-1.0.0": "dependencies:\n- name: unrelated\n  version: ^1.0.0\n- name: base\n  version: ^1.0.0\n  repository: https://charts.example.com\n",
+}
+
+func Test_Rdeps_Candidates(t *testing.T) {
+	tcs := []struct {
+		name     string
+		all      bool
+		expected []string
+	}{
+		{name: "newest", expected: []string{"app-2.0.0", "broken-1.0.0", "other-1.0.0"}},
+		{name: "all", all: true, expected: []string{"app-2.0.0", "app-1.0.0", "broken-1.0.0", "other-1.0.0"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cvs := candidates(testIndex(), "base", tc.all)
+			if len(cvs) != len(tc.expected) {
+				t.Fatalf("Incorrect number of candidates; expected %d, actual %d", len(tc.expected), len(cvs))
+			}
+			for k, cv := range cvs {
+				if actual := cv.Name + "-" + cv.Version; actual != tc.expected[k] {
+					t.Errorf("Incorrect candidate %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+			}
+		})
+	}
+}
+
+func Test_Rdeps_Scan(t *testing.T) {
+	var mu sync.Mutex
+	loaded := map[string]int{}
+	load := func(ctx context.Context, cv *repo.ChartVersion) (*chart.Chart, error) {
+		key := cv.Name + "-" + cv.Version
+		mu.Lock()
+		loaded[key]++
+		mu.Unlock()
+
+		if cv.Name == "broken" {
+			return nil, errors.Errorf("Failed to load '%s'", key)
+		}
+		ch := &chart.Chart{Metadata: cv.Metadata}
+		if r, ok := testRequirements[key]; ok {
+			ch.Files = []*any.Any{{TypeUrl: "requirements.yaml", Value: []byte(r)}}
+		}
+		return ch, nil
+	}
+
+	cvs := candidates(testIndex(), "base", true)
+	match := func(repository string) bool { return repository == "@dev" }
+	dependents, failures := scan(context.Background(), load, match, cvs, "base", "1.1.0", 3)
+
+	if len(failures) != 1 {
+		t.Errorf("Incorrect failures: %v", failures)
+	}
+	for _, cv := range cvs {
+		if n := loaded[cv.Name+"-"+cv.Version]; n != 1 {
+			t.Errorf("Loaded '%s-%s' %d times; expected once", cv.Name, cv.Version, n)
+		}
+	}
+
+	expected := []struct {
+		version string
+		admits  bool
+	}{
+		{version: "2.0.0", admits: true},
+		{version: "1.0.0", admits: false},
+	}
+	if len(dependents) != len(expected) {
+		t.Fatalf("Incorrect number of dependents; expected %d, actual %d", len(expected), len(dependents))
+	}
+	for k, d := range dependents {
+		if d.Chart != "app" || d.Version != expected[k].version || d.Admits == nil || *d.Admits != expected[k].admits {
+			t.Errorf("Incorrect dependent %d: %#v", k, d)
+		}
+	}
+
+	// A dependency on a chart of the same name from another repository is
+	// listed only if every repository matches.
+	dependents, _ = scan(context.Background(), load, func(string) bool { return true }, cvs, "base", "1.1.0", 3)
+	if len(dependents) != 3 || dependents[2].Chart != "other" || dependents[2].Repository != "https://charts.example.com" {
+		t.Errorf("Incorrect dependents from any repository: %#v", dependents)
+	}
+}
@@ -4,13 +4,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/object88/churl/cmd/cache"
 	"github.com/object88/churl/cmd/common"
 	"github.com/object88/churl/cmd/completion"
 	"github.com/object88/churl/cmd/config"
+	"github.com/object88/churl/cmd/deps"
+	"github.com/object88/churl/cmd/describe"
+	"github.com/object88/churl/cmd/diff"
+	"github.com/object88/churl/cmd/export"
 	"github.com/object88/churl/cmd/get"
 	initcmd "github.com/object88/churl/cmd/init"
+	"github.com/object88/churl/cmd/mirror"
+	"github.com/object88/churl/cmd/outdated"
+	"github.com/object88/churl/cmd/raw"
+	"github.com/object88/churl/cmd/rdeps"
+	"github.com/object88/churl/cmd/search"
+	"github.com/object88/churl/cmd/serve"
+	"github.com/object88/churl/cmd/template"
 	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/cmd/tree"
+	"github.com/object88/churl/cmd/verifybundle"
 	"github.com/object88/churl/cmd/version"
+	"github.com/object88/churl/cmd/watch"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -20,6 +35,14 @@ __churl_get_outputs()
 {
 	COMPREPLY=( "json", "json-compressed", "text" )
 }
+
+__churl_get_museums()
+{
+	local churl_out
+	if churl_out=$(churl config list 2>/dev/null); then
+		COMPREPLY=( $( compgen -W "${churl_out[*]}" -- "$cur" ) )
+	fi
+}
 `
 
 // InitializeCommands sets up the cobra commands
@@ -27,11 +50,26 @@ func InitializeCommands() *cobra.Command {
 	ca, rootCmd := createRootCommand()
 
 	rootCmd.AddCommand(
+		cache.CreateCommand(ca),
 		completion.CreateCommand(ca),
 		config.CreateCommand(ca),
+		deps.CreateCommand(ca),
+		describe.CreateCommand(ca),
+		diff.CreateCommand(ca),
+		export.CreateCommand(ca),
 		get.CreateCommand(ca),
 		initcmd.CreateCommand(ca),
+		mirror.CreateCommand(ca),
+		outdated.CreateCommand(ca),
+		raw.CreateCommand(ca),
+		rdeps.CreateCommand(ca),
+		search.CreateCommand(ca),
+		serve.CreateCommand(ca),
+		template.CreateCommand(ca),
+		tree.CreateCommand(ca),
+		verifybundle.CreateCommand(ca),
 		version.CreateCommand(),
+		watch.CreateCommand(ca),
 	)
 
 	return rootCmd
@@ -47,9 +85,12 @@ func createRootCommand() (*common.CommonArgs, *cobra.Command) {
 		BashCompletionFunction: bashCompletionFunc,
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 			start = time.Now()
-			ca.Evaluate()
 
-			return nil
+			// The arguments have been parsed; later failures are not usage
+			// errors.
+			cmd.SilenceUsage = true
+
+			return ca.Evaluate()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.HelpFunc()(cmd, args)
@@ -68,11 +109,17 @@ func createRootCommand() (*common.CommonArgs, *cobra.Command) {
 			}
 			f(cmd)
 
-			ca.Logger.Infof("Executed command \"%s\" in %s", strings.Join(segments, " "), duration)
+			ca.Logger.Infof("Executed command \"%s\" in %s\n", strings.Join(segments, " "), duration)
 			return nil
 		},
 	}
 
+	// Errors are reported by ReportError, so that they can be written as JSON
+	cmd.SilenceErrors = true
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.SetEnvPrefix("CHURL")
 
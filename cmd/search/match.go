@@ -0,0 +1,135 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Scores of the ways that a chart can match; a chart's score is the best of
+// its matches, so that, i.e., a chart named for the term ranks above one that
+// mentions it in its description
+const (
+	scoreNameExact     = 100
+	scoreNamePrefix    = 80
+	scoreName          = 60
+	scoreKeywordExact  = 50
+	scoreKeyword       = 40
+	scoreDescription   = 30
+	scoreMaintainer    = 25
+	scoreNameFuzzy     = 20
+	scoreMatchesAnyway = 1
+)
+
+// matcher scores a chart version against the search term; zero is no match
+type matcher interface {
+	score(cv *repo.ChartVersion) int
+}
+
+// newMatcher returns a matcher for `term`.  An empty term matches every
+// chart.  Otherwise, the term is a case-insensitive regular expression if
+// `regex` is set, or else a case-insensitive substring, which also matches a
+// name that contains its characters in order.
+func newMatcher(term string, regex bool) (matcher, error) {
+	switch {
+	case term == "":
+		return everything{}, nil
+	case regex:
+		re, err := regexp.Compile("(?i)" + term)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to compile regular expression '%s'", term)
+		}
+		return &regexMatcher{re: re}, nil
+	default:
+		return &fuzzyMatcher{term: strings.ToLower(term)}, nil
+	}
+}
+
+type everything struct{}
+
+func (everything) score(cv *repo.ChartVersion) int {
+	return scoreMatchesAnyway
+}
+
+type fuzzyMatcher struct {
+	term string
+}
+
+func (m *fuzzyMatcher) score(cv *repo.ChartVersion) int {
+	name := strings.ToLower(cv.Name)
+	switch {
+	case name == m.term:
+		return scoreNameExact
+	case strings.HasPrefix(name, m.term):
+		return scoreNamePrefix
+	case strings.Contains(name, m.term):
+		return scoreName
+	}
+
+	best := 0
+	for _, k := range cv.Keywords {
+		k = strings.ToLower(k)
+		if k == m.term {
+			return scoreKeywordExact
+		}
+		if strings.Contains(k, m.term) {
+			best = scoreKeyword
+		}
+	}
+	if best != 0 {
+		return best
+	}
+
+	if strings.Contains(strings.ToLower(cv.Description), m.term) {
+		return scoreDescription
+	}
+	for _, mt := range cv.Maintainers {
+		if strings.Contains(strings.ToLower(mt.Name), m.term) || strings.Contains(strings.ToLower(mt.Email), m.term) {
+			return scoreMaintainer
+		}
+	}
+
+	if subsequence(name, m.term) {
+		return scoreNameFuzzy
+	}
+	return 0
+}
+
+// subsequence reports whether the characters of `term` appear in `s`, in
+// order, i.e., "pgsql" in "postgresql"
+func subsequence(s, term string) bool {
+	for _, r := range term {
+		k := strings.IndexRune(s, r)
+		if k == -1 {
+			return false
+		}
+		s = s[k+len(string(r)):]
+	}
+	return true
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m *regexMatcher) score(cv *repo.ChartVersion) int {
+	if m.re.MatchString(cv.Name) {
+		return scoreName
+	}
+	for _, k := range cv.Keywords {
+		if m.re.MatchString(k) {
+			return scoreKeyword
+		}
+	}
+	if m.re.MatchString(cv.Description) {
+		return scoreDescription
+	}
+	for _, mt := range cv.Maintainers {
+		if m.re.MatchString(mt.Name) || m.re.MatchString(mt.Email) {
+			return scoreMaintainer
+		}
+	}
+	return 0
+}
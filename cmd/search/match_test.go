@@ -0,0 +1,69 @@
+package search
+
+import (
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func testIndex() *repo.IndexFile {
+	i := repo.NewIndexFile()
+	add := func(md *chart.Metadata) {
+		i.Entries[md.Name] = append(i.Entries[md.Name], &repo.ChartVersion{Metadata: md})
+	}
+	add(&chart.Metadata{Name: "postgresql", Version: "8.0.0", Keywords: []string{"database", "sql"}, Description: "PostgreSQL database"})
+	add(&chart.Metadata{Name: "postgresql", Version: "8.1.0", Keywords: []string{"database", "sql"}, Description: "PostgreSQL database"})
+	add(&chart.Metadata{Name: "redis", Version: "10.0.0", Keywords: []string{"cache", "database"}, Description: "In-memory store"})
+	add(&chart.Metadata{Name: "sql-proxy", Version: "0.1.0", Description: "Proxy for Cloud SQL"})
+	add(&chart.Metadata{Name: "web", Version: "1.0.0", Description: "Frontend", Maintainers: []*chart.Maintainer{{Name: "Platform Team", Email: "platform@example.com"}}})
+	i.SortEntries()
+	return i
+}
+
+func Test_Search_Search(t *testing.T) {
+	tcs := []struct {
+		name     string
+		term     string
+		regex    bool
+		versions bool
+		expected []string
+	}{
+		{name: "everything", expected: []string{"postgresql-8.1.0", "redis-10.0.0", "sql-proxy-0.1.0", "web-1.0.0"}},
+		{name: "name ranks above keyword", term: "sql", expected: []string{"sql-proxy-0.1.0", "postgresql-8.1.0"}},
+		{name: "keyword", term: "database", expected: []string{"postgresql-8.1.0", "redis-10.0.0"}},
+		{name: "description", term: "in-memory", expected: []string{"redis-10.0.0"}},
+		{name: "maintainer", term: "platform@", expected: []string{"web-1.0.0"}},
+		{name: "fuzzy", term: "pgsql", expected: []string{"postgresql-8.1.0"}},
+		{name: "case-insensitive", term: "REDIS", expected: []string{"redis-10.0.0"}},
+		{name: "versions", term: "postgresql", versions: true, expected: []string{"postgresql-8.1.0", "postgresql-8.0.0"}},
+		{name: "regex", term: "^(redis|web)$", regex: true, expected: []string{"redis-10.0.0", "web-1.0.0"}},
+		{name: "no match", term: "kafka"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := newMatcher(tc.term, tc.regex)
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			results := search(testIndex(), "dev", m, tc.versions)
+			rank(results)
+
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Incorrect number of results; expected %d, actual %d", len(tc.expected), len(results))
+			}
+			for k, r := range results {
+				if actual := r.Chart.Name + "-" + r.Chart.Version; actual != tc.expected[k] {
+					t.Errorf("Incorrect result %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+			}
+		})
+	}
+}
+
+func Test_Search_InvalidRegex(t *testing.T) {
+	if _, err := newMatcher("(", true); err == nil {
+		t.Errorf("Expected error")
+	}
+}
@@ -0,0 +1,289 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/helm/pkg/repo"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// Width at which descriptions are truncated in the text output
+	descriptionWidth = 60
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m       *manifest.Manifest
+	museums []string
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	regex    bool
+	versions bool
+
+	match matcher
+}
+
+// result is a chart version that matches the search term
+type result struct {
+	Museum string             `json:"museum"`
+	Score  int                `json:"score"`
+	Chart  *repo.ChartVersion `json:"chart"`
+}
+
+// CreateCommand returns the 'search' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "search [TERM]",
+			Short: "search finds charts by name, keyword, description, or maintainer",
+			Long: `search reads the museum's index, and lists the newest version of each chart
+that matches TERM, or of every chart if TERM is not provided.
+
+TERM matches, case-insensitively, a chart's name, keywords, description, and
+maintainers' names and emails.  A name that contains TERM's characters in
+order also matches, i.e., 'pgsql' matches 'postgresql'.  With --regexp, TERM is
+a regular expression.  The results are ranked: a chart named TERM first, then
+charts whose names start with or contain TERM, then keywords, descriptions, and
+maintainers.
+
+With --museum or --all-museums, every selected museum is searched.`,
+			Args: cobra.RangeArgs(0, 1),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVarP(&c.regex, "regexp", "r", false, "Treat TERM as a regular expression")
+	flgs.BoolVarP(&c.versions, "versions", "l", false, "List every matching version, rather than the newest")
+	flags.CreateAllMuseumsFlag(flgs)
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	term := ""
+	if len(args) == 1 {
+		term = strings.TrimSpace(args[0])
+	}
+
+	var err error
+	c.match, err = newMatcher(term, c.regex)
+	if err != nil {
+		return &churl.ConfigError{Err: err}
+	}
+
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, c.museums, err = c.OpenManifestWithMuseums(flags.ReadAllMuseumsFlag(cmd.Flags()))
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	found := make([][]*result, len(c.museums))
+	errs := make([]error, len(c.museums))
+
+	var wg sync.WaitGroup
+	wg.Add(len(c.museums))
+	for k, name := range c.museums {
+		go func(k int, name string) {
+			defer wg.Done()
+			found[k], errs[k] = c.query(name)
+		}(k, name)
+	}
+	wg.Wait()
+
+	results := []*result{}
+	for _, f := range found {
+		results = append(results, f...)
+	}
+	rank(results)
+
+	if len(c.museums) == 1 && errs[0] != nil {
+		return errs[0]
+	}
+
+	if err := c.write(os.Stdout, results); err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for k, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			failed = append(failed, c.museums[k])
+		}
+	}
+	if len(failed) != 0 {
+		return errors.Errorf("Failed to search %d of %d museums: '%s'", len(failed), len(c.museums), strings.Join(failed, "', '"))
+	}
+
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// query connects to the museum, and searches its index
+func (c *command) query(name string) ([]*result, error) {
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	index, err := client.Index(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not search museum '%s'", name)
+	}
+
+	return search(index, name, c.match, c.versions), nil
+}
+
+// search returns the chart versions in the index that match; the newest
+// matching version of each chart, or, with `versions`, every matching version
+func search(index *repo.IndexFile, museum string, m matcher, versions bool) []*result {
+	results := []*result{}
+	for _, cvs := range index.Entries {
+		// The index entries are sorted, newest first.
+		for _, cv := range cvs {
+			score := m.score(cv)
+			if score == 0 {
+				continue
+			}
+			results = append(results, &result{Museum: museum, Score: score, Chart: cv})
+			if !versions {
+				break
+			}
+		}
+	}
+	return results
+}
+
+// rank sorts the results by score, and then by name and museum; the versions
+// of a chart in a museum stay newest first
+func rank(results []*result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Chart.Name != b.Chart.Name {
+			return a.Chart.Name < b.Chart.Name
+		}
+		return a.Museum < b.Museum
+	})
+}
+
+func (c *command) write(w io.Writer, results []*result) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(results); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode search results")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(results)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode search results")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	multiple := len(c.museums) > 1
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if multiple {
+		fmt.Fprint(tw, "MUSEUM\t")
+	}
+	fmt.Fprintln(tw, "NAME\tVERSION\tAPP VERSION\tDESCRIPTION")
+	for _, r := range results {
+		if multiple {
+			fmt.Fprintf(tw, "%s\t", r.Museum)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Chart.Name, r.Chart.Version, r.Chart.AppVersion, truncate(r.Chart.Description, descriptionWidth))
+	}
+	return tw.Flush()
+}
+
+// truncate shortens `s` to `width` characters, ending in an ellipsis
+func truncate(s string, width int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-3]) + "..."
+}
@@ -0,0 +1,64 @@
+//+build test_integration
+
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Search(t *testing.T) {
+	dev := churltest.NewServer(
+		&churltest.Chart{Name: "postgresql", Version: "8.0.0", Description: "PostgreSQL database"},
+		&churltest.Chart{Name: "postgresql", Version: "8.1.0", Description: "PostgreSQL database"},
+		&churltest.Chart{Name: "redis", Version: "10.0.0", Description: "In-memory database"},
+	)
+	defer dev.Close()
+	prod := churltest.NewServer(&churltest.Chart{Name: "postgresql", Version: "7.0.0", Description: "PostgreSQL database"})
+	defer prod.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: dev.URL}, ctesting.Museum{Name: "prod", URL: prod.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	tcs := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "name", args: []string{"postgres"}, expected: []string{"dev/postgresql-8.1.0"}},
+		{name: "description", args: []string{"database"}, expected: []string{"dev/postgresql-8.1.0", "dev/redis-10.0.0"}},
+		{name: "versions", args: []string{"postgres", "--versions"}, expected: []string{"dev/postgresql-8.1.0", "dev/postgresql-8.0.0"}},
+		{name: "all museums", args: []string{"postgres", "--all-museums"}, expected: []string{"dev/postgresql-8.1.0", "prod/postgresql-7.0.0"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"search", "--config", config, "--output", "json"}, tc.args...)
+			out, exitCode := ctesting.RunChurl(t, args...)
+			if exitCode != 0 {
+				t.Fatalf("Unexpected exit code %d", exitCode)
+			}
+			results := []*result{}
+			if err := json.NewDecoder(strings.NewReader(out)).Decode(&results); err != nil {
+				t.Fatalf("Failed to decode results:\n%s", err.Error())
+			}
+			actual := []string{}
+			for _, r := range results {
+				actual = append(actual, fmt.Sprintf("%s/%s-%s", r.Museum, r.Chart.Name, r.Chart.Version))
+			}
+			if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Incorrect results; expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+
+	if _, exitCode := ctesting.RunChurl(t, "search", "(", "--regexp", "--config", config); exitCode != 3 {
+		t.Errorf("Unexpected exit code %d for an invalid regular expression", exitCode)
+	}
+}
@@ -0,0 +1,181 @@
+package serve
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"k8s.io/helm/pkg/repo"
+)
+
+const (
+	indexPath     = "index.yaml"
+	chartsPrefix  = "charts/"
+	provenanceExt = ".prov"
+)
+
+// handler serves a museum as a read-only helm repository: its index, and the
+// archives and provenance files it refers to.  Requests are answered through
+// the client, so that the index is revalidated, and archives are served from
+// the cache, where possible.
+type handler struct {
+	client *churl.Client
+
+	// logf logs each request
+	logf func(format string, v ...interface{})
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+	h.serve(rw, r)
+	h.logf("%s %s %s %d %d %s\n", r.RemoteAddr, r.Method, r.URL.Path, rw.status, rw.size, time.Since(start))
+}
+
+func (h *handler) serve(w http.ResponseWriter, r *http.Request) {
+	// churl does not alter museums, so neither does the repository it serves.
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case p == "" || p == indexPath:
+		h.serveIndex(w, r)
+	case strings.HasPrefix(p, chartsPrefix):
+		h.serveChart(w, r, p)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (h *handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	index, err := h.client.Index(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	// Absolute URLs name the museum, which helm cannot reach, and may be
+	// under a context path; every URL is rewritten to this server's charts
+	// path, relative to the index.
+	for _, cvs := range index.Entries {
+		for _, cv := range cvs {
+			for k, raw := range cv.URLs {
+				cv.URLs[k] = rewrite(raw)
+			}
+		}
+	}
+
+	b, err := yaml.Marshal(index)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.Write(b)
+}
+
+func (h *handler) serveChart(w http.ResponseWriter, r *http.Request, p string) {
+	ctx := r.Context()
+	index, err := h.client.Index(ctx)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	prov := strings.HasSuffix(p, provenanceExt)
+	cv := find(index, strings.TrimSuffix(p, provenanceExt))
+	if cv == nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if prov {
+		b, err := h.client.Provenance(ctx, cv)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pgp-signature")
+		w.Write(b)
+		return
+	}
+
+	rc, err := h.client.DownloadVersion(ctx, cv)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/gzip")
+	io.Copy(w, rc)
+}
+
+// find returns the index entry whose archive is served at the path `p`, as
+// rewritten in the index
+func find(index *repo.IndexFile, p string) *repo.ChartVersion {
+	for _, cvs := range index.Entries {
+		for _, cv := range cvs {
+			for _, raw := range cv.URLs {
+				if rewrite(raw) == p {
+					return cv
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rewrite returns the path that the archive at the URL `raw` is served at,
+// relative to the index: 'charts/', and the archive's file name
+func rewrite(raw string) string {
+	p := raw
+	if u, err := url.Parse(raw); err == nil {
+		p = u.Path
+	}
+	return chartsPrefix + path.Base(p)
+}
+
+// writeClientError reports a failure to reach the museum; a chart version the
+// museum does not have is not found, and anything else is a bad gateway
+func writeClientError(w http.ResponseWriter, err error) {
+	if churl.KindOf(err) == churl.KindNotFound {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusBadGateway, err.Error())
+}
+
+// writeError writes an error as ChartMuseum reports it
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// recorder records the status and size of a response, for the access log
+type recorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
@@ -0,0 +1,171 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/manifest"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Serve_Handler(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Provenance: "signature"},
+		&churltest.Chart{Name: "foo", Version: "1.1.0"},
+	)
+	defer s.Close()
+
+	client, err := churl.NewClient(context.Background(), "test", s.Museum())
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	defer client.Close()
+
+	logged := []string{}
+	h := &handler{
+		client: client,
+		logf: func(format string, v ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, v...))
+		},
+	}
+
+	tcs := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/charts/foo-1.0.0.tgz.prov", status: http.StatusOK, body: "signature"},
+		{method: http.MethodGet, path: "/charts/foo-1.1.0.tgz.prov", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/charts/foo-2.0.0.tgz", status: http.StatusNotFound},
+		{method: http.MethodHead, path: "/index.yaml", status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/charts", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/api/charts", status: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, path: "/api/charts/foo/1.0.0", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.status {
+				t.Errorf("Incorrect status; expected %d, actual %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("Incorrect body; expected '%s', actual '%s'", tc.body, w.Body.String())
+			}
+		})
+	}
+	for _, p := range s.Requests() {
+		if strings.HasPrefix(p, "/api/") {
+			t.Errorf("Request for '%s' was passed to the museum", p)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.yaml", nil))
+	i := &repo.IndexFile{}
+	if err = yaml.Unmarshal(w.Body.Bytes(), i); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	cv := i.Entries["foo"][0]
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+cv.URLs[0], nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("Incorrect archive response %d", w.Code)
+	}
+
+	if len(logged) != len(tcs)+2 || !strings.Contains(logged[0], "GET /charts/foo-1.0.0.tgz.prov 200") {
+		t.Errorf("Incorrect access log: %v", logged)
+	}
+}
+
+func Test_Serve_ContextPath(t *testing.T) {
+	c := &churltest.Chart{Name: "foo", Version: "1.0.0"}
+	archive, digest, err := c.Archive()
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	// The museum is under '/museum', and advertises absolute URLs.
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/museum/index.yaml":
+			i := repo.NewIndexFile()
+			i.Add(c.Metadata(), c.Filename(), s.URL+"/museum/charts", digest)
+			b, _ := yaml.Marshal(i)
+			w.Write(b)
+		case "/museum/charts/" + c.Filename():
+			w.Write(archive)
+		case "/museum/charts/" + c.Filename() + ".prov":
+			w.Write([]byte("signature"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	client, err := churl.NewClient(context.Background(), "test", &manifest.ChartMuseum{URL: s.URL + "/museum"})
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	defer client.Close()
+	h := &handler{client: client, logf: func(string, ...interface{}) {}}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.yaml", nil))
+	i := &repo.IndexFile{}
+	if err = yaml.Unmarshal(w.Body.Bytes(), i); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if u := i.Entries["foo"][0].URLs[0]; u != "charts/foo-1.0.0.tgz" {
+		t.Fatalf("Incorrect URL in index; expected 'charts/foo-1.0.0.tgz', actual '%s'", u)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/charts/foo-1.0.0.tgz", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), archive) {
+		t.Errorf("Incorrect archive response %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/charts/foo-1.0.0.tgz.prov", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "signature" {
+		t.Errorf("Incorrect provenance response %d", w.Code)
+	}
+}
+
+func Test_Serve_Rewrite(t *testing.T) {
+	tcs := []struct {
+		raw      string
+		expected string
+	}{
+		{raw: "charts/foo-1.0.0.tgz", expected: "charts/foo-1.0.0.tgz"},
+		{raw: "foo-1.0.0.tgz", expected: "charts/foo-1.0.0.tgz"},
+		{raw: "https://charts.example.com/charts/foo-1.0.0.tgz", expected: "charts/foo-1.0.0.tgz"},
+		{raw: "https://charts.example.com/museum/charts/foo-1.0.0.tgz", expected: "charts/foo-1.0.0.tgz"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.raw, func(t *testing.T) {
+			if actual := rewrite(tc.raw); actual != tc.expected {
+				t.Errorf("Incorrect URL; expected '%s', actual '%s'", tc.expected, actual)
+			}
+		})
+	}
+
+	i := repo.NewIndexFile()
+	i.Add(&chart.Metadata{Name: "foo", Version: "1.0.0"}, "foo-1.0.0.tgz", "https://charts.example.com/museum/charts", "")
+	if cv := find(i, "charts/foo-1.0.0.tgz"); cv == nil {
+		t.Errorf("Entry with an absolute URL under a context path was not found")
+	}
+}
@@ -0,0 +1,176 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// defaultPort is the port that `helm serve` uses
+	defaultPort = 8879
+
+	// shutdownTimeout is how long requests in flight may take to finish once
+	// the server is stopped
+	shutdownTimeout = 5 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+
+	address string
+	port    int
+}
+
+// CreateCommand returns the 'serve' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "serve [--port PORT]",
+			Short: "serve serves the current museum locally as a read-only helm repository",
+			Long: `serve opens a connection to the current museum, through a port forward if
+needed, and serves its index.yaml and chart archives and provenance files on
+--address and --port until it is interrupted, i.e.:
+
+  helm repo add internal http://localhost:8879
+
+Responses come through the cache, so an unchanged index is not downloaded
+again, and archives are verified and served from the cache.  Chart URLs in the
+index are rewritten to 'charts/' and the archive's file name, relative to the
+index, whether the museum advertises absolute URLs or is under a context path.
+Only GET and HEAD requests are accepted.  With -v, each request is logged.`,
+			Args: cobra.NoArgs,
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringVar(&c.address, "address", "127.0.0.1", "Address to listen on")
+	flgs.IntVar(&c.port, "port", defaultPort, "Port to listen on; 0 picks a free port")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	if c.port < 0 || c.port > 65535 {
+		return cmdutil.UsageErrorf(cmd, "--port must be between 0 and 65535, got %d", c.port)
+	}
+
+	var err error
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	name := c.m.CurrentName()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	l, err := net.Listen("tcp", net.JoinHostPort(c.address, strconv.Itoa(c.port)))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on '%s' port %d", c.address, c.port)
+	}
+
+	srv := &http.Server{
+		Handler: &handler{client: client, logf: c.Logger.Verbosef},
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+	fmt.Fprintf(os.Stdout, "Serving museum '%s' at http://%s\n", name, l.Addr().String())
+
+	select {
+	case err = <-errs:
+		return errors.Wrapf(err, "Failed to serve museum '%s'", name)
+	case <-ctx.Done():
+	}
+
+	sctx, scancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer scancel()
+	if err = srv.Shutdown(sctx); err != nil {
+		return errors.Wrapf(err, "Failed to stop serving museum '%s'", name)
+	}
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
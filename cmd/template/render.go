@@ -0,0 +1,123 @@
+package template
+
+import (
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/strvals"
+	"k8s.io/helm/pkg/timeconv"
+	"k8s.io/helm/pkg/version"
+)
+
+const notesName = "NOTES.txt"
+
+// rendered is a rendered template
+type rendered struct {
+	Name    string
+	Content string
+}
+
+// values merges the values files, in order, and then the `--set` and
+// `--set-string` values, the same way as `helm template`
+func values(files, set, setString []string) ([]byte, error) {
+	base := map[string]interface{}{}
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read values file '%s'", f)
+		}
+		current := map[string]interface{}{}
+		if err = yaml.Unmarshal(b, &current); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse values file '%s'", f)
+		}
+		base = mergeValues(base, current)
+	}
+
+	for _, s := range set {
+		if err := strvals.ParseInto(s, base); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse --set '%s'", s)
+		}
+	}
+	for _, s := range setString {
+		if err := strvals.ParseIntoString(s, base); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse --set-string '%s'", s)
+		}
+	}
+
+	return yaml.Marshal(base)
+}
+
+// mergeValues merges `src` into `dst`; maps are merged recursively, and any
+// other value in `src` replaces the one in `dst`
+func mergeValues(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		srcMap, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dstMap, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dst[k] = mergeValues(dstMap, srcMap)
+	}
+	return dst
+}
+
+// render renders the chart's templates with the user's values, and returns
+// the manifests sorted by name.  Partials, NOTES.txt, and templates that
+// render to whitespace are omitted.
+func render(ch *chart.Chart, raw []byte, releaseName, namespace string) ([]*rendered, error) {
+	config := &chart.Config{Raw: string(raw), Values: map[string]*chart.Value{}}
+
+	if err := chartutil.ProcessRequirementsEnabled(ch, config); err != nil {
+		return nil, errors.Wrapf(err, "Failed to process the requirements of chart '%s'", ch.Metadata.Name)
+	}
+	if err := chartutil.ProcessRequirementsImportValues(ch); err != nil {
+		return nil, errors.Wrapf(err, "Failed to import the values of the requirements of chart '%s'", ch.Metadata.Name)
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: namespace,
+		Time:      timeconv.Now(),
+		Revision:  1,
+		IsInstall: true,
+	}
+	caps := &chartutil.Capabilities{
+		APIVersions:   chartutil.DefaultVersionSet,
+		KubeVersion:   chartutil.DefaultKubeVersion,
+		TillerVersion: version.GetVersionProto(),
+	}
+	vals, err := chartutil.ToRenderValuesCaps(ch, config, options, caps)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to compose the values of chart '%s'", ch.Metadata.Name)
+	}
+
+	out, err := engine.New().Render(ch, vals)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to render chart '%s'", ch.Metadata.Name)
+	}
+
+	manifests := []*rendered{}
+	for name, content := range out {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || base == notesName || strings.TrimSpace(content) == "" {
+			continue
+		}
+		manifests = append(manifests, &rendered{Name: name, Content: content})
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Name < manifests[j].Name })
+
+	return manifests, nil
+}
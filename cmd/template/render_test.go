@@ -0,0 +1,115 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func testChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"},
+		Values:   &chart.Config{Raw: "replicas: 1\nimage:\n  tag: \"1.0\"\n"},
+		Templates: []*chart.Template{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "foo.name" }}{{ .Release.Name }}-foo{{ end }}`)},
+			{Name: "templates/deployment.yaml", Data: []byte("name: {{ template \"foo.name\" . }}\nnamespace: {{ .Release.Namespace }}\nreplicas: {{ .Values.replicas }}\ntag: {{ .Values.image.tag }}\n")},
+			{Name: "templates/empty.yaml", Data: []byte("{{ if false }}never{{ end }}\n")},
+			{Name: "templates/NOTES.txt", Data: []byte("Thanks\n")},
+		},
+	}
+}
+
+func Test_Template_Render(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	valuesFile := filepath.Join(root, "values.yaml")
+	ioutil.WriteFile(valuesFile, []byte("replicas: 3\nimage:\n  tag: \"2.0\"\n"), 0644)
+
+	tcs := []struct {
+		name      string
+		files     []string
+		set       []string
+		setString []string
+		expected  string
+	}{
+		{
+			name:     "defaults",
+			expected: "name: rel-foo\nnamespace: ns\nreplicas: 1\ntag: 1.0\n",
+		},
+		{
+			name:     "values file",
+			files:    []string{valuesFile},
+			expected: "name: rel-foo\nnamespace: ns\nreplicas: 3\ntag: 2.0\n",
+		},
+		{
+			name:     "set overrides file",
+			files:    []string{valuesFile},
+			set:      []string{"replicas=5"},
+			expected: "name: rel-foo\nnamespace: ns\nreplicas: 5\ntag: 2.0\n",
+		},
+		{
+			name:      "set string",
+			setString: []string{"image.tag=3"},
+			expected:  "name: rel-foo\nnamespace: ns\nreplicas: 1\ntag: 3\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := values(tc.files, tc.set, tc.setString)
+			if err != nil {
+				t.Fatalf("Unexpected error from values:\n%s", err.Error())
+			}
+			manifests, err := render(testChart(), raw, "rel", "ns")
+			if err != nil {
+				t.Fatalf("Unexpected error from render:\n%s", err.Error())
+			}
+			if len(manifests) != 1 || manifests[0].Name != "foo/templates/deployment.yaml" {
+				t.Fatalf("Incorrect manifests: %#v", manifests)
+			}
+			if manifests[0].Content != tc.expected {
+				t.Errorf("Incorrect content; expected:\n%s\nactual:\n%s", tc.expected, manifests[0].Content)
+			}
+		})
+	}
+}
+
+func Test_Template_Values_Errors(t *testing.T) {
+	tcs := []struct {
+		name  string
+		files []string
+		set   []string
+	}{
+		{name: "missing file", files: []string{"/does/not/exist.yaml"}},
+		{name: "invalid set", set: []string{"a.=b"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := values(tc.files, tc.set, nil); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func Test_Template_WriteFiles(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	manifests := []*rendered{{Name: "foo/templates/deployment.yaml", Content: "kind: Deployment\n"}}
+	var out strings.Builder
+	if err := writeFiles(&out, root, manifests); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(root, "foo", "templates", "deployment.yaml"))
+	if err != nil || string(b) != "kind: Deployment\n" {
+		t.Errorf("Incorrect file; read '%s', error %v", b, err)
+	}
+}
@@ -0,0 +1,191 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	defaultReleaseName = "release-name"
+	defaultNamespace   = "default"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+
+	valueFiles  []string
+	set         []string
+	setString   []string
+	releaseName string
+	namespace   string
+	outputDir   string
+
+	chartname string
+	version   string
+}
+
+// CreateCommand returns the 'template' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "template CHART [VERSION]",
+			Short: "template renders a chart's manifests locally",
+			Long: `template downloads a chart's archive, and renders its templates with the
+chart's default values, the values files, and the --set values, without Tiller
+or a helm binary.  If VERSION is not provided, the newest version is rendered.
+
+The manifests are written as a YAML stream, or, with --output-dir, to one file
+per template.`,
+			Args: cobra.RangeArgs(1, 2),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.StringArrayVarP(&c.valueFiles, "values", "f", []string{}, "Values file; may be repeated, and later files take precedence")
+	flgs.StringArrayVar(&c.set, "set", []string{}, "Set a value, i.e., `key1=val1,key2=val2`; may be repeated")
+	flgs.StringArrayVar(&c.setString, "set-string", []string{}, "Set a string value, i.e., `key1=val1,key2=val2`; may be repeated")
+	flgs.StringVar(&c.releaseName, "release-name", defaultReleaseName, "Release name used in the templates")
+	flgs.StringVar(&c.namespace, "namespace", defaultNamespace, "Release namespace used in the templates")
+	flgs.StringVar(&c.outputDir, "output-dir", "", "Write each manifest to a file under this directory, instead of to STDOUT")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartname = strings.TrimSpace(args[0])
+	if len(args) == 2 {
+		c.version = strings.TrimSpace(args[1])
+	}
+
+	var err error
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	// Read the values first, so that a mistake does not wait for the museum.
+	raw, err := values(c.valueFiles, c.set, c.setString)
+	if err != nil {
+		return err
+	}
+
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	client, err := c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ch, _, err := client.Load(ctx, c.chartname, c.version)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := render(ch, raw, c.releaseName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	if c.outputDir != "" {
+		return writeFiles(os.Stdout, c.outputDir, manifests)
+	}
+	return writeStream(os.Stdout, manifests)
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// writeStream writes the manifests as a YAML stream, each preceded by its
+// source, like `helm template`
+func writeStream(w io.Writer, manifests []*rendered) error {
+	for _, m := range manifests {
+		content := m.Content
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if _, err := fmt.Fprintf(w, "---\n# Source: %s\n%s", m.Name, content); err != nil {
+			return errors.Wrapf(err, "Failed to write manifest '%s'", m.Name)
+		}
+	}
+	return nil
+}
+
+// writeFiles writes each manifest to its path under `dir`, and reports the
+// files that it wrote to `w`
+func writeFiles(w io.Writer, dir string, manifests []*rendered) error {
+	for _, m := range manifests {
+		p := filepath.Join(dir, filepath.FromSlash(m.Name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return errors.Wrapf(err, "Failed to create directory for '%s'", p)
+		}
+		if err := ioutil.WriteFile(p, []byte(m.Content), 0644); err != nil {
+			return errors.Wrapf(err, "Failed to write '%s'", p)
+		}
+		fmt.Fprintf(w, "wrote %s\n", p)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+//+build test_integration
+
+package template
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Template(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{
+		Name:    "foo",
+		Version: "1.0.0",
+		Files: map[string]string{
+			"values.yaml":               "replicas: 1\n",
+			"templates/deployment.yaml": "replicas: {{ .Values.replicas }}\n",
+		},
+	})
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	root, config := fixture.Root, fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "template", "foo", "--config", config, "--set", "replicas=2")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	expected := "---\n# Source: foo/templates/deployment.yaml\nreplicas: 2\n"
+	if out != expected {
+		t.Errorf("Incorrect output; expected:\n%s\nactual:\n%s", expected, out)
+	}
+
+	dir := path.Join(root, "out")
+	if _, exitCode = ctesting.RunChurl(t, "template", "foo", "1.0.0", "--config", config, "--output-dir", dir); exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	b, err := ioutil.ReadFile(path.Join(dir, "foo", "templates", "deployment.yaml"))
+	if err != nil || string(b) != "replicas: 1\n" {
+		t.Errorf("Incorrect file; read '%s', error %v", b, err)
+	}
+}
@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeText writes the tree with box-drawing characters, followed by the
+// reason that each flagged chart was flagged
+func writeText(w io.Writer, root *node) error {
+	fmt.Fprintln(w, describe(root))
+	writeChildren(w, root, "")
+
+	root.walk(func(n *node) {
+		if n.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", n.Name, n.Error)
+		}
+	})
+	return nil
+}
+
+func writeChildren(w io.Writer, n *node, prefix string) {
+	for k, d := range n.Dependencies {
+		branch, indent := "├── ", "│   "
+		if k == len(n.Dependencies)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, describe(d))
+		writeChildren(w, d, prefix+indent)
+	}
+}
+
+// describe returns a node's line in the text tree, i.e.,
+// `bar 1.2.0 (^1.0.0 from dev)`
+func describe(n *node) string {
+	var sb strings.Builder
+	sb.WriteString(n.Name)
+	if n.Version != "" {
+		sb.WriteString(" ")
+		sb.WriteString(n.Version)
+	}
+
+	source := n.Museum
+	if n.Vendored {
+		source = "vendored"
+	}
+	switch {
+	case n.Constraint != "" && source != "":
+		fmt.Fprintf(&sb, " (%s from %s)", n.Constraint, source)
+	case n.Constraint != "":
+		fmt.Fprintf(&sb, " (%s)", n.Constraint)
+	case source != "":
+		fmt.Fprintf(&sb, " (%s)", source)
+	}
+
+	if n.Problem != "" {
+		fmt.Fprintf(&sb, " [%s]", n.Problem)
+	}
+	return sb.String()
+}
+
+// writeDot writes the tree as a Graphviz digraph.  A chart at one version is
+// one vertex, however often it is depended on; each edge is labeled with the
+// constraint, and flagged charts are red.
+func writeDot(w io.Writer, root *node) error {
+	fmt.Fprintf(w, "digraph %q {\n", root.Name)
+
+	seen := map[string]bool{}
+	var visit func(n *node)
+	visit = func(n *node) {
+		id := vertex(n)
+		if !seen[id] || n.Problem != "" {
+			attrs := fmt.Sprintf("label=%q", strings.TrimSpace(n.Name+"\n"+n.Version))
+			if n.Problem != "" {
+				attrs += fmt.Sprintf(", color=red, tooltip=%q", n.Error)
+			}
+			fmt.Fprintf(w, "  %q [%s];\n", id, attrs)
+		}
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+
+		for _, d := range n.Dependencies {
+			visit(d)
+			if d.Constraint != "" {
+				fmt.Fprintf(w, "  %q -> %q [label=%q];\n", id, vertex(d), d.Constraint)
+			} else {
+				fmt.Fprintf(w, "  %q -> %q;\n", id, vertex(d))
+			}
+		}
+	}
+	visit(root)
+
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// vertex returns the ID of a node's vertex; a chart without a version is
+// identified by its constraint
+func vertex(n *node) string {
+	if n.Version != "" {
+		return n.Name + "@" + n.Version
+	}
+	return n.Name + "@" + n.Constraint
+}
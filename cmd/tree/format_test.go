@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+)
+
+func testTree() *node {
+	return &node{
+		Name:    "foo",
+		Version: "1.0.0",
+		Museum:  "dev",
+		Dependencies: []*node{
+			{
+				Name:       "bar",
+				Version:    "1.2.0",
+				Constraint: "^1.0.0",
+				Museum:     "dev",
+				Dependencies: []*node{
+					{Name: "common", Version: "0.1.0", Vendored: true},
+				},
+			},
+			{Name: "qux", Constraint: "^3.0.0", Museum: "dev", Problem: Unsatisfied, Error: "No version"},
+		},
+	}
+}
+
+func Test_Tree_WriteText(t *testing.T) {
+	var sb strings.Builder
+	if err := writeText(&sb, testTree()); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	expected := `foo 1.0.0 (dev)
+├── bar 1.2.0 (^1.0.0 from dev)
+│   └── common 0.1.0 (vendored)
+└── qux (^3.0.0 from dev) [unsatisfied]
+qux: No version
+`
+	if sb.String() != expected {
+		t.Errorf("Incorrect tree; expected:\n%s\nactual:\n%s", expected, sb.String())
+	}
+}
+
+func Test_Tree_WriteDot(t *testing.T) {
+	var sb strings.Builder
+	if err := writeDot(&sb, testTree()); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	expected := `digraph "foo" {
+  "foo@1.0.0" [label="foo\n1.0.0"];
+  "bar@1.2.0" [label="bar\n1.2.0"];
+  "common@0.1.0" [label="common\n0.1.0"];
+  "bar@1.2.0" -> "common@0.1.0";
+  "foo@1.0.0" -> "bar@1.2.0" [label="^1.0.0"];
+  "qux@^3.0.0" [label="qux", color=red, tooltip="No version"];
+  "foo@1.0.0" -> "qux@^3.0.0" [label="^3.0.0"];
+}
+`
+	if sb.String() != expected {
+		t.Errorf("Incorrect graph; expected:\n%s\nactual:\n%s", expected, sb.String())
+	}
+}
@@ -0,0 +1,238 @@
+package tree
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/object88/churl/internal/resolve"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Problem is the reason that a dependency is flagged
+type Problem string
+
+const (
+	// Unsatisfied is a dependency that no version in its repository satisfies
+	Unsatisfied Problem = "unsatisfied"
+
+	// Cycle is a dependency on a chart that is already an ancestor
+	Cycle Problem = "cycle"
+
+	// Conflict is a chart that is resolved at more than one version in the
+	// tree
+	Conflict Problem = "conflict"
+
+	// Failed is a dependency whose repository or archive could not be read
+	Failed Problem = "failed"
+)
+
+// node is a chart in the dependency tree
+type node struct {
+	Name         string  `json:"name"`
+	Version      string  `json:"version,omitempty"`
+	Constraint   string  `json:"constraint,omitempty"`
+	Repository   string  `json:"repository,omitempty"`
+	Museum       string  `json:"museum,omitempty"`
+	Vendored     bool    `json:"vendored,omitempty"`
+	Problem      Problem `json:"problem,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	Dependencies []*node `json:"dependencies,omitempty"`
+}
+
+func (n *node) flag(p Problem, err error) {
+	n.Problem = p
+	n.Error = err.Error()
+}
+
+// walk calls `fn` for `n` and each of its descendants, depth first
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, d := range n.Dependencies {
+		d.walk(fn)
+	}
+}
+
+// builder resolves the dependencies of charts recursively
+type builder struct {
+	resolver *resolve.Resolver
+
+	// charts are the archives that were loaded, by museum, name, and version
+	charts map[string]*chart.Chart
+}
+
+func newBuilder(resolver *resolve.Resolver) *builder {
+	return &builder{
+		resolver: resolver,
+		charts:   map[string]*chart.Chart{},
+	}
+}
+
+// build returns the dependency tree of `ch`, from the museum `museum`, with
+// the cycles, unsatisfied constraints, and conflicting versions flagged
+func (b *builder) build(ctx context.Context, ch *chart.Chart, museum string) (*node, error) {
+	root := &node{
+		Name:    ch.Metadata.Name,
+		Version: ch.Metadata.Version,
+		Museum:  museum,
+	}
+	if err := b.dependencies(ctx, root, ch, []string{root.Name}); err != nil {
+		return nil, err
+	}
+	flagConflicts(root)
+	return root, nil
+}
+
+// dependencies adds the dependencies of `ch` to `n`.  A dependency on a
+// repository is resolved against it; any other dependency, and a subchart
+// that is not in the requirements, is read from the chart's charts/
+// directory.
+func (b *builder) dependencies(ctx context.Context, n *node, ch *chart.Chart, ancestors []string) error {
+	reqs, err := chartutil.LoadRequirements(ch)
+	switch {
+	case err == chartutil.ErrRequirementsNotFound:
+		reqs = &chartutil.Requirements{}
+	case err != nil:
+		return errors.Wrapf(err, "Failed to read the requirements of chart '%s'", ch.Metadata.Name)
+	}
+
+	declared := map[string]bool{}
+	for _, dep := range reqs.Dependencies {
+		declared[dep.Name] = true
+
+		d := &node{
+			Name:       dep.Name,
+			Constraint: dep.Version,
+			Repository: dep.Repository,
+		}
+		n.Dependencies = append(n.Dependencies, d)
+
+		var sub *chart.Chart
+		if resolve.IsLocal(dep.Repository) {
+			d.Vendored = true
+			if sub = subchart(ch, dep.Name); sub == nil {
+				d.flag(Failed, errors.Errorf("Dependency '%s' is not vendored in chart '%s'", dep.Name, ch.Metadata.Name))
+				continue
+			}
+			d.Version = sub.Metadata.Version
+		} else if sub = b.resolve(ctx, d); sub == nil {
+			continue
+		}
+
+		if err := b.descend(ctx, d, sub, ancestors); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range ch.Dependencies {
+		if declared[sub.Metadata.Name] {
+			continue
+		}
+		d := &node{
+			Name:     sub.Metadata.Name,
+			Version:  sub.Metadata.Version,
+			Vendored: true,
+		}
+		n.Dependencies = append(n.Dependencies, d)
+		if err := b.descend(ctx, d, sub, ancestors); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// descend adds the dependencies of `sub` to `d`, unless `d` is an ancestor
+func (b *builder) descend(ctx context.Context, d *node, sub *chart.Chart, ancestors []string) error {
+	for _, a := range ancestors {
+		if a == d.Name {
+			path := append(append([]string{}, ancestors...), d.Name)
+			d.flag(Cycle, errors.Errorf("Chart '%s' depends on itself: %s", d.Name, strings.Join(path, " -> ")))
+			return nil
+		}
+	}
+	if sub == nil {
+		return nil
+	}
+	return b.dependencies(ctx, d, sub, append(ancestors, d.Name))
+}
+
+// resolve picks the version of the dependency `d` from its repository, and
+// loads that archive.  If the dependency cannot be resolved, `d` is flagged,
+// and resolve returns nil.
+func (b *builder) resolve(ctx context.Context, d *node) *chart.Chart {
+	name, index, err := b.resolver.Index(ctx, d.Repository)
+	d.Museum = name
+	if err != nil {
+		d.flag(Failed, err)
+		return nil
+	}
+
+	d.Version, _, err = resolve.Newest(index, d.Name, d.Constraint)
+	switch {
+	case err != nil:
+		d.flag(Failed, err)
+		return nil
+	case d.Version == "":
+		d.flag(Unsatisfied, errors.Errorf("No version of chart '%s' in museum '%s' satisfies '%s'", d.Name, name, d.Constraint))
+		return nil
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", name, d.Name, d.Version)
+	if ch, ok := b.charts[key]; ok {
+		return ch
+	}
+
+	_, client, err := b.resolver.Client(ctx, d.Repository)
+	if err != nil {
+		d.flag(Failed, err)
+		return nil
+	}
+	ch, _, err := client.Load(ctx, d.Name, d.Version)
+	if err != nil {
+		d.flag(Failed, err)
+		return nil
+	}
+	b.charts[key] = ch
+	return ch
+}
+
+// subchart returns the subchart `name` under the charts/ directory of `ch`
+func subchart(ch *chart.Chart, name string) *chart.Chart {
+	for _, sub := range ch.Dependencies {
+		if sub.Metadata.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// flagConflicts flags each chart that is resolved at more than one version in
+// the tree.  The version at which a cycle closes is not counted.
+func flagConflicts(root *node) {
+	versions := map[string]map[string]bool{}
+	root.walk(func(n *node) {
+		if n.Version == "" || n.Problem == Cycle {
+			return
+		}
+		if versions[n.Name] == nil {
+			versions[n.Name] = map[string]bool{}
+		}
+		versions[n.Name][n.Version] = true
+	})
+
+	root.walk(func(n *node) {
+		if n.Problem != "" || len(versions[n.Name]) < 2 {
+			return
+		}
+		vs := make([]string, 0, len(versions[n.Name]))
+		for v := range versions[n.Name] {
+			vs = append(vs, v)
+		}
+		sort.Strings(vs)
+		n.flag(Conflict, errors.Errorf("Chart '%s' is resolved at versions %s", n.Name, strings.Join(vs, ", ")))
+	})
+}
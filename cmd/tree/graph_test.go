@@ -0,0 +1,115 @@
+package tree
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/internal/resolve"
+	"github.com/object88/churl/manifest"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+func requires(deps ...string) map[string]string {
+	r := "dependencies:\n"
+	for _, d := range deps {
+		r += d
+	}
+	return map[string]string{"requirements.yaml": r}
+}
+
+func dep(name, constraint, repository string) string {
+	return fmt.Sprintf("- name: %s\n  version: %q\n  repository: %q\n", name, constraint, repository)
+}
+
+func Test_Tree_Build(t *testing.T) {
+	other := churltest.NewServer(
+		&churltest.Chart{Name: "baz", Version: "0.1.0"},
+		&churltest.Chart{Name: "baz", Version: "0.2.0"},
+	)
+	defer other.Close()
+
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "bar", Version: "1.2.0", Files: requires(dep("baz", "~0.1.0", other.URL))},
+		&churltest.Chart{Name: "a", Version: "1.0.0", Files: requires(dep("b", "^1.0.0", "@dev"))},
+		&churltest.Chart{Name: "b", Version: "1.0.0", Files: requires(dep("a", "^1.0.0", "@dev"))},
+	)
+	defer s.Close()
+
+	m := manifest.New()
+	m.Museums["dev"] = s.Museum()
+	connect := func(ctx context.Context, name string, cm *manifest.ChartMuseum) (*churl.Client, error) {
+		return churl.NewClient(ctx, name, cm)
+	}
+
+	tcs := []struct {
+		name     string
+		chart    *churltest.Chart
+		expected map[string]Problem
+	}{
+		{
+			name:     "resolved across museums",
+			chart:    &churltest.Chart{Name: "foo", Version: "1.0.0", Files: requires(dep("bar", "^1.0.0", s.URL))},
+			expected: map[string]Problem{"foo": "", "bar": "", "baz": ""},
+		},
+		{
+			name:     "unsatisfied",
+			chart:    &churltest.Chart{Name: "foo", Version: "1.0.0", Files: requires(dep("bar", "^2.0.0", "@dev"))},
+			expected: map[string]Problem{"foo": "", "bar": Unsatisfied},
+		},
+		{
+			name:     "conflict",
+			chart:    &churltest.Chart{Name: "foo", Version: "1.0.0", Files: requires(dep("bar", "^1.0.0", "@dev"), dep("baz", "~0.2.0", other.URL))},
+			expected: map[string]Problem{"foo": "", "bar": "", "baz": Conflict},
+		},
+		{
+			name:     "cycle",
+			chart:    &churltest.Chart{Name: "a", Version: "1.0.0", Files: requires(dep("b", "^1.0.0", "@dev"))},
+			expected: map[string]Problem{"a": Cycle, "b": ""},
+		},
+		{
+			name:     "unknown museum",
+			chart:    &churltest.Chart{Name: "foo", Version: "1.0.0", Files: requires(dep("bar", "^1.0.0", "@other"))},
+			expected: map[string]Problem{"foo": "", "bar": Failed},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _, err := tc.chart.Archive()
+			if err != nil {
+				t.Fatalf("Failed to create archive:\n%s", err.Error())
+			}
+			ch, err := chartutil.LoadArchive(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("Failed to load chart:\n%s", err.Error())
+			}
+
+			r := resolve.New(m, connect)
+			defer r.Close()
+			root, err := newBuilder(r).build(context.Background(), ch, "dev")
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+
+			actual := map[string]Problem{}
+			root.walk(func(n *node) {
+				// A cycle repeats the ancestor's name; keep the flagged node.
+				if p, ok := actual[n.Name]; !ok || p == "" {
+					actual[n.Name] = n.Problem
+				}
+			})
+			if len(actual) != len(tc.expected) {
+				t.Fatalf("Incorrect charts; expected %v, actual %v", tc.expected, actual)
+			}
+			for name, p := range tc.expected {
+				if actual[name] != p {
+					t.Errorf("Incorrect problem for '%s'; expected '%s', actual '%s'", name, p, actual[name])
+				}
+			}
+		})
+	}
+}
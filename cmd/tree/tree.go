@@ -0,0 +1,202 @@
+package tree
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/internal/resolve"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	dot bool
+
+	chartname string
+	version   string
+}
+
+// CreateCommand returns the 'tree' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "tree CHART [VERSION]",
+			Short: "tree shows a chart's dependencies, resolved recursively",
+			Long: `tree downloads a chart, and resolves its dependencies against the museums
+and repositories that they name, then theirs, and so on.  A repository that is
+a museum in the manifest is reached through that museum; any other repository
+is reached directly.  Subcharts under charts/ are read from the archive.  If
+VERSION is not provided, the newest version is shown.
+
+Dependencies are flagged if no version satisfies their constraint, if they
+depend on an ancestor (a cycle), or if the same chart is resolved at more than
+one version (a conflict).  If a constraint cannot be satisfied, or a
+repository cannot be queried, tree fails after writing the tree.
+
+The tree is written as text, as JSON or YAML with --output, or as a Graphviz
+graph with --dot.`,
+			Args: cobra.RangeArgs(1, 2),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.BoolVar(&c.dot, "dot", false, "Write the tree as a Graphviz DOT graph")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.chartname = strings.TrimSpace(args[0])
+	if len(args) == 2 {
+		c.version = strings.TrimSpace(args[1])
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	name := c.m.CurrentName()
+
+	ctx := context.Background()
+	resolver := resolve.New(c.m, c.connect)
+	defer resolver.Close()
+
+	// The chart's museum is shared with the dependencies that it serves.
+	_, client, err := resolver.Client(ctx, "@"+name)
+	if err != nil {
+		return err
+	}
+
+	ch, _, err := client.Load(ctx, c.chartname, c.version)
+	if err != nil {
+		return err
+	}
+
+	root, err := newBuilder(resolver).build(ctx, ch, name)
+	if err != nil {
+		return err
+	}
+
+	if err = c.write(os.Stdout, root); err != nil {
+		return err
+	}
+
+	failed := []string{}
+	root.walk(func(n *node) {
+		if n.Problem == Unsatisfied || n.Problem == Failed {
+			failed = append(failed, n.Name)
+		}
+	})
+	if len(failed) != 0 {
+		return errors.Errorf("Failed to resolve %d dependencies of chart '%s': '%s'", len(failed), root.Name, strings.Join(failed, "', '"))
+	}
+
+	return nil
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
+
+// connect creates a client for a dependency's repository
+func (c *command) connect(ctx context.Context, name string, cm *manifest.ChartMuseum) (*churl.Client, error) {
+	return c.Connect(ctx, c.cflags, c.podTimeout, name, cm)
+}
+
+func (c *command) write(w io.Writer, root *node) error {
+	if c.dot {
+		return writeDot(w, root)
+	}
+
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(root); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependency tree")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(root)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode dependency tree")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	return writeText(w, root)
+}
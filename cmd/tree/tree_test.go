@@ -0,0 +1,54 @@
+//+build test_integration
+
+package tree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/object88/churl/churltest"
+	ctesting "github.com/object88/churl/internal/testing"
+)
+
+func Test_Cmd_Tree(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0", Files: requires(dep("bar", "^1.0.0", "@dev"))},
+		&churltest.Chart{Name: "foo", Version: "1.1.0", Files: requires(dep("bar", "^2.0.0", "@dev"))},
+		&churltest.Chart{Name: "bar", Version: "1.2.0"},
+	)
+	defer s.Close()
+
+	fixture := ctesting.Config(t, ctesting.Museum{Name: "dev", URL: s.URL})
+	defer fixture.Close()
+	config := fixture.Config
+
+	out, exitCode := ctesting.RunChurl(t, "tree", "foo", "1.0.0", "--config", config)
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	expected := "foo 1.0.0 (dev)\n└── bar 1.2.0 (^1.0.0 from dev)\n"
+	if out != expected {
+		t.Errorf("Incorrect tree; expected:\n%s\nactual:\n%s", expected, out)
+	}
+
+	out, exitCode = ctesting.RunChurl(t, "tree", "foo", "1.0.0", "--config", config, "--output", "json")
+	if exitCode != 0 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	n := &node{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(n); err != nil {
+		t.Fatalf("Failed to decode tree:\n%s", err.Error())
+	}
+	if len(n.Dependencies) != 1 || n.Dependencies[0].Version != "1.2.0" {
+		t.Errorf("Incorrect tree:\n%s", out)
+	}
+
+	out, exitCode = ctesting.RunChurl(t, "tree", "foo", "--config", config, "--dot")
+	if exitCode != 1 {
+		t.Fatalf("Unexpected exit code %d", exitCode)
+	}
+	if !strings.Contains(out, `"bar@^2.0.0" [label="bar", color=red`) {
+		t.Errorf("Graph does not flag the unsatisfied dependency:\n%s", out)
+	}
+}
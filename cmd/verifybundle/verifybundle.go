@@ -0,0 +1,129 @@
+package verifybundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/bundle"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	output flags.Output
+}
+
+// report is the result of verifying a bundle
+type report struct {
+	Bundle    string            `json:"bundle"`
+	Museum    string            `json:"museum"`
+	Timestamp time.Time         `json:"timestamp"`
+	Charts    int               `json:"charts"`
+	Files     int               `json:"files"`
+	Problems  []*bundle.Problem `json:"problems"`
+}
+
+// CreateCommand returns the 'verify-bundle' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "verify-bundle BUNDLE",
+			Short: "verify-bundle checks that a bundle matches its manifest",
+			Long: `verify-bundle reads a bundle written by 'churl export', and checks every file
+against the size and digest in its manifest, every chart archive against the
+chart's digest, and the bundle's index.yaml against the manifest's charts.
+Files that are missing, or that are not in the manifest, are reported.
+
+verify-bundle does not use the manifest or contact a museum.  It fails if the
+bundle has any problem.`,
+			Args: cobra.ExactArgs(1),
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	return err
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open bundle '%s'", args[0])
+	}
+	defer f.Close()
+
+	m, problems, err := bundle.Verify(f)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read bundle '%s'", args[0])
+	}
+
+	r := &report{
+		Bundle:    args[0],
+		Museum:    m.Museum,
+		Timestamp: m.Timestamp,
+		Charts:    len(m.Charts),
+		Files:     len(m.Files),
+		Problems:  problems,
+	}
+	if err = c.write(os.Stdout, r); err != nil {
+		return err
+	}
+
+	if len(problems) != 0 {
+		return errors.Errorf("Bundle '%s' has %d problems", args[0], len(problems))
+	}
+	return nil
+}
+
+func (c *command) write(w io.Writer, r *report) error {
+	switch c.output {
+	case flags.JSON, flags.JSONCompact:
+		enc := json.NewEncoder(w)
+		if c.output == flags.JSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(r); err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode bundle report")
+		}
+		return nil
+	case flags.Yaml:
+		b, err := yaml.Marshal(r)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode bundle report")
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	fmt.Fprintf(w, "Bundle '%s' of museum '%s' at %s: %d chart versions, %d files\n", r.Bundle, r.Museum, r.Timestamp.Format(time.RFC3339), r.Charts, r.Files)
+	for _, p := range r.Problems {
+		fmt.Fprintf(w, "%s: %s\n", p.Path, p.Message)
+	}
+	if len(r.Problems) == 0 {
+		fmt.Fprintln(w, "OK")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+//+build test_integration
+
+package verifybundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/object88/churl/bundle"
+	ctesting "github.com/object88/churl/internal/testing"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Cmd_VerifyBundle(t *testing.T) {
+	fixture := ctesting.Config(t)
+	defer fixture.Close()
+
+	archive := []byte("foo archive")
+	h := sha256.Sum256(archive)
+	cv := &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"},
+		URLs:     []string{"charts/foo-1.0.0.tgz"},
+		Digest:   hex.EncodeToString(h[:]),
+	}
+
+	var buf bytes.Buffer
+	w := bundle.NewWriter(&buf, "prod", time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC))
+	if err := w.Add(cv, archive, nil); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	good := path.Join(fixture.Root, "good.tgz")
+	ioutil.WriteFile(good, buf.Bytes(), 0644)
+	bad := path.Join(fixture.Root, "bad.tgz")
+	ioutil.WriteFile(bad, replaceFile(t, buf.Bytes(), "charts/foo-1.0.0.tgz", []byte("tampered")), 0644)
+
+	tcs := []struct {
+		name     string
+		bundle   string
+		exitCode int
+		problems []string
+	}{
+		{name: "intact", bundle: good, exitCode: 0, problems: []string{}},
+		{name: "modified archive", bundle: bad, exitCode: 1, problems: []string{"charts/foo-1.0.0.tgz"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out, exitCode := ctesting.RunChurl(t, "verify-bundle", tc.bundle, "--output", "json")
+			if exitCode != tc.exitCode {
+				t.Fatalf("Unexpected exit code %d", exitCode)
+			}
+
+			var r report
+			if err := json.NewDecoder(strings.NewReader(out)).Decode(&r); err != nil {
+				t.Fatalf("Failed to decode report:\n%s", err.Error())
+			}
+			if r.Museum != "prod" || r.Charts != 1 {
+				t.Errorf("Incorrect report: %+v", r)
+			}
+			actual := []string{}
+			for _, p := range r.Problems {
+				actual = append(actual, p.Path)
+			}
+			if strings.Join(actual, ",") != strings.Join(tc.problems, ",") {
+				t.Errorf("Incorrect problems; expected %v, actual %v", tc.problems, actual)
+			}
+		})
+	}
+
+	if _, exitCode := ctesting.RunChurl(t, "verify-bundle", path.Join(fixture.Root, "missing.tgz")); exitCode != 1 {
+		t.Errorf("Unexpected exit code %d for a missing bundle", exitCode)
+	}
+}
+
+// replaceFile copies the bundle `b`, replacing the contents of the file `name`
+func replaceFile(t *testing.T, b []byte, name string, content []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error:\n%s", err.Error())
+		}
+		c, _ := ioutil.ReadAll(tr)
+		if hdr.Name == name {
+			c = content
+		}
+		hdr.Size = int64(len(c))
+		tw.WriteHeader(hdr)
+		tw.Write(c)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
@@ -34,10 +34,6 @@ func CreateCommand() *cobra.Command {
 		},
 	}
 
-	flgs := c.Flags()
-
-	flags.CreateOutputFlag(flgs)
-
 	return traverse.TraverseRunHooks(&c.Command)
 }
 
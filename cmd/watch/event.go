@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+// EventType is the kind of change to a chart version
+type EventType string
+
+const (
+	// Added is a chart version that the museum did not have
+	Added EventType = "added"
+
+	// Removed is a chart version that the museum no longer has
+	Removed EventType = "removed"
+
+	// Changed is a chart version whose archive has a different digest, i.e.,
+	// it was overwritten
+	Changed EventType = "changed"
+)
+
+// event is a change to a chart version in the museum
+type event struct {
+	Time           time.Time `json:"time"`
+	Type           EventType `json:"type"`
+	Museum         string    `json:"museum"`
+	Chart          string    `json:"chart"`
+	Version        string    `json:"version"`
+	Digest         string    `json:"digest,omitempty"`
+	PreviousDigest string    `json:"previousDigest,omitempty"`
+}
+
+// snapshot is the digest of each version of each chart, by chart and version
+type snapshot map[string]map[string]string
+
+// newSnapshot returns the versions in the index of the charts in `charts`, or
+// of every chart if `charts` is empty
+func newSnapshot(index *repo.IndexFile, charts map[string]bool) snapshot {
+	s := snapshot{}
+	for name, cvs := range index.Entries {
+		if len(charts) != 0 && !charts[name] {
+			continue
+		}
+		versions := map[string]string{}
+		for _, cv := range cvs {
+			versions[cv.Version] = cv.Digest
+		}
+		s[name] = versions
+	}
+	return s
+}
+
+// diff returns the events that change `prev` into `next`, ordered by chart and
+// version
+func diff(prev, next snapshot, museum string, now time.Time) []*event {
+	events := []*event{}
+	add := func(t EventType, chart, version, digest, previous string) {
+		events = append(events, &event{Time: now, Type: t, Museum: museum, Chart: chart, Version: version, Digest: digest, PreviousDigest: previous})
+	}
+
+	for chart, versions := range next {
+		for version, digest := range versions {
+			previous, ok := prev[chart][version]
+			switch {
+			case !ok:
+				add(Added, chart, version, digest, "")
+			case previous != digest:
+				add(Changed, chart, version, digest, previous)
+			}
+		}
+	}
+	for chart, versions := range prev {
+		for version, previous := range versions {
+			if _, ok := next[chart][version]; !ok {
+				add(Removed, chart, version, "", previous)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Chart != b.Chart {
+			return a.Chart < b.Chart
+		}
+		return lessVersion(a.Version, b.Version)
+	})
+	return events
+}
+
+// lessVersion orders semantic versions by precedence, and any other versions
+// as strings
+func lessVersion(a, b string) bool {
+	va, erra := semver.NewVersion(a)
+	vb, errb := semver.NewVersion(b)
+	if erra != nil || errb != nil {
+		return a < b
+	}
+	return va.LessThan(vb)
+}
+
+// readSnapshot reads the snapshot that a previous watch saved to `p`.  If
+// there is no such file, the snapshot is nil.
+func readSnapshot(p string) (snapshot, error) {
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read state file '%s'", p)
+	}
+	s := snapshot{}
+	if err = json.Unmarshal(b, &s); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode state file '%s'", p)
+	}
+	return s, nil
+}
+
+// writeSnapshot saves the snapshot to `p`, replacing it atomically so that a
+// watch that is stopped while writing does not leave a partial file
+func writeSnapshot(p string, s snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrapf(err, "Internal error: failed to encode state")
+	}
+	tmp := p + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.Wrapf(err, "Failed to write state file '%s'", p)
+	}
+	if err = os.Rename(tmp, p); err != nil {
+		return errors.Wrapf(err, "Failed to write state file '%s'", p)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Watch_Diff(t *testing.T) {
+	prev := snapshot{
+		"foo": {"1.0.0": "a", "1.1.0": "b"},
+		"bar": {"2.0.0": "c"},
+	}
+	tcs := []struct {
+		name     string
+		next     snapshot
+		expected []string
+	}{
+		{name: "unchanged", next: prev},
+		{name: "added", next: snapshot{"foo": {"1.0.0": "a", "1.1.0": "b", "1.10.0": "d", "1.2.0": "e"}, "bar": {"2.0.0": "c"}, "baz": {"0.1.0": "f"}}, expected: []string{"added baz-0.1.0", "added foo-1.2.0", "added foo-1.10.0"}},
+		{name: "removed", next: snapshot{"foo": {"1.1.0": "b"}}, expected: []string{"removed bar-2.0.0", "removed foo-1.0.0"}},
+		{name: "changed", next: snapshot{"foo": {"1.0.0": "a", "1.1.0": "x"}, "bar": {"2.0.0": "c"}}, expected: []string{"changed foo-1.1.0"}},
+	}
+
+	now := time.Now().UTC()
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			events := diff(prev, tc.next, "dev", now)
+			if len(events) != len(tc.expected) {
+				t.Fatalf("Incorrect number of events; expected %d, actual %d", len(tc.expected), len(events))
+			}
+			for k, e := range events {
+				if actual := fmt.Sprintf("%s %s-%s", e.Type, e.Chart, e.Version); actual != tc.expected[k] {
+					t.Errorf("Incorrect event %d; expected '%s', actual '%s'", k, tc.expected[k], actual)
+				}
+				if e.Museum != "dev" || e.Time != now {
+					t.Errorf("Incorrect event %d: %+v", k, e)
+				}
+			}
+		})
+	}
+
+	e := diff(prev, snapshot{"foo": {"1.0.0": "a", "1.1.0": "x"}, "bar": {"2.0.0": "c"}}, "dev", now)[0]
+	if e.Digest != "x" || e.PreviousDigest != "b" {
+		t.Errorf("Incorrect digests; expected 'x' (was 'b'), actual '%s' (was '%s')", e.Digest, e.PreviousDigest)
+	}
+}
+
+func Test_Watch_Snapshot(t *testing.T) {
+	i := repo.NewIndexFile()
+	i.Add(&chart.Metadata{Name: "foo", Version: "1.0.0"}, "foo-1.0.0.tgz", "", "a")
+	i.Add(&chart.Metadata{Name: "bar", Version: "2.0.0"}, "bar-2.0.0.tgz", "", "b")
+
+	s := newSnapshot(i, map[string]bool{"foo": true})
+	if len(s) != 1 || s["foo"]["1.0.0"] != "a" {
+		t.Errorf("Incorrect snapshot: %v", s)
+	}
+	if s = newSnapshot(i, map[string]bool{}); len(s) != 2 {
+		t.Errorf("Incorrect snapshot: %v", s)
+	}
+
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	p := path.Join(root, "state.json")
+
+	if s, err := readSnapshot(p); err != nil || s != nil {
+		t.Fatalf("Expected no snapshot; got %v, %v", s, err)
+	}
+	if err := writeSnapshot(p, newSnapshot(i, nil)); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	read, err := readSnapshot(p)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if len(diff(s, read, "dev", time.Now())) != 0 {
+		t.Errorf("Incorrect snapshot after reading: %v", read)
+	}
+}
@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/pkg/errors"
+)
+
+// Amount of time to wait for a webhook to respond
+const webhookTimeout = 10 * time.Second
+
+// hook is told of each event
+type hook func(ctx context.Context, e *event) error
+
+// printer returns a hook that writes each event to `w`, in the output format
+func printer(w io.Writer, output flags.Output) hook {
+	return func(ctx context.Context, e *event) error {
+		switch output {
+		case flags.JSON, flags.JSONCompact:
+			enc := json.NewEncoder(w)
+			if output == flags.JSON {
+				enc.SetIndent("", "  ")
+			}
+			if err := enc.Encode(e); err != nil {
+				return errors.Wrapf(err, "Internal error: failed to encode event")
+			}
+			return nil
+		case flags.Yaml:
+			b, err := yaml.Marshal(e)
+			if err != nil {
+				return errors.Wrapf(err, "Internal error: failed to encode event")
+			}
+			_, err = fmt.Fprintf(w, "---\n%s", b)
+			return err
+		}
+
+		line := fmt.Sprintf("%s %s %s/%s %s", e.Time.Format(time.RFC3339), e.Type, e.Museum, e.Chart, e.Version)
+		switch e.Type {
+		case Added:
+			line += " " + e.Digest
+		case Changed:
+			line += fmt.Sprintf(" %s (was %s)", e.Digest, e.PreviousDigest)
+		}
+		_, err := fmt.Fprintln(w, line)
+		return err
+	}
+}
+
+// runner returns a hook that runs `command` with the shell for each event.
+// The event is written to the command's stdin as JSON, and its fields are set
+// in the environment.  The command's output is written to `out`.
+func runner(command string, out io.Writer) hook {
+	return func(ctx context.Context, e *event) error {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode event")
+		}
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(b)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		cmd.Env = append(os.Environ(),
+			"CHURL_EVENT="+string(e.Type),
+			"CHURL_MUSEUM="+e.Museum,
+			"CHURL_CHART="+e.Chart,
+			"CHURL_VERSION="+e.Version,
+			"CHURL_DIGEST="+e.Digest,
+			"CHURL_PREVIOUS_DIGEST="+e.PreviousDigest,
+		)
+		if err = cmd.Run(); err != nil {
+			return errors.Wrapf(err, "Command for %s %s-%s failed", e.Type, e.Chart, e.Version)
+		}
+		return nil
+	}
+}
+
+// poster returns a hook that POSTs each event to `url` as JSON
+func poster(url string) hook {
+	client := &http.Client{Timeout: webhookTimeout}
+	return func(ctx context.Context, e *event) error {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return errors.Wrapf(err, "Internal error: failed to encode event")
+		}
+
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create webhook request")
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return errors.Wrapf(err, "Webhook for %s %s-%s failed", e.Type, e.Chart, e.Version)
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return errors.Errorf("Webhook for %s %s-%s failed: %s", e.Type, e.Chart, e.Version, resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+package watch
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/cmd/common"
+	"github.com/object88/churl/cmd/flags"
+	"github.com/object88/churl/cmd/traverse"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	// Amount of time to wait until at least one pod is running
+	defaultPodPortForwardWaitTimeout = 2 * time.Second
+
+	// Amount of time between polls of the index
+	defaultInterval = 30 * time.Second
+
+	// Longest amount of time between attempts to reach an unreachable museum
+	defaultMaxBackoff = 5 * time.Minute
+)
+
+type command struct {
+	cobra.Command
+	*common.CommonArgs
+
+	m *manifest.Manifest
+
+	cflags     *genericclioptions.ConfigFlags
+	podTimeout time.Duration
+	output     flags.Output
+
+	interval   time.Duration
+	maxBackoff time.Duration
+	exec       string
+	webhook    string
+	state      string
+
+	charts map[string]bool
+}
+
+// CreateCommand returns the 'watch' subcommand
+func CreateCommand(ca *common.CommonArgs) *cobra.Command {
+	var c *command
+
+	c = &command{
+		Command: cobra.Command{
+			Use:   "watch [CHART...]",
+			Short: "watch reports chart versions as they are added to or removed from the museum",
+			Long: `watch polls the museum's index every --interval, and writes an event whenever
+a version of CHART, or of any chart if none are provided, is added, removed,
+or changed, i.e., overwritten with a different digest.  The index is
+revalidated with its ETag, so an unchanged index is not downloaded again.
+
+The first poll records the index, and later polls are compared against it.
+With --state, the index is saved to a file after each poll, and a restarted
+watch reports the changes that it missed.
+
+With --exec, each event runs a shell command, with the event on stdin as JSON,
+and in the environment as CHURL_EVENT, CHURL_MUSEUM, CHURL_CHART,
+CHURL_VERSION, CHURL_DIGEST, and CHURL_PREVIOUS_DIGEST.  With --webhook, each
+event is POSTed to the URL as JSON.  A failed command or webhook is reported
+to stderr, and the watch continues.
+
+If the museum cannot be reached, i.e., because the port forward dropped, the
+watch connects again, waiting twice as long after each failure, up to
+--max-backoff.  The watch runs until it is interrupted or terminated.`,
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Preexecute(cmd, args)
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.Execute(cmd, args)
+			},
+			PostRunE: func(cmd *cobra.Command, args []string) error {
+				return c.Postexecute(cmd, args)
+			},
+		},
+		CommonArgs: ca,
+	}
+
+	flgs := c.Flags()
+
+	flgs.DurationVar(&c.interval, "interval", defaultInterval, "Time between polls of the index")
+	flgs.DurationVar(&c.maxBackoff, "max-backoff", defaultMaxBackoff, "Longest time between attempts to reach the museum after a failure")
+	flgs.StringVar(&c.exec, "exec", "", "Shell command to run for each event")
+	flgs.StringVar(&c.webhook, "webhook", "", "URL to POST each event to")
+	flgs.StringVar(&c.state, "state", "", "File to save the index to, so that a restarted watch reports what it missed")
+
+	c.cflags = genericclioptions.NewConfigFlags(false)
+	c.cflags.Namespace = nil
+	c.cflags.AddFlags(flgs)
+
+	cmdutil.AddPodRunningTimeoutFlag(&c.Command, defaultPodPortForwardWaitTimeout)
+
+	return traverse.TraverseRunHooks(&c.Command)
+}
+
+func (c *command) Preexecute(cmd *cobra.Command, args []string) error {
+	c.charts = map[string]bool{}
+	for _, arg := range args {
+		c.charts[strings.TrimSpace(arg)] = true
+	}
+
+	if c.interval <= 0 {
+		return cmdutil.UsageErrorf(cmd, "--interval must be greater than zero; got %s", c.interval)
+	}
+	if c.maxBackoff < c.interval {
+		return cmdutil.UsageErrorf(cmd, "--max-backoff must be at least --interval; got %s", c.maxBackoff)
+	}
+	if c.webhook != "" {
+		u, err := url.Parse(c.webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &churl.ConfigError{Err: errors.Errorf("Webhook '%s' is not an http or https URL", c.webhook)}
+		}
+	}
+
+	var err error
+	c.output, err = flags.ReadOutputFlag()
+	if err != nil {
+		return err
+	}
+
+	c.m, err = c.OpenManifest()
+	if err != nil {
+		return err
+	}
+
+	// Get timeout from cobra.Command
+	c.podTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, err.Error())
+	}
+
+	return nil
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) error {
+	name := c.m.CurrentName()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	w := &watcher{
+		museum: name,
+		connect: func(ctx context.Context) (*churl.Client, error) {
+			return c.Connect(ctx, c.cflags, c.podTimeout, name, c.m.Museums[name])
+		},
+		charts:     c.charts,
+		interval:   c.interval,
+		maxBackoff: c.maxBackoff,
+		state:      c.state,
+		hooks:      []hook{printer(os.Stdout, c.output)},
+		errs:       os.Stderr,
+	}
+	if c.exec != "" {
+		w.hooks = append(w.hooks, runner(c.exec, os.Stderr))
+	}
+	if c.webhook != "" {
+		w.hooks = append(w.hooks, poster(c.webhook))
+	}
+
+	return w.run(ctx)
+}
+
+func (c *command) Postexecute(cmd *cobra.Command, args []string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.m != nil {
+		c.m.Close()
+		c.m = nil
+	}
+
+	return nil
+}
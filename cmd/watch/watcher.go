@@ -0,0 +1,125 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/object88/churl"
+)
+
+// connectFunc creates a client for the watched museum
+type connectFunc func(ctx context.Context) (*churl.Client, error)
+
+// watcher polls a museum's index, and tells its hooks of each change
+type watcher struct {
+	museum  string
+	connect connectFunc
+	charts  map[string]bool
+
+	// interval is the time between polls; after a failure, the time before
+	// the next attempt doubles, up to maxBackoff
+	interval   time.Duration
+	maxBackoff time.Duration
+
+	// state is the file that the last snapshot is saved to, if set
+	state string
+
+	hooks []hook
+
+	// errs receives the failures that the watcher recovers from
+	errs io.Writer
+
+	// polled, if set, is called after each successful poll
+	polled func()
+}
+
+// run polls until `ctx` is done.  The first poll is compared against the
+// saved state, if there is any, and otherwise only records the index.  A
+// failed poll, i.e., from a dropped port forward, closes the client, and the
+// museum is connected to again.
+func (w *watcher) run(ctx context.Context) error {
+	var last snapshot
+	if w.state != "" {
+		var err error
+		if last, err = readSnapshot(w.state); err != nil {
+			return err
+		}
+	}
+
+	var client *churl.Client
+	defer func() {
+		client.Close()
+	}()
+
+	wait := w.interval
+	for {
+		next, err := w.poll(ctx, &client)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			fmt.Fprintf(w.errs, "%s; retrying in %s\n", err.Error(), wait)
+			client.Close()
+			client = nil
+		default:
+			if last != nil {
+				for _, e := range diff(last, next, w.museum, time.Now().UTC()) {
+					w.notify(ctx, e)
+				}
+			}
+			last = next
+			if w.state != "" {
+				if err := writeSnapshot(w.state, last); err != nil {
+					fmt.Fprintln(w.errs, err.Error())
+				}
+			}
+			if w.polled != nil {
+				w.polled()
+			}
+			wait = w.interval
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+
+		if err != nil {
+			if wait *= 2; wait > w.maxBackoff {
+				wait = w.maxBackoff
+			}
+		}
+	}
+}
+
+// poll reads the index, connecting to the museum first if there is no client
+func (w *watcher) poll(ctx context.Context, client **churl.Client) (snapshot, error) {
+	if *client == nil {
+		c, err := w.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		*client = c
+	}
+
+	// The client's cache revalidates the index with its ETag, so an unchanged
+	// index is not downloaded again.
+	index, err := (*client).Index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(index, w.charts), nil
+}
+
+// notify tells each hook of the event.  A hook that fails does not stop the
+// watch, or the other hooks.
+func (w *watcher) notify(ctx context.Context, e *event) {
+	for _, h := range w.hooks {
+		if err := h(ctx, e); err != nil {
+			fmt.Fprintln(w.errs, err.Error())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/object88/churl"
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/cmd/flags"
+)
+
+// startWatcher runs a watcher against `s` until the returned function is
+// called, which returns what the watcher wrote to its error writer
+func startWatcher(t *testing.T, s *churltest.Server, state string) (<-chan *event, <-chan struct{}, func() string) {
+	events := make(chan *event, 10)
+	polled := make(chan struct{}, 100)
+	var errs bytes.Buffer
+
+	w := &watcher{
+		museum: "dev",
+		connect: func(ctx context.Context) (*churl.Client, error) {
+			return churl.NewClient(ctx, "dev", s.Museum())
+		},
+		charts:     map[string]bool{},
+		interval:   10 * time.Millisecond,
+		maxBackoff: 40 * time.Millisecond,
+		state:      state,
+		hooks: []hook{func(ctx context.Context, e *event) error {
+			events <- e
+			return nil
+		}},
+		errs:   &errs,
+		polled: func() { polled <- struct{}{} },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.run(ctx)
+	}()
+
+	return events, polled, func() string {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Unexpected error:\n%s", err.Error())
+		}
+		return errs.String()
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan *event, expected string) {
+	select {
+	case e := <-events:
+		if actual := fmt.Sprintf("%s %s-%s", e.Type, e.Chart, e.Version); actual != expected {
+			t.Fatalf("Incorrect event; expected '%s', actual '%s'", expected, actual)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for '%s'", expected)
+	}
+}
+
+func waitForPoll(t *testing.T, polled <-chan struct{}) {
+	select {
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a poll")
+	}
+}
+
+func Test_Watch_Run(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	events, polled, stop := startWatcher(t, s, "")
+	waitForPoll(t, polled)
+
+	s.AddChart(&churltest.Chart{Name: "foo", Version: "2.0.0"})
+	expectEvent(t, events, "added foo-2.0.0")
+
+	// The watch survives the museum failing, and picks up where it left off.
+	s.Inject(churltest.Fault{Path: "/index.yaml", Status: http.StatusServiceUnavailable, Message: "down", Times: 3})
+	s.RemoveChart("foo", "1.0.0")
+	expectEvent(t, events, "removed foo-1.0.0")
+
+	errs := stop()
+	if strings.Count(errs, "retrying") != 3 {
+		t.Errorf("Incorrect failures:\n%s", errs)
+	}
+	if len(events) != 0 {
+		t.Errorf("Unexpected events: %d", len(events))
+	}
+}
+
+func Test_Watch_State(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+	state := path.Join(root, "state.json")
+
+	_, polled, stop := startWatcher(t, s, state)
+	waitForPoll(t, polled)
+	stop()
+
+	// A change while the watch is stopped is reported when it starts again.
+	s.AddChart(&churltest.Chart{Name: "bar", Version: "1.0.0"})
+	events, _, stop := startWatcher(t, s, state)
+	expectEvent(t, events, "added bar-1.0.0")
+	stop()
+}
+
+func Test_Watch_Hooks(t *testing.T) {
+	e := &event{Time: time.Now().UTC(), Type: Changed, Museum: "dev", Chart: "foo", Version: "1.0.0", Digest: "b", PreviousDigest: "a"}
+
+	var out bytes.Buffer
+	if err := printer(&out, flags.Text)(context.Background(), e); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if !strings.Contains(out.String(), "changed dev/foo 1.0.0 b (was a)") {
+		t.Errorf("Incorrect line: %s", out.String())
+	}
+
+	out.Reset()
+	if err := runner(`echo "$CHURL_EVENT $CHURL_CHART $CHURL_VERSION"; cat`, &out)(context.Background(), e); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if !strings.HasPrefix(out.String(), "changed foo 1.0.0\n{") {
+		t.Errorf("Incorrect command output: %s", out.String())
+	}
+	if err := runner("exit 1", &out)(context.Background(), e); err == nil {
+		t.Errorf("Expected error from failed command")
+	}
+
+	received := make(chan *event, 1)
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hook" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		x := &event{}
+		json.NewDecoder(r.Body).Decode(x)
+		received <- x
+	}))
+	defer hs.Close()
+
+	if err := poster(hs.URL+"/hook")(context.Background(), e); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if x := <-received; x.Type != Changed || x.Chart != "foo" || x.PreviousDigest != "a" {
+		t.Errorf("Incorrect event: %+v", x)
+	}
+	if err := poster(hs.URL+"/missing")(context.Background(), e); err == nil {
+		t.Errorf("Expected error from failed webhook")
+	}
+}
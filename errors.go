@@ -0,0 +1,243 @@
+package churl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrorKind classifies a failure, so that callers can distinguish, e.g., a
+// missing chart from an unreachable cluster
+type ErrorKind string
+
+const (
+	// KindUnknown is any failure that is not otherwise classified
+	KindUnknown ErrorKind = "unknown"
+
+	// KindNotFound is a chart, version, or resource that the museum does not
+	// have
+	KindNotFound ErrorKind = "not-found"
+
+	// KindUnauthorized is a request that the museum rejected for missing or
+	// incorrect credentials
+	KindUnauthorized ErrorKind = "unauthorized"
+
+	// KindForwardFailed is a port forward that could not be opened
+	KindForwardFailed ErrorKind = "forward-failed"
+
+	// KindPodNotReady is a museum pod that was not running before the timeout
+	KindPodNotReady ErrorKind = "pod-not-ready"
+
+	// KindInvalidConfig is a manifest or flag that could not be used
+	KindInvalidConfig ErrorKind = "invalid-config"
+
+	// KindServerError is any other unsuccessful response from the museum
+	KindServerError ErrorKind = "server-error"
+)
+
+// Error is implemented by each of the typed errors
+type Error interface {
+	error
+	Kind() ErrorKind
+}
+
+// HTTPError is implemented by the typed errors that come from a museum
+// response
+type HTTPError interface {
+	Error
+	StatusCode() int
+	RequestURL() string
+}
+
+// KindOf returns the kind of the first typed error in the chain of causes of
+// `err`, or KindUnknown
+func KindOf(err error) ErrorKind {
+	if e := AsError(err); e != nil {
+		return e.Kind()
+	}
+	return KindUnknown
+}
+
+// AsError returns the first typed error in the chain of causes of `err`, or
+// nil.  Both github.com/pkg/errors causes and wrapped errors are followed.
+func AsError(err error) Error {
+	for err != nil {
+		if e, ok := err.(Error); ok {
+			return e
+		}
+		switch t := err.(type) {
+		case interface{ Cause() error }:
+			err = t.Cause()
+		case interface{ Unwrap() error }:
+			err = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// NotFoundError is returned when the museum does not have the requested chart,
+// chart version, or resource
+type NotFoundError struct {
+	Museum  string
+	Chart   string
+	Version string
+	Status  int
+	URL     string
+}
+
+func (e *NotFoundError) Error() string {
+	switch {
+	case e.Chart == "":
+		return fmt.Sprintf("'%s' not found in %s", e.URL, describeMuseum(e.Museum))
+	case e.Version == "":
+		return fmt.Sprintf("Chart '%s' not found in %s", e.Chart, describeMuseum(e.Museum))
+	default:
+		return fmt.Sprintf("Chart '%s' version '%s' not found in %s", e.Chart, e.Version, describeMuseum(e.Museum))
+	}
+}
+
+// Kind satisfies Error
+func (e *NotFoundError) Kind() ErrorKind { return KindNotFound }
+
+// StatusCode satisfies HTTPError
+func (e *NotFoundError) StatusCode() int { return e.Status }
+
+// RequestURL satisfies HTTPError
+func (e *NotFoundError) RequestURL() string { return e.URL }
+
+// UnauthorizedError is returned when the museum rejects the credentials
+type UnauthorizedError struct {
+	Museum  string
+	Message string
+	Status  int
+	URL     string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("The credentials for '%s' were rejected by %s with status %d: %s", e.URL, describeMuseum(e.Museum), e.Status, e.Message)
+}
+
+// Kind satisfies Error
+func (e *UnauthorizedError) Kind() ErrorKind { return KindUnauthorized }
+
+// StatusCode satisfies HTTPError
+func (e *UnauthorizedError) StatusCode() int { return e.Status }
+
+// RequestURL satisfies HTTPError
+func (e *UnauthorizedError) RequestURL() string { return e.URL }
+
+// ServerError is returned for any other unsuccessful response from the museum
+type ServerError struct {
+	Museum  string
+	Message string
+	Status  int
+	URL     string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("Request for '%s' to %s failed with status %d: %s", e.URL, describeMuseum(e.Museum), e.Status, e.Message)
+}
+
+// Kind satisfies Error
+func (e *ServerError) Kind() ErrorKind { return KindServerError }
+
+// StatusCode satisfies HTTPError
+func (e *ServerError) StatusCode() int { return e.Status }
+
+// RequestURL satisfies HTTPError
+func (e *ServerError) RequestURL() string { return e.URL }
+
+// ForwardError is returned when the port forward to the museum cannot be
+// opened
+type ForwardError struct {
+	Museum string
+	Err    error
+}
+
+func (e *ForwardError) Error() string {
+	return fmt.Sprintf("Failed to forward port to museum '%s': %s", e.Museum, e.Err.Error())
+}
+
+// Kind satisfies Error
+func (e *ForwardError) Kind() ErrorKind { return KindForwardFailed }
+
+// Cause returns the underlying error, for github.com/pkg/errors.Cause
+func (e *ForwardError) Cause() error { return e.Err }
+
+// Unwrap returns the underlying error, for errors.Is and errors.As
+func (e *ForwardError) Unwrap() error { return e.Err }
+
+// PodNotReadyError is returned when no museum pod is running before the pod
+// timeout
+type PodNotReadyError struct {
+	Museum string
+	Err    error
+}
+
+func (e *PodNotReadyError) Error() string {
+	return fmt.Sprintf("No pod for museum '%s' is ready: %s", e.Museum, e.Err.Error())
+}
+
+// Kind satisfies Error
+func (e *PodNotReadyError) Kind() ErrorKind { return KindPodNotReady }
+
+// Cause returns the underlying error, for github.com/pkg/errors.Cause
+func (e *PodNotReadyError) Cause() error { return e.Err }
+
+// Unwrap returns the underlying error, for errors.Is and errors.As
+func (e *PodNotReadyError) Unwrap() error { return e.Err }
+
+// ConfigError is returned when a manifest or option cannot be used
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+// Kind satisfies Error
+func (e *ConfigError) Kind() ErrorKind { return KindInvalidConfig }
+
+// Cause returns the underlying error, for github.com/pkg/errors.Cause
+func (e *ConfigError) Cause() error { return e.Err }
+
+// Unwrap returns the underlying error, for errors.Is and errors.As
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// responseError creates the typed error for an unsuccessful response.  The
+// body is read as an ApiError if possible.  A 404 response is reported as
+// `notFound`, if provided, with the status and URL filled in.
+func responseError(museum, url string, code int, body io.Reader, notFound *NotFoundError) error {
+	aerr := &ApiError{}
+	json.NewDecoder(body).Decode(aerr)
+	if aerr.Err == "" {
+		aerr.Err = http.StatusText(code)
+	}
+
+	switch code {
+	case http.StatusNotFound:
+		if notFound == nil {
+			notFound = &NotFoundError{Museum: museum}
+		}
+		notFound.Status = code
+		notFound.URL = url
+		return notFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &UnauthorizedError{Museum: museum, Message: aerr.Err, Status: code, URL: url}
+	default:
+		return &ServerError{Museum: museum, Message: aerr.Err, Status: code, URL: url}
+	}
+}
+
+// describeMuseum names the museum in an error message; a MetadataReader does
+// not know the name of its museum
+func describeMuseum(museum string) string {
+	if museum == "" {
+		return "the museum"
+	}
+	return fmt.Sprintf("museum '%s'", museum)
+}
@@ -0,0 +1,132 @@
+package churl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/object88/churl/forwarder"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// forward opens a port forward to the museum, waits until it is ready, and
+// returns the local port
+func (c *Client) forward(ctx context.Context, cm *manifest.ChartMuseum) (string, error) {
+	b := c.o.backend
+	if b == nil {
+		kf := kubeFlagsFor(c.o.kubeFlags, cm)
+
+		config, err := kf.ToRESTConfig()
+		if err != nil {
+			return "", &ForwardError{Museum: c.name, Err: errors.Wrapf(err, "Failed to get REST config")}
+		}
+
+		b = forwarder.NewKubeBackend(cmdutil.NewFactory(kf), config)
+	}
+
+	ready := make(chan struct{})
+	options := []forwarder.Option{
+		forwarder.Out(c.o.out),
+		forwarder.Err(c.o.err),
+		forwarder.PodTimeout(c.o.podTimeout),
+		forwarder.Ready(ready),
+	}
+	fw, err := forwarder.OpenWithBackend(b, cm, options...)
+	if pnrerr, ok := err.(*forwarder.PodNotReadyError); ok {
+		return "", &PodNotReadyError{Museum: c.name, Err: pnrerr.Err}
+	} else if err != nil {
+		return "", &ForwardError{Museum: c.name, Err: err}
+	}
+
+	failed := make(chan error, 1)
+	go func() {
+		if err := fw.ForwardPorts(); err != nil {
+			failed <- err
+		}
+	}()
+
+	select {
+	case <-ready:
+	case err = <-failed:
+		return "", &ForwardError{Museum: c.name, Err: err}
+	case <-ctx.Done():
+		fw.Close()
+		return "", &ForwardError{Museum: c.name, Err: ctx.Err()}
+	}
+
+	port, err := fw.LocalPort()
+	if err != nil {
+		fw.Close()
+		return "", errors.Wrapf(err, "Internal error: failed to get local port for museum '%s'", c.name)
+	}
+
+	c.fw = fw
+	return port, nil
+}
+
+// kubeFlagsFor returns a copy of `base`, using the museum's kube context if
+// `base` does not set one.  Each museum gets its own copy so that several may
+// be connected at once.
+func kubeFlagsFor(base *genericclioptions.ConfigFlags, cm *manifest.ChartMuseum) *genericclioptions.ConfigFlags {
+	kf := genericclioptions.NewConfigFlags(false)
+	copyString := func(dst **string, src *string) {
+		if src != nil {
+			v := *src
+			*dst = &v
+		}
+	}
+	copyString(&kf.CacheDir, base.CacheDir)
+	copyString(&kf.KubeConfig, base.KubeConfig)
+	copyString(&kf.ClusterName, base.ClusterName)
+	copyString(&kf.AuthInfoName, base.AuthInfoName)
+	copyString(&kf.Context, base.Context)
+	copyString(&kf.APIServer, base.APIServer)
+	copyString(&kf.CertFile, base.CertFile)
+	copyString(&kf.KeyFile, base.KeyFile)
+	copyString(&kf.CAFile, base.CAFile)
+	copyString(&kf.BearerToken, base.BearerToken)
+	copyString(&kf.Impersonate, base.Impersonate)
+	copyString(&kf.Username, base.Username)
+	copyString(&kf.Password, base.Password)
+	copyString(&kf.Timeout, base.Timeout)
+	if base.Insecure != nil {
+		v := *base.Insecure
+		kf.Insecure = &v
+	}
+	if base.ImpersonateGroup != nil {
+		v := append([]string{}, *base.ImpersonateGroup...)
+		kf.ImpersonateGroup = &v
+	}
+	kf.Namespace = nil
+
+	if kf.Context == nil || *kf.Context == "" {
+		ctx := cm.KubeContext
+		kf.Context = &ctx
+	}
+
+	return kf
+}
+
+// cacheSource identifies the server that the museum names, for the cache: its
+// URL, without credentials, or the API server, namespace, service, and port
+// that its port forward reaches.  The kube context is resolved from the
+// kubeconfig, so that a museum without one follows the current context.
+func cacheSource(base *genericclioptions.ConfigFlags, cm *manifest.ChartMuseum) string {
+	if cm.URL != "" {
+		u, err := url.Parse(cm.URL)
+		if err != nil {
+			return cm.URL
+		}
+		u.User = nil
+		return u.String()
+	}
+
+	server := cm.KubeContext
+	if config, err := kubeFlagsFor(base, cm).ToRESTConfig(); err == nil {
+		server = config.Host
+	}
+	return fmt.Sprintf("%s/%s/%s:%s", server, cm.Namespace, cm.ServiceName, cm.Port)
+}
@@ -0,0 +1,94 @@
+package forwarder
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/object88/churl/manifest"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport/spdy"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// Backend resolves a museum to a pod, and creates the tunnel to it.  The
+// backend returned by NewKubeBackend uses a Kubernetes API server.
+type Backend interface {
+	// Object returns the service or pod that the museum names
+	Object(cm *manifest.ChartMuseum) (runtime.Object, error)
+
+	// Pod returns the pod that backs `obj`, waiting up to `timeout` for one
+	Pod(obj runtime.Object, timeout time.Duration) (*v1.Pod, error)
+
+	// Dialer returns the dialer used to open port forwarding streams to `pod`
+	Dialer(pod *v1.Pod) (httpstream.Dialer, error)
+}
+
+// DialerFunc returns the dialer used to open port forwarding streams to `pod`
+type DialerFunc func(pod *v1.Pod) (httpstream.Dialer, error)
+
+// kubeBackend is the Backend for a Kubernetes API server
+type kubeBackend struct {
+	factory cmdutil.Factory
+	config  *rest.Config
+
+	// dial replaces the SPDY dialer, if set
+	dial DialerFunc
+}
+
+// NewKubeBackend returns a Backend that uses the API server configured by
+// `factory` and `config`
+func NewKubeBackend(factory cmdutil.Factory, config *rest.Config) Backend {
+	return &kubeBackend{
+		factory: factory,
+		config:  config,
+	}
+}
+
+// NewKubeBackendWithDialer returns a Backend that finds the museum's pod
+// through the API server configured by `factory`, and opens the tunnel to it
+// with `dial` instead of through the API server.  Tests use it with the fake
+// cluster in forwardertest.
+func NewKubeBackendWithDialer(factory cmdutil.Factory, dial DialerFunc) Backend {
+	return &kubeBackend{
+		factory: factory,
+		dial:    dial,
+	}
+}
+
+func (b *kubeBackend) Object(cm *manifest.ChartMuseum) (runtime.Object, error) {
+	builder := b.factory.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		ContinueOnError().
+		NamespaceParam(cm.Namespace).DefaultNamespace()
+	builder.ResourceNames("pods", cm.ServiceName)
+
+	return builder.Do().Object()
+}
+
+func (b *kubeBackend) Pod(obj runtime.Object, timeout time.Duration) (*v1.Pod, error) {
+	return polymorphichelpers.AttachablePodForObjectFn(b.factory, obj, timeout)
+}
+
+func (b *kubeBackend) Dialer(pod *v1.Pod) (httpstream.Dialer, error) {
+	if b.dial != nil {
+		return b.dial(pod)
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(b.config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := b.factory.RESTClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req := client.Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward")
+	return spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL()), nil
+}
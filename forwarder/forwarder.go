@@ -2,7 +2,6 @@ package forwarder
 
 import (
 	"fmt"
-	"net/http"
 	"strconv"
 
 	"github.com/object88/churl/manifest"
@@ -10,10 +9,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
-	"k8s.io/client-go/transport/spdy"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
-	"k8s.io/kubectl/pkg/polymorphichelpers"
-	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util"
 )
 
@@ -21,11 +17,32 @@ type Forwarder struct {
 	fw *portforward.PortForwarder
 
 	stop chan struct{}
+}
+
+// PodNotReadyError is returned by Open when no pod for the museum is running
+// before the pod timeout
+type PodNotReadyError struct {
+	Err error
+}
 
-	SourcePort string
+func (e *PodNotReadyError) Error() string {
+	return e.Err.Error()
 }
 
-func Open(factory cmdutil.Factory, config *rest.Config, m *manifest.Manifest, options ...Option) (*Forwarder, error) {
+// Cause returns the underlying error, for github.com/pkg/errors.Cause
+func (e *PodNotReadyError) Cause() error {
+	return e.Err
+}
+
+// Open creates a port forward to the museum through the Kubernetes API
+// server configured by `factory` and `config`
+func Open(factory cmdutil.Factory, config *rest.Config, cm *manifest.ChartMuseum, options ...Option) (*Forwarder, error) {
+	return OpenWithBackend(NewKubeBackend(factory, config), cm, options...)
+}
+
+// OpenWithBackend creates a port forward to the museum, using `b` to find the
+// museum's pod and to open the tunnel to it
+func OpenWithBackend(b Backend, cm *manifest.ChartMuseum, options ...Option) (*Forwarder, error) {
 	o := NewOptions()
 	for _, opt := range options {
 		if err := opt(o); err != nil {
@@ -33,25 +50,24 @@ func Open(factory cmdutil.Factory, config *rest.Config, m *manifest.Manifest, op
 		}
 	}
 
-	cm := m.Current()
-
-	builder := factory.NewBuilder().
-		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
-		ContinueOnError().
-		NamespaceParam(cm.Namespace).DefaultNamespace()
-	builder.ResourceNames("pods", cm.ServiceName)
-
-	obj, err := builder.Do().Object()
+	obj, err := b.Object(cm)
 	if err != nil {
 		return nil, err
 	}
 
-	forwardablePod, err := polymorphichelpers.AttachablePodForObjectFn(factory, obj, o.podTimeout)
+	forwardablePod, err := b.Pod(obj, o.podTimeout)
 	if err != nil {
-		return nil, err
+		return nil, &PodNotReadyError{Err: err}
+	}
+	if forwardablePod.Status.Phase != v1.PodRunning {
+		// As `kubectl port-forward` does, refuse a pod that cannot accept the
+		// tunnel, rather than failing on the first request.
+		return nil, &PodNotReadyError{Err: errors.Errorf("Pod '%s' is not running; its phase is '%s'", forwardablePod.Name, forwardablePod.Status.Phase)}
 	}
 
-	sourcePort := "9999"
+	// Listen on a random local port, so that several forwarders may be open at
+	// once; see LocalPort.
+	sourcePort := "0"
 	destinationPort := cm.Port
 
 	// handle service port mapping to target port if needed
@@ -65,31 +81,21 @@ func Open(factory cmdutil.Factory, config *rest.Config, m *manifest.Manifest, op
 		return nil, err
 	}
 
-	transport, upgrader, err := spdy.RoundTripperFor(config)
+	dialer, err := b.Dialer(forwardablePod)
 	if err != nil {
 		return nil, err
 	}
 
 	portmap := []string{fmt.Sprintf("%s:%s", sourcePort, destinationPort)}
-
-	client, err := factory.RESTClient()
-	if err != nil {
-		return nil, err
-	}
-
-	req := client.Post().Resource("pods").Namespace(forwardablePod.Namespace).Name(forwardablePod.Name).SubResource("portforward")
 	stop := make(chan struct{})
-
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
 	fw, err := portforward.New(dialer, portmap, stop, o.ready, o.out, o.err)
 	if err != nil {
 		return nil, err
 	}
 
 	f := &Forwarder{
-		fw:         fw,
-		SourcePort: sourcePort,
-		stop:       stop,
+		fw:   fw,
+		stop: stop,
 	}
 
 	return f, nil
@@ -99,9 +105,22 @@ func (f *Forwarder) ForwardPorts() error {
 	return f.fw.ForwardPorts()
 }
 
+// LocalPort returns the local port that is forwarded to the chart museum.  It
+// fails until the forwarder is ready.
+func (f *Forwarder) LocalPort() (string, error) {
+	ports, err := f.fw.GetPorts()
+	if err != nil {
+		return "", err
+	}
+	if len(ports) == 0 {
+		return "", errors.Errorf("Internal error: no ports are forwarded")
+	}
+	return strconv.Itoa(int(ports[0].Local)), nil
+}
+
+// Close stops forwarding; ForwardPorts closes the listeners as it returns.
 func (f *Forwarder) Close() error {
 	close(f.stop)
-	f.fw.Close()
 
 	return nil
 }
@@ -0,0 +1,258 @@
+package forwarder
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/forwarder/forwardertest"
+	"github.com/object88/churl/manifest"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var museumLabels = map[string]string{"app": "chartmuseum"}
+
+func Test_Forwarder_TranslateServicePortToTargetPort(t *testing.T) {
+	pod := forwardertest.RunningPod("default", "cm-0", museumLabels, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+
+	tcs := []struct {
+		name       string
+		svcPort    v1.ServicePort
+		remotePort string
+		expected   string
+		fails      bool
+	}{
+		{
+			name:       "same port",
+			svcPort:    v1.ServicePort{Port: 8080, TargetPort: intstr.FromInt(8080)},
+			remotePort: "8080",
+			expected:   "8080",
+		},
+		{
+			name:       "numeric target port",
+			svcPort:    v1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8080)},
+			remotePort: "80",
+			expected:   "8080",
+		},
+		{
+			name:       "named target port",
+			svcPort:    v1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")},
+			remotePort: "80",
+			expected:   "8080",
+		},
+		{
+			name:       "named service port",
+			svcPort:    v1.ServicePort{Name: "web", Port: 80, TargetPort: intstr.FromString("http")},
+			remotePort: "web",
+			expected:   "8080",
+		},
+		{
+			name:       "undeclared service port",
+			svcPort:    v1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8080)},
+			remotePort: "443",
+			fails:      true,
+		},
+		{
+			name:       "unknown service port name",
+			svcPort:    v1.ServicePort{Name: "web", Port: 80, TargetPort: intstr.FromInt(8080)},
+			remotePort: "https",
+			fails:      true,
+		},
+		{
+			name:       "unknown target port name",
+			svcPort:    v1.ServicePort{Port: 80, TargetPort: intstr.FromString("grpc")},
+			remotePort: "80",
+			fails:      true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := forwardertest.Service("default", "cm", museumLabels, tc.svcPort)
+			local, remote, err := translateServicePortToTargetPort("0", tc.remotePort, *svc, *pod)
+			if tc.fails {
+				if err == nil {
+					t.Fatalf("Expected error, got remote port '%s'", remote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if local != "0" || remote != tc.expected {
+				t.Errorf("Incorrect ports; expected '0:%s', actual '%s:%s'", tc.expected, local, remote)
+			}
+		})
+	}
+}
+
+func Test_Forwarder_ConvertPodNamedPortToNumber(t *testing.T) {
+	pod := forwardertest.RunningPod("default", "cm-0", museumLabels, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+
+	tcs := []struct {
+		name       string
+		remotePort string
+		expected   string
+		fails      bool
+	}{
+		{name: "number", remotePort: "9090", expected: "9090"},
+		{name: "name", remotePort: "http", expected: "8080"},
+		{name: "unknown name", remotePort: "grpc", fails: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, remote, err := convertPodNamedPortToNumber("0", tc.remotePort, *pod)
+			if tc.fails {
+				if err == nil {
+					t.Fatalf("Expected error, got remote port '%s'", remote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if remote != tc.expected {
+				t.Errorf("Incorrect remote port; expected '%s', actual '%s'", tc.expected, remote)
+			}
+		})
+	}
+}
+
+func Test_Forwarder_Open(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "foo", Version: "1.0.0"})
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	containerPort, _ := strconv.Atoi(port)
+
+	tcs := []struct {
+		name        string
+		serviceName string
+		port        string
+	}{
+		{name: "service", serviceName: "svc/cm", port: "80"},
+		{name: "service by name", serviceName: "service/cm", port: "web"},
+		{name: "pod", serviceName: "cm-1", port: port},
+		{name: "pod by name", serviceName: "pod/cm-1", port: "http"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := forwardertest.NewCluster(
+				forwardertest.Service("charts", "cm", museumLabels, v1.ServicePort{Name: "web", Port: 80, TargetPort: intstr.FromString("http")}),
+				forwardertest.RunningPod("charts", "cm-1", museumLabels, v1.ContainerPort{Name: "http", ContainerPort: int32(containerPort)}),
+			)
+			defer c.Close()
+			c.Targets[int32(containerPort)] = s.Listener.Addr().String()
+
+			cm := &manifest.ChartMuseum{Namespace: "charts", ServiceName: tc.serviceName, Port: tc.port}
+			fw := open(t, NewKubeBackendWithDialer(c.Factory(), c.Dialer), cm, time.Second)
+			defer fw.Close()
+
+			localPort, err := fw.LocalPort()
+			if err != nil {
+				t.Fatalf("Failed to get local port:\n%s", err.Error())
+			}
+
+			resp, err := http.Get("http://localhost:" + localPort + "/health")
+			if err != nil {
+				t.Fatalf("Failed to reach museum:\n%s", err.Error())
+			}
+			defer resp.Body.Close()
+			ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Incorrect status %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func Test_Forwarder_Open_Errors(t *testing.T) {
+	pending := forwardertest.RunningPod("charts", "cm-0", museumLabels, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+	pending.Status.Phase = v1.PodPending
+
+	tcs := []struct {
+		name        string
+		serviceName string
+		port        string
+		notReady    bool
+	}{
+		{name: "missing service", serviceName: "svc/other", port: "80"},
+		{name: "missing pod", serviceName: "cm-9", port: "8080"},
+		{name: "unsupported type", serviceName: "deploy/cm", port: "80"},
+		{name: "pending pod", serviceName: "cm-0", port: "8080", notReady: true},
+		{name: "service's pod pending", serviceName: "svc/cm", port: "80", notReady: true},
+		{name: "no pod for service", serviceName: "svc/empty", port: "80", notReady: true},
+		{name: "unknown port", serviceName: "other-0", port: "grpc"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := forwardertest.NewCluster(
+				forwardertest.Service("charts", "cm", museumLabels, v1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}),
+				forwardertest.Service("charts", "empty", map[string]string{"app": "none"}, v1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}),
+				pending,
+				forwardertest.RunningPod("charts", "other-0", map[string]string{"app": "other"}, v1.ContainerPort{Name: "http", ContainerPort: 8080}),
+			)
+			defer c.Close()
+
+			cm := &manifest.ChartMuseum{Namespace: "charts", ServiceName: tc.serviceName, Port: tc.port}
+			start := time.Now()
+			_, err := OpenWithBackend(NewKubeBackendWithDialer(c.Factory(), c.Dialer), cm, PodTimeout(50*time.Millisecond))
+			if err == nil {
+				t.Fatalf("Expected error")
+			}
+			_, ok := err.(*PodNotReadyError)
+			if ok != tc.notReady {
+				t.Errorf("Incorrect error; expected PodNotReadyError %t, got %#v", tc.notReady, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Took %s to fail", elapsed)
+			}
+		})
+	}
+}
+
+func Test_Forwarder_Open_PodStarts(t *testing.T) {
+	c := forwardertest.NewCluster(
+		forwardertest.Service("charts", "cm", museumLabels, v1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}),
+	)
+	defer c.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Clientset.CoreV1().Pods("charts").Create(forwardertest.RunningPod("charts", "cm-0", museumLabels, v1.ContainerPort{Name: "http", ContainerPort: 8080}))
+	}()
+
+	cm := &manifest.ChartMuseum{Namespace: "charts", ServiceName: "svc/cm", Port: "80"}
+	fw, err := OpenWithBackend(NewKubeBackendWithDialer(c.Factory(), c.Dialer), cm, PodTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	fw.Close()
+}
+
+// open opens a forwarder, and waits until it is forwarding ports
+func open(t *testing.T, b Backend, cm *manifest.ChartMuseum, timeout time.Duration) *Forwarder {
+	ready := make(chan struct{})
+	fw, err := OpenWithBackend(b, cm, PodTimeout(timeout), Ready(ready))
+	if err != nil {
+		t.Fatalf("Failed to open forwarder:\n%s", err.Error())
+	}
+
+	go fw.ForwardPorts()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		fw.Close()
+		t.Fatalf("Forwarder was not ready")
+	}
+	return fw
+}
@@ -0,0 +1,228 @@
+// Package forwardertest provides a fake Kubernetes cluster for testing the
+// forwarder.  The cluster's API server answers from client-go's fake
+// clientset, so that the forwarder's Kubernetes backend finds the museum's
+// pod as it would in a real cluster, and forwarded connections are dialed to
+// local addresses.
+package forwardertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// Cluster is a fake Kubernetes API server that serves the pods and services
+// of a fake clientset
+type Cluster struct {
+	Clientset *fake.Clientset
+
+	// Targets maps a container port to the local address, i.e.,
+	// `127.0.0.1:8080`, that receives the connections forwarded to it
+	Targets map[int32]string
+
+	server *httptest.Server
+}
+
+// NewCluster starts a cluster whose clientset holds `objects`.  The cluster
+// must be closed.
+func NewCluster(objects ...runtime.Object) *Cluster {
+	c := &Cluster{
+		Clientset: fake.NewSimpleClientset(objects...),
+		Targets:   map[int32]string{},
+	}
+	c.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: v1.SchemeGroupVersion.String(),
+			APIResources: []metav1.APIResource{
+				{Name: "pods", ShortNames: []string{"po"}, Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get", "list", "watch"}},
+				{Name: "services", ShortNames: []string{"svc"}, Namespaced: true, Kind: "Service", Verbs: metav1.Verbs{"get", "list", "watch"}},
+			},
+		},
+	}
+	c.server = httptest.NewServer(http.HandlerFunc(c.serve))
+	return c
+}
+
+// Close stops the API server
+func (c *Cluster) Close() {
+	c.server.Close()
+}
+
+// Factory returns a factory whose clients reach the cluster's API server
+func (c *Cluster) Factory() cmdutil.Factory {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["fake"] = &clientcmdapi.Cluster{Server: c.server.URL}
+	config.AuthInfos["fake"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["fake"] = &clientcmdapi.Context{Cluster: "fake", AuthInfo: "fake", Namespace: metav1.NamespaceDefault}
+	config.CurrentContext = "fake"
+
+	flags := genericclioptions.NewTestConfigFlags().
+		WithClientConfig(clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{})).
+		WithDiscoveryClient(&discovery{c.Clientset.Discovery().(*fakediscovery.FakeDiscovery)})
+	return cmdutil.NewFactory(flags)
+}
+
+// Dialer returns a loopback dialer, which connects each forwarded port to its
+// target
+func (c *Cluster) Dialer(pod *v1.Pod) (httpstream.Dialer, error) {
+	return &dialer{targets: c.Targets}, nil
+}
+
+// serve answers the requests for a single pod or service, and the requests
+// to list or watch the pods, i.e.,
+// `/api/v1/namespaces/NAMESPACE/pods[/NAME]`
+func (c *Cluster) serve(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method != http.MethodGet || len(segments) < 5 || len(segments) > 6 || segments[0] != "api" || segments[1] != "v1" || segments[2] != "namespaces" {
+		writeError(w, apierrors.NewNotFound(v1.Resource(r.URL.Path), ""))
+		return
+	}
+	namespace, resource := segments[3], segments[4]
+
+	var obj runtime.Object
+	var err error
+	switch {
+	case len(segments) == 6 && resource == "pods":
+		obj, err = c.Clientset.CoreV1().Pods(namespace).Get(segments[5], metav1.GetOptions{})
+	case len(segments) == 6 && resource == "services":
+		obj, err = c.Clientset.CoreV1().Services(namespace).Get(segments[5], metav1.GetOptions{})
+	case len(segments) == 5 && resource == "pods" && r.URL.Query().Get("watch") == "true":
+		c.watchPods(w, r, namespace)
+		return
+	case len(segments) == 5 && resource == "pods":
+		obj, err = c.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: r.URL.Query().Get("labelSelector")})
+	default:
+		err = apierrors.NewNotFound(v1.Resource(resource), "")
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(encode(obj))
+}
+
+// watchPods streams the events for the pods that match the request's label
+// selector, until the client goes away
+func (c *Cluster) watchPods(w http.ResponseWriter, r *http.Request, namespace string) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	watcher, err := c.Clientset.CoreV1().Pods(namespace).Watch(metav1.ListOptions{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer watcher.Stop()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			if pod, ok := event.Object.(*v1.Pod); !ok || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			enc.Encode(&metav1.WatchEvent{
+				Type:   string(event.Type),
+				Object: runtime.RawExtension{Raw: encode(event.Object)},
+			})
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func encode(obj runtime.Object) []byte {
+	b, err := runtime.Encode(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), obj)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	s := status.Status()
+	s.Kind, s.APIVersion = "Status", "v1"
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(s.Code))
+	json.NewEncoder(w).Encode(&s)
+}
+
+// discovery is the fake clientset's discovery client, which never needs to be
+// refreshed
+type discovery struct {
+	*fakediscovery.FakeDiscovery
+}
+
+func (d *discovery) Fresh() bool { return true }
+
+func (d *discovery) Invalidate() {}
+
+// RunningPod returns a running pod with the labels and container ports
+func RunningPod(namespace, name string, labels map[string]string, ports ...v1.ContainerPort) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "chartmuseum", Ports: ports},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+		},
+	}
+}
+
+// Service returns a service that selects pods with the labels
+func Service(namespace, name string, selector map[string]string, ports ...v1.ServicePort) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: selector,
+			Ports:    ports,
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package forwardertest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+)
+
+// dialer satisfies httpstream.Dialer.  Instead of upgrading a connection to
+// an API server, it returns a connection whose data streams are dialed to the
+// target of the requested port.
+type dialer struct {
+	targets map[int32]string
+}
+
+func (d *dialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	c := &connection{
+		targets: d.targets,
+		closed:  make(chan bool),
+	}
+	return c, portforward.PortForwardProtocolV1Name, nil
+}
+
+type connection struct {
+	targets map[int32]string
+
+	mu      sync.Mutex
+	conns   []net.Conn
+	closed  chan bool
+	once    sync.Once
+	lastID  uint32
+	isClose bool
+}
+
+func (c *connection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	id := atomic.AddUint32(&c.lastID, 1)
+
+	if headers.Get(v1.StreamType) == v1.StreamTypeError {
+		// Nothing is written to the error stream, so it ends immediately.
+		return &errorStream{headers: headers, id: id}, nil
+	}
+
+	port, err := strconv.Atoi(headers.Get(v1.PortHeader))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid port header")
+	}
+	target, ok := c.targets[int32(port)]
+	if !ok {
+		return nil, errors.Errorf("No target for port %d", port)
+	}
+
+	conn, err := net.Dial("tcp", target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to dial target '%s' for port %d", target, port)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClose {
+		conn.Close()
+		return nil, errors.Errorf("Connection is closed")
+	}
+	c.conns = append(c.conns, conn)
+
+	return &dataStream{Conn: conn, headers: headers, id: id}, nil
+}
+
+func (c *connection) Close() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.isClose = true
+		for _, conn := range c.conns {
+			conn.Close()
+		}
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *connection) CloseChan() <-chan bool {
+	return c.closed
+}
+
+func (c *connection) SetIdleTimeout(timeout time.Duration) {}
+
+// dataStream forwards to a connection to the target
+type dataStream struct {
+	net.Conn
+	headers http.Header
+	id      uint32
+}
+
+// Close stops writing to the target, which, like an SPDY stream, leaves the
+// other direction open
+func (s *dataStream) Close() error {
+	if tcp, ok := s.Conn.(*net.TCPConn); ok {
+		return tcp.CloseWrite()
+	}
+	return s.Conn.Close()
+}
+
+func (s *dataStream) Reset() error {
+	return s.Conn.Close()
+}
+
+func (s *dataStream) Headers() http.Header {
+	return s.headers
+}
+
+func (s *dataStream) Identifier() uint32 {
+	return s.id
+}
+
+type errorStream struct {
+	headers http.Header
+	id      uint32
+}
+
+func (s *errorStream) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (s *errorStream) Write(p []byte) (int, error) {
+	return 0, errors.Errorf("The error stream is read-only")
+}
+
+func (s *errorStream) Close() error {
+	return nil
+}
+
+func (s *errorStream) Reset() error {
+	return nil
+}
+
+func (s *errorStream) Headers() http.Header {
+	return s.headers
+}
+
+func (s *errorStream) Identifier() uint32 {
+	return s.id
+}
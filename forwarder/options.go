@@ -18,7 +18,8 @@ type Options struct {
 
 func NewOptions() *Options {
 	return &Options{
-		out: &nilwriter{},
+		out:   &nilwriter{},
+		ready: make(chan struct{}),
 	}
 }
 
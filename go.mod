@@ -3,17 +3,26 @@ module github.com/object88/churl
 go 1.12
 
 require (
-	github.com/Masterminds/semver v1.5.0 // indirect
+	github.com/Masterminds/goutils v1.1.1 // indirect
+	github.com/Masterminds/semver v1.5.0
+	github.com/Masterminds/sprig v2.22.0+incompatible // indirect
 	github.com/cyphar/filepath-securejoin v0.2.2 // indirect
+	github.com/ghodss/yaml v1.0.0
 	github.com/gobwas/glob v0.2.3 // indirect
 	github.com/golang/mock v1.3.1
+	github.com/golang/protobuf v1.3.2
 	github.com/google/uuid v1.1.1
+	github.com/huandu/xstrings v1.6.2 // indirect
 	github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af
+	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/pkg/errors v0.8.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.3.2
+	gopkg.in/yaml.v2 v2.2.4
 	k8s.io/api v0.0.0-20191105025951-7aa4c14eac98
+	k8s.io/apimachinery v0.0.0-20191104232853-7449f4ff0238
 	k8s.io/cli-runtime v0.0.0-20191102031428-d1199d98239f
 	k8s.io/client-go v0.0.0-20191105030321-52092c3c67fa
 	k8s.io/helm v2.16.1+incompatible
@@ -1,10 +1,12 @@
 package request
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -14,6 +16,9 @@ type Request struct {
 
 	baseURL url.URL
 	c       *http.Client
+
+	username string
+	password string
 }
 
 func NewRequest(baseURL string) (*Request, error) {
@@ -29,28 +34,78 @@ func NewRequest(baseURL string) (*Request, error) {
 	return r, nil
 }
 
-func (r *Request) ProcessGet(query string) (io.ReadCloser, bool, error) {
-	body, code, err := r.process(http.MethodGet, query, nil)
-	if err != nil {
-		return nil, false, err
-	}
+// SetBasicAuth sets the credentials sent with each request.  If both are
+// empty, no Authorization header is sent.
+func (r *Request) SetBasicAuth(username, password string) {
+	r.username = username
+	r.password = password
+}
 
-	switch code {
-	case http.StatusOK:
-		return body, true, nil
-	default:
-		return body, false, nil
-	}
+// Get performs a GET request for `query`, relative to the base URL, and
+// returns the response body and status code
+func (r *Request) Get(ctx context.Context, query string) (io.ReadCloser, int, error) {
+	return r.process(ctx, http.MethodGet, query, "", nil)
+}
+
+// Post performs a POST request for `query`, relative to the base URL, with
+// `body` of `contentType`, and returns the response body and status code
+func (r *Request) Post(ctx context.Context, query, contentType string, body io.Reader) (io.ReadCloser, int, error) {
+	return r.process(ctx, http.MethodPost, query, contentType, body)
+}
+
+// Do performs a request with `method` for `query`, relative to the base URL,
+// and returns the response, whatever its status; the caller must close its
+// body
+func (r *Request) Do(ctx context.Context, method, query string) (*http.Response, error) {
+	return r.do(ctx, method, query, "", nil)
 }
 
-func (r *Request) process(verb, query string, body io.Reader) (io.ReadCloser, int, error) {
+// URL returns the complete URL for `query`, which may include a query string
+func (r *Request) URL(query string) string {
 	u := r.baseURL
+	if i := strings.IndexByte(query, '?'); i >= 0 {
+		u.RawQuery = query[i+1:]
+		query = query[:i]
+	}
 	u.Path = path.Join(u.Path, query)
-	completeURL := u.String()
+	return u.String()
+}
+
+// Relative returns `raw`, a URL from the museum's index, relative to the base
+// URL.  An absolute URL is reduced to its path, less the base URL's path, so
+// that it is requested through this connection, even if the museum is under a
+// context path.
+func (r *Request) Relative(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		return raw
+	}
+	if base := strings.TrimSuffix(r.baseURL.Path, "/"); base != "" && strings.HasPrefix(u.Path, base+"/") {
+		return strings.TrimPrefix(u.Path, base)
+	}
+	return u.Path
+}
+
+func (r *Request) process(ctx context.Context, verb, query, contentType string, body io.Reader) (io.ReadCloser, int, error) {
+	resp, err := r.do(ctx, verb, query, contentType, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Body, resp.StatusCode, nil
+}
+
+func (r *Request) do(ctx context.Context, verb, query, contentType string, body io.Reader) (*http.Response, error) {
+	completeURL := r.URL(query)
 
-	req, err := http.NewRequest(verb, completeURL, body)
+	req, err := http.NewRequestWithContext(ctx, verb, completeURL, body)
 	if err != nil {
-		return nil, 0, errors.Wrapf(err, "Failed to create request for '%s %s'", verb, completeURL)
+		return nil, errors.Wrapf(err, "Failed to create request for '%s %s'", verb, completeURL)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.username != "" || r.password != "" {
+		req.SetBasicAuth(r.username, r.password)
 	}
 
 	c := http.Client{
@@ -62,8 +117,8 @@ func (r *Request) process(verb, query string, body io.Reader) (io.ReadCloser, in
 		if resp != nil && resp.Body != nil {
 			resp.Body.Close()
 		}
-		return nil, 0, errors.Wrapf(err, "Failed to perform request")
+		return nil, errors.Wrapf(err, "Failed to perform request")
 	}
 
-	return resp.Body, resp.StatusCode, nil
+	return resp, nil
 }
@@ -0,0 +1,163 @@
+package resolve
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/object88/churl"
+	"github.com/object88/churl/manifest"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/repo"
+)
+
+const localPrefix = "file://"
+
+// ConnectFunc creates a client for the chart museum `cm`
+type ConnectFunc func(ctx context.Context, name string, cm *manifest.ChartMuseum) (*churl.Client, error)
+
+// Resolver resolves chart dependencies against the helm repositories that
+// they name.  A repository that is a museum in the manifest is reached through
+// that museum; any other repository is reached directly.  Each repository is
+// connected to, and its index read, at most once; the clients stay open until
+// Close is called.
+type Resolver struct {
+	m       *manifest.Manifest
+	connect ConnectFunc
+
+	clients  map[string]*churl.Client
+	indexes  map[string]*repo.IndexFile
+	failures map[string]error
+}
+
+// New creates a Resolver for the museums in `m`
+func New(m *manifest.Manifest, connect ConnectFunc) *Resolver {
+	return &Resolver{
+		m:        m,
+		connect:  connect,
+		clients:  map[string]*churl.Client{},
+		indexes:  map[string]*repo.IndexFile{},
+		failures: map[string]error{},
+	}
+}
+
+// IsLocal reports whether a dependency on `repository` is read from the file
+// system, or vendored under the chart's charts/ directory
+func IsLocal(repository string) bool {
+	return repository == "" || strings.HasPrefix(repository, localPrefix)
+}
+
+// Museum returns the name of the museum that serves `repository`, and the
+// museum.  A repository that is not in the manifest is named by its URL.
+func (r *Resolver) Museum(repository string) (string, *manifest.ChartMuseum, error) {
+	if name, ok := r.m.MuseumForRepository(repository); ok {
+		return name, r.m.Museums[name], nil
+	}
+	if strings.HasPrefix(repository, "@") || strings.HasPrefix(repository, "alias:") {
+		return "", nil, &churl.ConfigError{Err: errors.Errorf("Repository '%s' is not a museum in the manifest; known museums are '%s'", repository, strings.Join(r.m.Names(), "', '"))}
+	}
+	return repository, &manifest.ChartMuseum{URL: repository}, nil
+}
+
+// Client returns the name of the museum that serves `repository`, and a client
+// for it
+func (r *Resolver) Client(ctx context.Context, repository string) (string, *churl.Client, error) {
+	name, cm, err := r.Museum(repository)
+	if err != nil {
+		return "", nil, err
+	}
+	if c, ok := r.clients[name]; ok {
+		return name, c, nil
+	}
+	if err, ok := r.failures[name]; ok {
+		return name, nil, err
+	}
+
+	c, err := r.connect(ctx, name, cm)
+	if err != nil {
+		r.failures[name] = err
+		return name, nil, err
+	}
+	r.clients[name] = c
+	return name, c, nil
+}
+
+// Index returns the name of the museum that serves `repository`, and its
+// index
+func (r *Resolver) Index(ctx context.Context, repository string) (string, *repo.IndexFile, error) {
+	name, c, err := r.Client(ctx, repository)
+	if err != nil {
+		return name, nil, err
+	}
+	if index, ok := r.indexes[name]; ok {
+		return name, index, nil
+	}
+
+	index, err := c.Index(ctx)
+	if err != nil {
+		err = errors.Wrapf(err, "Could not get the index of museum '%s'", name)
+		r.failures[name] = err
+		return name, nil, err
+	}
+	r.indexes[name] = index
+	return name, index, nil
+}
+
+// Close closes every client
+func (r *Resolver) Close() error {
+	var err error
+	for name, c := range r.clients {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(r.clients, name)
+	}
+	return err
+}
+
+// Newest returns the newest version of the chart in the index that satisfies
+// `constraint`, like `helm dependency update`, and the newest version that is
+// not a prerelease.  Either is empty if there is no such version.  An empty
+// constraint allows any version.
+func Newest(index *repo.IndexFile, chart, constraint string) (string, string, error) {
+	if constraint == "" {
+		constraint = "*"
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Chart '%s' has an invalid version constraint '%s'", chart, constraint)
+	}
+
+	var resolved, latest string
+	// The index entries are sorted, newest first.
+	for _, cv := range index.Entries[chart] {
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		if latest == "" && v.Prerelease() == "" {
+			latest = cv.Version
+		}
+		if resolved == "" && c.Check(v) {
+			resolved = cv.Version
+		}
+	}
+	return resolved, latest, nil
+}
+
+// Satisfies reports whether `version` satisfies `constraint`.  An empty
+// constraint allows any version.
+func Satisfies(constraint, version string) (bool, error) {
+	if constraint == "" {
+		constraint = "*"
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, errors.Wrapf(err, "Invalid version constraint '%s'", constraint)
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, errors.Wrapf(err, "Invalid version '%s'", version)
+	}
+	return c.Check(v), nil
+}
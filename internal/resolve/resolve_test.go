@@ -0,0 +1,111 @@
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/object88/churl"
+	"github.com/object88/churl/churltest"
+	"github.com/object88/churl/manifest"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func Test_Resolve_Newest(t *testing.T) {
+	index := repo.NewIndexFile()
+	for _, v := range []string{"1.0.0", "1.2.0", "2.0.0", "2.1.0-rc.1"} {
+		index.Entries["bar"] = append(index.Entries["bar"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "bar", Version: v}})
+	}
+	index.SortEntries()
+
+	tcs := []struct {
+		name       string
+		chart      string
+		constraint string
+		resolved   string
+		latest     string
+		err        bool
+	}{
+		{name: "caret", chart: "bar", constraint: "^1.0.0", resolved: "1.2.0", latest: "2.0.0"},
+		{name: "any", chart: "bar", resolved: "2.0.0", latest: "2.0.0"},
+		{name: "prerelease", chart: "bar", constraint: ">=2.1.0-0", resolved: "2.1.0-rc.1", latest: "2.0.0"},
+		{name: "unsatisfiable", chart: "bar", constraint: "^3.0.0", latest: "2.0.0"},
+		{name: "unknown chart", chart: "baz", constraint: "^1.0.0"},
+		{name: "invalid constraint", chart: "bar", constraint: "not a version", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, latest, err := Newest(index, tc.chart, tc.constraint)
+			if (err != nil) != tc.err {
+				t.Fatalf("Incorrect error; expected %t, actual %v", tc.err, err)
+			}
+			if resolved != tc.resolved || latest != tc.latest {
+				t.Errorf("Incorrect versions; expected %s/%s, actual %s/%s", tc.resolved, tc.latest, resolved, latest)
+			}
+		})
+	}
+}
+
+func Test_Resolve_Satisfies(t *testing.T) {
+	tcs := []struct {
+		constraint string
+		version    string
+		expected   bool
+		err        bool
+	}{
+		{constraint: "^1.0.0", version: "1.2.0", expected: true},
+		{constraint: "^1.0.0", version: "2.0.0"},
+		{constraint: "", version: "2.0.0", expected: true},
+		{constraint: "~1.2.0", version: "1.2.0-rc.1"},
+		{constraint: "not a version", version: "1.0.0", err: true},
+		{constraint: "^1.0.0", version: "latest", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.constraint+" "+tc.version, func(t *testing.T) {
+			ok, err := Satisfies(tc.constraint, tc.version)
+			if (err != nil) != tc.err {
+				t.Fatalf("Incorrect error; expected %t, actual %v", tc.err, err)
+			}
+			if ok != tc.expected {
+				t.Errorf("Incorrect result; expected %t, actual %t", tc.expected, ok)
+			}
+		})
+	}
+}
+
+func Test_Resolve_Resolver(t *testing.T) {
+	s := churltest.NewServer(&churltest.Chart{Name: "bar", Version: "1.0.0"})
+	defer s.Close()
+
+	m := manifest.New()
+	m.Museums["dev"] = s.Museum()
+
+	connects := map[string]int{}
+	connect := func(ctx context.Context, name string, cm *manifest.ChartMuseum) (*churl.Client, error) {
+		connects[name]++
+		return churl.NewClient(ctx, name, cm)
+	}
+
+	r := New(m, connect)
+	defer r.Close()
+
+	ctx := context.Background()
+	for _, repository := range []string{s.URL, "@dev", s.URL + "/"} {
+		name, index, err := r.Index(ctx, repository)
+		if err != nil {
+			t.Fatalf("Unexpected error for '%s':\n%s", repository, err.Error())
+		}
+		if name != "dev" || len(index.Entries["bar"]) != 1 {
+			t.Errorf("Incorrect index for '%s' from museum '%s'", repository, name)
+		}
+	}
+	if connects["dev"] != 1 {
+		t.Errorf("Connected to museum 'dev' %d times; expected once", connects["dev"])
+	}
+
+	if _, _, err := r.Index(ctx, "@other"); churl.KindOf(err) != churl.KindInvalidConfig {
+		t.Errorf("Expected a configuration error for an unknown museum; got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package testing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Museum is a museum that Config writes to the manifest
+type Museum struct {
+	Name string
+	URL  string
+}
+
+// Fixture is a temporary directory for a test of the churl binary, with a
+// manifest and a cache
+type Fixture struct {
+	// Root is the temporary directory
+	Root string
+
+	// Config is the manifest file, to pass with `--config`
+	Config string
+
+	// Cache is the cache directory, which `$CHURL_CACHE_DIR` points at
+	Cache string
+}
+
+// Config creates a Fixture, and points `$CHURL_CACHE_DIR` at its cache.  The
+// manifest is written with `museums` (see WriteConfig); without museums, it
+// is not written.  Close removes the directory.
+func Config(t *testing.T, museums ...Museum) *Fixture {
+	root, err := ioutil.TempDir("", uuid.New().String())
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory:\n%s", err.Error())
+	}
+
+	f := &Fixture{
+		Root:   root,
+		Config: path.Join(root, "config.json"),
+		Cache:  path.Join(root, "cache"),
+	}
+	os.Setenv("CHURL_CACHE_DIR", f.Cache)
+
+	if len(museums) != 0 {
+		f.WriteConfig(t, museums...)
+	}
+
+	return f
+}
+
+// WriteConfig writes the fixture's manifest, which defines `museums`; the
+// first of them is current
+func (f *Fixture) WriteConfig(t *testing.T, museums ...Museum) {
+	entries := make([]string, len(museums))
+	for k, m := range museums {
+		entries[k] = fmt.Sprintf(`{"name": %q, "url": %q}`, m.Name, m.URL)
+	}
+	manifest := fmt.Sprintf(`{"apiVersion": "v3", "museums": [%s], "current": %q}`, strings.Join(entries, ", "), museums[0].Name)
+	if err := ioutil.WriteFile(f.Config, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest:\n%s", err.Error())
+	}
+}
+
+// Close unsets `$CHURL_CACHE_DIR` and removes the fixture's directory
+func (f *Fixture) Close() {
+	os.Unsetenv("CHURL_CACHE_DIR")
+	os.RemoveAll(f.Root)
+}
@@ -82,6 +82,16 @@ func (l *Log) SetLevel(lvl Level) {
 	l.lvl = lvl
 }
 
+// Level returns the logger's level.  If the pointer receiver is nil, the
+// level of the log for `os.Stdout` is returned.
+func (l *Log) Level() Level {
+	if l == nil {
+		l = Stdout()
+	}
+
+	return l.lvl
+}
+
 // Verbosef will write if the log level is at least Verbose.
 // If the pointer receiver is nil, the log for `os.Stdout` will be used.
 func (l *Log) Verbosef(msg string, v ...interface{}) {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/object88/churl/cmd"
@@ -10,7 +9,6 @@ import (
 func main() {
 	rootCmd := cmd.InitializeCommands()
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
+		os.Exit(cmd.ReportError(os.Stderr, err))
 	}
 }
@@ -0,0 +1,139 @@
+package manifest
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIVersionLegacy is the implied version of manifests written before the
+	// `apiVersion` key was introduced
+	APIVersionLegacy string = "v0"
+
+	// APIVersionV1 adds the top-level `apiVersion` key
+	APIVersionV1 = "v1"
+
+	// APIVersionV2 adds the museum `username` and `password` keys, and
+	// resolves `${VAR}`, `file:`, and `exec:` references in museum values; a
+	// literal `$` is written `$$`
+	APIVersionV2 = "v2"
+
+	// APIVersionV3 adds the museum `url` key, for museums that are reached
+	// without a port forward
+	APIVersionV3 = "v3"
+
+	// APIVersionLatest is the version that this build of churl writes
+	APIVersionLatest = APIVersionV3
+)
+
+// migration upgrades a raw manifest document from one apiVersion to the next.
+// Migrations work on the undecoded document so that they can rename, move, or
+// remove keys that the current Go types no longer understand.
+type migration struct {
+	from string
+	to   string
+	fn   func(doc map[string]*json.RawMessage) error
+}
+
+// migrations must be kept in order; each entry's `to` is the next entry's
+// `from`, and the last entry's `to` is APIVersionLatest.
+var migrations = []migration{
+	{
+		from: APIVersionLegacy,
+		to:   APIVersionV1,
+		fn:   func(doc map[string]*json.RawMessage) error { return nil },
+	},
+	{
+		from: APIVersionV1,
+		to:   APIVersionV2,
+		fn:   escapeDollars,
+	},
+	{
+		from: APIVersionV2,
+		to:   APIVersionV3,
+		fn:   func(doc map[string]*json.RawMessage) error { return nil },
+	},
+}
+
+// escapeDollars doubles each `$` in the museum values, which were literal
+// before APIVersionV2 introduced `${VAR}` references
+func escapeDollars(doc map[string]*json.RawMessage) error {
+	r, ok := doc[museumsKey]
+	if !ok || r == nil {
+		return nil
+	}
+
+	var museums []map[string]*json.RawMessage
+	if err := json.Unmarshal(*r, &museums); err != nil {
+		return errors.Wrapf(err, "Failed to unmarshal '%s' value into array of objects", museumsKey)
+	}
+
+	for _, museum := range museums {
+		for k, v := range museum {
+			var s string
+			if k == nameKey || v == nil || json.Unmarshal(*v, &s) != nil || !strings.Contains(s, "$") {
+				continue
+			}
+			b, err := json.Marshal(strings.Replace(s, "$", "$$", -1))
+			if err != nil {
+				return errors.Wrapf(err, "Failed to marshal museum key '%s'", k)
+			}
+			raw := json.RawMessage(b)
+			museum[k] = &raw
+		}
+	}
+
+	b, err := json.Marshal(museums)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to marshal '%s' value", museumsKey)
+	}
+	raw := json.RawMessage(b)
+	doc[museumsKey] = &raw
+	return nil
+}
+
+// SupportedVersions returns the apiVersions that can be read, oldest first
+func SupportedVersions() []string {
+	versions := make([]string, 0, len(migrations)+1)
+	for _, m := range migrations {
+		versions = append(versions, m.from)
+	}
+	return append(versions, APIVersionLatest)
+}
+
+// migrate upgrades `doc` in place to APIVersionLatest, and returns the
+// apiVersion that the document was written with
+func migrate(doc map[string]*json.RawMessage) (string, error) {
+	version := APIVersionLegacy
+	if r, ok := doc[apiVersionKey]; ok {
+		if err := json.Unmarshal(*r, &version); err != nil {
+			return "", errors.Wrapf(err, "Failed to unmarshal '%s' value into string", apiVersionKey)
+		}
+	}
+
+	start := -1
+	for k, m := range migrations {
+		if m.from == version {
+			start = k
+			break
+		}
+	}
+	if start == -1 {
+		if version != APIVersionLatest {
+			return "", errors.Errorf("Unsupported manifest %s '%s'; this build of churl reads '%s'", apiVersionKey, version, strings.Join(SupportedVersions(), "', '"))
+		}
+		return version, nil
+	}
+
+	for _, m := range migrations[start:] {
+		if err := m.fn(doc); err != nil {
+			return "", errors.Wrapf(err, "Failed to migrate manifest from %s '%s' to '%s'", apiVersionKey, m.from, m.to)
+		}
+		raw := json.RawMessage(`"` + m.to + `"`)
+		doc[apiVersionKey] = &raw
+	}
+
+	return version, nil
+}
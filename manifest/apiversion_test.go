@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Manifest_Migrate_Legacy(t *testing.T) {
+	m, err := Open(strings.NewReader(knownGoodManifest))
+	if err != nil {
+		t.Fatalf("Failed to open legacy manifest:\n%s", err.Error())
+	}
+
+	if m.LoadedVersion() != APIVersionLegacy {
+		t.Errorf("Incorrect loaded version; expected '%s', actual '%s'", APIVersionLegacy, m.LoadedVersion())
+	}
+
+	var buf bytes.Buffer
+	if _, err = m.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write manifest:\n%s", err.Error())
+	}
+
+	m2, err := Open(&buf)
+	if err != nil {
+		t.Fatalf("Failed to reopen migrated manifest:\n%s", err.Error())
+	}
+
+	if m2.LoadedVersion() != APIVersionLatest {
+		t.Errorf("Incorrect loaded version; expected '%s', actual '%s'", APIVersionLatest, m2.LoadedVersion())
+	}
+
+	cm, ok := m2.Museums["default"]
+	if !ok {
+		t.Fatalf("Failed to find chart museum 'default' after round trip")
+	}
+	if cm.KubeContext != "krobot" || cm.ServiceName != "cm-chartmuseum" || cm.Port != "8080" {
+		t.Errorf("Chart museum did not survive round trip: %#v", cm)
+	}
+}
+
+func Test_Manifest_Migrate_LiteralDollar(t *testing.T) {
+	os.Setenv("CHURL_TEST_LITERAL", "expanded")
+
+	manifest := `{"apiVersion": "v1", "museums": [{"name": "aaa", "namespace": "${CHURL_TEST_LITERAL}", "port": 8080}], "current": "aaa"}`
+	m, err := Open(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Failed to open manifest:\n%s", err.Error())
+	}
+
+	cm, err := m.Current().Resolve()
+	if err != nil {
+		t.Fatalf("Failed to resolve museum:\n%s", err.Error())
+	}
+	if cm.Namespace != "${CHURL_TEST_LITERAL}" || cm.Port != "8080" {
+		t.Errorf("Value written before references were introduced was not literal: %#v", cm)
+	}
+}
+
+func Test_Manifest_Migrate_Invalid(t *testing.T) {
+	tcs := []struct {
+		name     string
+		manifest string
+	}{
+		{
+			name:     "future version",
+			manifest: `{"apiVersion": "v99", "museums": [{"name": "aaa"}], "current": "aaa"}`,
+		},
+		{
+			name:     "non-string version",
+			manifest: `{"apiVersion": 1, "museums": [{"name": "aaa"}], "current": "aaa"}`,
+		},
+		{
+			name:     "extra key",
+			manifest: `{"apiVersion": "v1", "museums": [{"name": "aaa"}], "current": "aaa", "foo": "bar"}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Open(strings.NewReader(tc.manifest))
+			if err == nil {
+				t.Errorf("Expected error, got none")
+			}
+		})
+	}
+}
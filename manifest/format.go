@@ -0,0 +1,225 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Format is the encoding of a manifest document
+type Format int
+
+const (
+	// JSON is the default manifest encoding
+	JSON Format = iota
+
+	// YAML is the YAML manifest encoding
+	YAML
+)
+
+// Extensions returns the file extensions, without the leading '.', that are
+// recognized as manifest files
+func Extensions() []string {
+	return []string{"json", "yaml", "yml"}
+}
+
+// String satisfies fmt.Stringer
+func (f Format) String() string {
+	switch f {
+	case YAML:
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// formatFromPath determines the format from the file extension; the second
+// return value is false if the extension is not recognized
+func formatFromPath(p string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".json":
+		return JSON, true
+	case ".yaml", ".yml":
+		return YAML, true
+	default:
+		return JSON, false
+	}
+}
+
+// sniffFormat determines the format from the content: a JSON manifest is
+// always an object, so anything else is treated as YAML
+func sniffFormat(b []byte) Format {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) != 0 && trimmed[0] == '{' {
+		return JSON
+	}
+	return YAML
+}
+
+// toJSON checks `b` for duplicate keys and converts it to JSON.  Both formats
+// report duplicate keys with the same error, so that the behavior does not
+// depend on the encoding.
+func toJSON(b []byte, f Format) ([]byte, error) {
+	if f == JSON {
+		if err := checkJSONDuplicateKeys(b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode YAML")
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAMLAsJSON(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fromJSON converts the JSON document `b` to the requested format, keeping
+// the order of object keys
+func fromJSON(b []byte, f Format) ([]byte, error) {
+	if f == JSON {
+		return b, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := readOrderedJSON(dec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Internal error: failed to read JSON")
+	}
+
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Internal error: failed to encode YAML")
+	}
+	return out, nil
+}
+
+func checkJSONDuplicateKeys(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	_, err := readOrderedJSON(dec)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to decode JSON")
+	}
+	return nil
+}
+
+// readOrderedJSON reads the next value from `dec`, representing objects as
+// yaml.MapSlice so that key order is preserved.  Duplicate keys are an error.
+func readOrderedJSON(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		ms := yaml.MapSlice{}
+		seen := map[string]struct{}{}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k := kt.(string)
+			if _, ok := seen[k]; ok {
+				return nil, duplicateKeyError(k)
+			}
+			seen[k] = struct{}{}
+
+			v, err := readOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			ms = append(ms, yaml.MapItem{Key: k, Value: v})
+		}
+		if _, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		return ms, nil
+	case json.Delim('['):
+		s := []interface{}{}
+		for dec.More() {
+			v, err := readOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		if _, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case nil:
+		return nil, nil
+	}
+
+	if n, ok := t.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+
+	return t, nil
+}
+
+func writeYAMLAsJSON(w io.Writer, v interface{}) error {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		io.WriteString(w, "{")
+		seen := map[string]struct{}{}
+		for k, item := range t {
+			key := fmt.Sprint(item.Key)
+			if _, ok := seen[key]; ok {
+				return duplicateKeyError(key)
+			}
+			seen[key] = struct{}{}
+
+			if k != 0 {
+				io.WriteString(w, ",")
+			}
+			b, _ := json.Marshal(key)
+			w.Write(b)
+			io.WriteString(w, ":")
+			if err := writeYAMLAsJSON(w, item.Value); err != nil {
+				return err
+			}
+		}
+		io.WriteString(w, "}")
+	case []interface{}:
+		io.WriteString(w, "[")
+		for k, item := range t {
+			if k != 0 {
+				io.WriteString(w, ",")
+			}
+			if err := writeYAMLAsJSON(w, item); err != nil {
+				return err
+			}
+		}
+		io.WriteString(w, "]")
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to convert YAML value '%v' to JSON", t)
+		}
+		w.Write(b)
+	}
+	return nil
+}
+
+func duplicateKeyError(k string) error {
+	return errors.Errorf("Found duplicate key '%s'", k)
+}
@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const knownGoodYamlManifest = `apiVersion: v1
+museums:
+- name: default
+  kubeContext: krobot
+  serviceName: cm-chartmuseum
+  port: "8080"
+current: default
+`
+
+func Test_Manifest_Open_Yaml(t *testing.T) {
+	m, err := Open(strings.NewReader(knownGoodYamlManifest))
+	if err != nil {
+		t.Fatalf("Failed to open manifest string:\n%s", err.Error())
+	}
+
+	cm := m.Current()
+	if cm == nil {
+		t.Fatalf("Current museum is nil")
+	}
+	if cm.KubeContext != "krobot" {
+		t.Errorf("Chart museum did not unmarshal")
+	}
+}
+
+func Test_Manifest_Open_Yaml_NumericPort(t *testing.T) {
+	m, err := Open(strings.NewReader(strings.Replace(knownGoodYamlManifest, `port: "8080"`, "port: 8080", 1)))
+	if err != nil {
+		t.Fatalf("Failed to open manifest string:\n%s", err.Error())
+	}
+
+	if cm := m.Current(); cm == nil || cm.Port != "8080" {
+		t.Errorf("Chart museum port did not unmarshal: %#v", cm)
+	}
+}
+
+func Test_Manifest_Save_Yaml(t *testing.T) {
+	chartdir, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(chartdir)
+	chartfile := path.Join(chartdir, "manifest.yaml")
+
+	err := ioutil.WriteFile(chartfile, []byte(knownGoodYamlManifest), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write manifest file:\n%s", err.Error())
+	}
+
+	m, err := OpenFromFile(chartfile)
+	if err != nil {
+		t.Fatalf("Failed to open manifest file:\n%s", err.Error())
+	}
+	m.Museums["default"].Namespace = "chartmuseum"
+	if err = m.Save(); err != nil {
+		t.Fatalf("Failed to save:\n%s", err.Error())
+	}
+	m.Close()
+
+	b, err := ioutil.ReadFile(chartfile)
+	if err != nil {
+		t.Fatalf("Failed to read manifest file:\n%s", err.Error())
+	}
+	if sniffFormat(b) != YAML {
+		t.Errorf("Manifest was not saved as YAML:\n%s", b)
+	}
+	if !bytes.Contains(b, []byte("namespace: chartmuseum")) {
+		t.Errorf("Manifest did not save change:\n%s", b)
+	}
+}
+
+func Test_Manifest_Open_Invalid(t *testing.T) {
+	tcs := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{
+			name:     "json duplicate key",
+			manifest: `{"apiVersion": "v1", "museums": [{"name": "aaa", "port": "1", "port": "2"}], "current": "aaa"}`,
+			err:      "Found duplicate key 'port'",
+		},
+		{
+			name:     "yaml duplicate key",
+			manifest: "apiVersion: v1\nmuseums:\n- name: aaa\n  port: \"1\"\n  port: \"2\"\ncurrent: aaa\n",
+			err:      "Found duplicate key 'port'",
+		},
+		{
+			name:     "json duplicate top-level key",
+			manifest: `{"apiVersion": "v1", "museums": [{"name": "aaa"}], "current": "aaa", "current": "aaa"}`,
+			err:      "Found duplicate key 'current'",
+		},
+		{
+			name:     "yaml duplicate top-level key",
+			manifest: "apiVersion: v1\nmuseums:\n- name: aaa\ncurrent: aaa\ncurrent: aaa\n",
+			err:      "Found duplicate key 'current'",
+		},
+		{
+			name:     "json unknown key",
+			manifest: `{"apiVersion": "v1", "museums": [{"name": "aaa", "foo": "bar"}], "current": "aaa"}`,
+			err:      "Found extra keys 'foo'",
+		},
+		{
+			name:     "yaml unknown key",
+			manifest: "apiVersion: v1\nmuseums:\n- name: aaa\n  foo: bar\ncurrent: aaa\n",
+			err:      "Found extra keys 'foo'",
+		},
+		{
+			name:     "json duplicate museum",
+			manifest: `{"apiVersion": "v1", "museums": [{"name": "aaa"}, {"name": "aaa"}], "current": "aaa"}`,
+			err:      "Found duplicate museum name 'aaa'",
+		},
+		{
+			name:     "yaml duplicate museum",
+			manifest: "apiVersion: v1\nmuseums:\n- name: aaa\n- name: aaa\ncurrent: aaa\n",
+			err:      "Found duplicate museum name 'aaa'",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Open(strings.NewReader(tc.manifest))
+			if err == nil {
+				t.Fatalf("Expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Incorrect error; expected to contain '%s', actual '%s'", tc.err, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	fileReferencePrefix = "file:"
+	execReferencePrefix = "exec:"
+)
+
+// Resolve returns a copy of the museum where environment variable references
+// in every string field, and `file:` and `exec:` references in the fields
+// tagged `sensitive:"true"`, are replaced by their values.  The manifest
+// itself keeps the references, so that it is written back out as it was read,
+// and so that a reference is only resolved for a museum that is used.  A
+// museum from an untrusted manifest (see Manifest.Distrust) may not use
+// `file:` or `exec:` references.
+//
+// Environment variables are referenced as `${VAR}`, or `${VAR:-default}` to
+// use `default` when VAR is unset or empty.  `$$` is a literal `$`.
+func (cm *ChartMuseum) Resolve() (*ChartMuseum, error) {
+	return cm.resolve(true)
+}
+
+// resolve returns a copy of the museum with its references resolved; `file:`
+// and `exec:` references are only resolved if `references` is set.
+func (cm *ChartMuseum) resolve(references bool) (*ChartMuseum, error) {
+	c := *cm
+	c.untrusted = ""
+
+	v := reflect.ValueOf(&c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Type.Kind() != reflect.String {
+			continue
+		}
+		field := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		s, err := expandEnv(v.Field(i).String())
+		if err == nil && references && f.Tag.Get("sensitive") == "true" {
+			if cm.untrusted != "" && isReference(s) {
+				err = errors.Errorf("References to files and commands are not allowed in the untrusted manifest '%s'", cm.untrusted)
+			} else {
+				s, err = resolveReference(s)
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Field '%s'", field)
+		}
+		v.Field(i).SetString(s)
+	}
+
+	return &c, nil
+}
+
+// expandEnv replaces `${VAR}` and `${VAR:-default}` in `s`
+func expandEnv(s string) (string, error) {
+	var sb strings.Builder
+	for {
+		offset := strings.IndexRune(s, '$')
+		if offset == -1 || offset == len(s)-1 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		sb.WriteString(s[:offset])
+		s = s[offset:]
+
+		switch s[1] {
+		case '$':
+			sb.WriteRune('$')
+			s = s[2:]
+			continue
+		case '{':
+		default:
+			sb.WriteRune('$')
+			s = s[1:]
+			continue
+		}
+
+		end := strings.IndexRune(s, '}')
+		if end == -1 {
+			return "", errors.Errorf("Unterminated reference '%s'", s)
+		}
+		expr := s[2:end]
+		s = s[end+1:]
+
+		name, def, hasDefault := expr, "", false
+		if sep := strings.Index(expr, ":-"); sep != -1 {
+			name, def, hasDefault = expr[:sep], expr[sep+2:], true
+		}
+		if name == "" {
+			return "", errors.Errorf("Empty environment variable reference")
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok || (hasDefault && value == "") {
+			if !hasDefault {
+				return "", errors.Errorf("Environment variable '%s' is not set", name)
+			}
+			value = def
+		}
+		sb.WriteString(value)
+	}
+}
+
+// isReference reports whether `s` is a `file:` or `exec:` reference
+func isReference(s string) bool {
+	return strings.HasPrefix(s, fileReferencePrefix) || strings.HasPrefix(s, execReferencePrefix)
+}
+
+// resolveReference reads the value of a `file:PATH` or `exec:COMMAND`
+// reference.  Other values are returned unchanged.  Trailing newlines are
+// removed from the file contents or command output.
+func resolveReference(s string) (string, error) {
+	var b []byte
+	var err error
+
+	switch {
+	case strings.HasPrefix(s, fileReferencePrefix):
+		p := strings.TrimPrefix(s, fileReferencePrefix)
+		b, err = ioutil.ReadFile(p)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to read referenced file '%s'", p)
+		}
+	case strings.HasPrefix(s, execReferencePrefix):
+		command := strings.TrimPrefix(s, execReferencePrefix)
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		b, err = cmd.Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "Referenced command '%s' failed: %s", command, strings.TrimSpace(stderr.String()))
+		}
+	default:
+		return s, nil
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,189 @@
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_Manifest_ExpandEnv(t *testing.T) {
+	os.Setenv("CHURL_TEST_SET", "foo")
+	os.Setenv("CHURL_TEST_EMPTY", "")
+	os.Unsetenv("CHURL_TEST_UNSET")
+
+	tcs := []struct {
+		name     string
+		input    string
+		expected string
+		err      bool
+	}{
+		{name: "literal", input: "foo", expected: "foo"},
+		{name: "set", input: "a-${CHURL_TEST_SET}-b", expected: "a-foo-b"},
+		{name: "default unused", input: "${CHURL_TEST_SET:-bar}", expected: "foo"},
+		{name: "default unset", input: "${CHURL_TEST_UNSET:-bar}", expected: "bar"},
+		{name: "default empty", input: "${CHURL_TEST_EMPTY:-bar}", expected: "bar"},
+		{name: "empty", input: "${CHURL_TEST_EMPTY}", expected: ""},
+		{name: "escaped", input: "$${CHURL_TEST_SET}", expected: "${CHURL_TEST_SET}"},
+		{name: "bare dollar", input: "a$b$", expected: "a$b$"},
+		{name: "unset", input: "${CHURL_TEST_UNSET}", err: true},
+		{name: "unterminated", input: "${CHURL_TEST_SET", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := expandEnv(tc.input)
+			if tc.err {
+				if err == nil {
+					t.Errorf("Expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if actual != tc.expected {
+				t.Errorf("Incorrect expansion; expected '%s', actual '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Manifest_Resolve(t *testing.T) {
+	secret := writeManifestFile(t, "hunter2\n")
+	defer os.Remove(secret)
+
+	os.Setenv("CHURL_TEST_CONTEXT", "ci")
+	os.Setenv("CHURL_TEST_SECRET", secret)
+
+	manifest := `{
+		"apiVersion": "v2",
+		"museums": [{
+			"name": "default",
+			"kubeContext": "${CHURL_TEST_CONTEXT}",
+			"namespace": "${CHURL_TEST_NAMESPACE:-chartmuseum}",
+			"username": "exec:echo admin",
+			"password": "file:${CHURL_TEST_SECRET}"
+		}],
+		"current": "default"
+	}`
+
+	m, err := Open(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Failed to open manifest:\n%s", err.Error())
+	}
+
+	if m.Current().Password != "file:${CHURL_TEST_SECRET}" {
+		t.Errorf("Reference was resolved when the manifest was loaded: %#v", m.Current())
+	}
+
+	cm, err := m.Current().Resolve()
+	if err != nil {
+		t.Fatalf("Failed to resolve museum:\n%s", err.Error())
+	}
+	if cm.KubeContext != "ci" || cm.Namespace != "chartmuseum" || cm.Username != "admin" || cm.Password != "hunter2" {
+		t.Errorf("References were not resolved: %#v", cm)
+	}
+
+	m.Current().Namespace = "other"
+
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "file:${CHURL_TEST_SECRET}") {
+		t.Errorf("Resolved secret was written:\n%s", out)
+	}
+	if !strings.Contains(out, "${CHURL_TEST_CONTEXT}") {
+		t.Errorf("Reference was not written:\n%s", out)
+	}
+	if !strings.Contains(out, `"other"`) {
+		t.Errorf("Changed value was not written:\n%s", out)
+	}
+}
+
+func Test_Manifest_Resolve_Invalid(t *testing.T) {
+	os.Unsetenv("CHURL_TEST_UNSET")
+
+	tcs := []struct {
+		name   string
+		museum string
+		err    string
+	}{
+		{
+			name:   "unset variable",
+			museum: `{"name": "aaa", "kubeContext": "${CHURL_TEST_UNSET}"}`,
+			err:    "Field 'kubeContext'",
+		},
+		{
+			name:   "missing file",
+			museum: `{"name": "aaa", "password": "file:/does/not/exist"}`,
+			err:    "Field 'password'",
+		},
+		{
+			name:   "failed command",
+			museum: `{"name": "aaa", "username": "exec:exit 1"}`,
+			err:    "Field 'username'",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			manifest := `{"apiVersion": "v2", "museums": [` + tc.museum + `], "current": "aaa"}`
+			m, err := Open(strings.NewReader(manifest))
+			if err != nil {
+				t.Fatalf("Failed to open manifest:\n%s", err.Error())
+			}
+			_, err = m.Current().Resolve()
+			if err == nil {
+				t.Fatalf("Expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Incorrect error; expected to contain '%s', actual '%s'", tc.err, err.Error())
+			}
+		})
+	}
+}
+
+func Test_Manifest_Resolve_NotSensitive(t *testing.T) {
+	manifest := `{"apiVersion": "v2", "museums": [{"name": "aaa", "serviceName": "file:foo"}], "current": "aaa"}`
+	m, err := Open(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Failed to open manifest:\n%s", err.Error())
+	}
+	cm, err := m.Current().Resolve()
+	if err != nil {
+		t.Fatalf("Failed to resolve museum:\n%s", err.Error())
+	}
+	if cm.ServiceName != "file:foo" {
+		t.Errorf("Reference in non-sensitive field was resolved")
+	}
+}
+
+func Test_Manifest_Resolve_Untrusted(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	marker := path.Join(root, "marker")
+	project := path.Join(root, ".churl.json")
+	manifest := `{"apiVersion": "v3", "museums": [{"name": "aaa", "url": "http://localhost", "password": "exec:touch ` + marker + `"}], "current": "aaa"}`
+	if err := ioutil.WriteFile(project, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+
+	m, err := Load(project)
+	if err != nil {
+		t.Fatalf("Failed to load manifest:\n%s", err.Error())
+	}
+	m.Distrust(project)
+
+	if _, err = m.Current().Resolve(); err == nil || !strings.Contains(err.Error(), "untrusted") {
+		t.Errorf("Expected untrusted reference error, got '%v'", err)
+	}
+	if _, err = os.Stat(marker); err == nil {
+		t.Errorf("Command in untrusted manifest was run")
+	}
+}
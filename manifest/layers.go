@@ -0,0 +1,123 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProjectFileBase is the name, without extension, of a project-local manifest.
+// A project manifest is merged on top of the user's manifest, so that a team
+// can share museum definitions in source control.
+const ProjectFileBase = ".churl"
+
+// vcsMarkers are the entries that mark the root of a working copy; the search
+// for a project manifest does not continue above it.
+var vcsMarkers = []string{".git", ".hg", ".svn"}
+
+// FindProjectFile searches `dir` and each of its parents for a project
+// manifest (i.e., `.churl.json`), and returns the path of the first one found.
+// The search stops at the root of the version-controlled working copy that
+// contains `dir`, so that a manifest in a parent directory such as `$HOME` is
+// not picked up.  The second return value is false if there is no project
+// manifest.
+func FindProjectFile(dir string) (string, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "Failed to get absolute path of '%s'", dir)
+	}
+
+	for {
+		for _, ext := range Extensions() {
+			candidate := filepath.Join(dir, ProjectFileBase+"."+ext)
+			fi, err := os.Stat(candidate)
+			if err == nil && !fi.IsDir() {
+				return candidate, true, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || isVCSRoot(dir) {
+			return "", false, nil
+		}
+		dir = parent
+	}
+}
+
+func isVCSRoot(dir string) bool {
+	for _, marker := range vcsMarkers {
+		// `.git` is a file in a worktree or submodule.
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads each of the manifest files in `paths` and merges them in order:
+// a museum defined in a later file replaces a museum of the same name from an
+// earlier file, and the last file to set `current` decides the current
+// museum.  Individual files may omit `current`, but the merged manifest must
+// have a valid current museum.  The returned manifest is not backed by a file,
+// and cannot be saved.
+func Load(paths ...string) (*Manifest, error) {
+	if len(paths) == 0 {
+		return nil, errors.Errorf("No manifest files provided")
+	}
+
+	layers := make([]*Manifest, len(paths))
+	for k, p := range paths {
+		l, err := OpenLayerFromFile(p)
+		if err != nil {
+			return nil, err
+		}
+		l.Close()
+		layers[k] = l
+	}
+
+	m := merge(layers...)
+
+	if m.current == "" {
+		return nil, errors.Errorf("None of the manifest files '%s' set a '%s' museum", strings.Join(paths, "', '"), currentKey)
+	}
+	if _, ok := m.Museums[m.current]; !ok {
+		return nil, errors.Errorf("Manifest file '%s' contains invalid 'current' museum '%s'", m.currentOrigin, m.current)
+	}
+
+	return m, nil
+}
+
+// Distrust marks the museums defined by the manifest file `path`, such as a
+// project manifest checked out from source control, as untrusted: Resolve
+// refuses their `file:` and `exec:` references, so that loading a repository's
+// manifest cannot read local files or run commands.
+func (m *Manifest) Distrust(path string) {
+	for name, origin := range m.origins {
+		if cm := m.Museums[name]; origin == path && cm != nil {
+			cm.untrusted = path
+		}
+	}
+}
+
+func merge(layers ...*Manifest) *Manifest {
+	m := New()
+	m.origins = map[string]string{}
+	if len(layers) != 0 {
+		m.format = layers[0].format
+	}
+
+	for _, l := range layers {
+		for name, cm := range l.Museums {
+			m.Museums[name] = cm
+			m.origins[name] = l.origins[name]
+		}
+		if l.current != "" {
+			m.current = l.current
+			m.currentOrigin = l.currentOrigin
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,152 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const userManifest = `{
+	"apiVersion": "v1",
+	"museums": [
+		{"name": "dev", "kubeContext": "laptop", "serviceName": "cm-chartmuseum", "port": "8080"},
+		{"name": "prod", "kubeContext": "prod", "serviceName": "cm-chartmuseum", "port": "8080"}
+	],
+	"current": "prod"
+}
+`
+
+const projectManifest = `apiVersion: v1
+museums:
+- name: dev
+  kubeContext: team
+  serviceName: cm-chartmuseum
+  port: "8080"
+current: dev
+`
+
+func Test_Manifest_FindProjectFile(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	nested := path.Join(root, "a", "b")
+	os.MkdirAll(nested, 0755)
+
+	if _, ok, _ := FindProjectFile(nested); ok {
+		t.Skipf("Found a project manifest above '%s'; cannot test", root)
+	}
+
+	expected := path.Join(root, ".churl.yaml")
+	if err := ioutil.WriteFile(expected, []byte(projectManifest), 0644); err != nil {
+		t.Fatalf("Failed to write project manifest:\n%s", err.Error())
+	}
+
+	actual, ok, err := FindProjectFile(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if !ok || actual != expected {
+		t.Errorf("Incorrect project file; expected '%s', actual '%s'", expected, actual)
+	}
+}
+
+func Test_Manifest_FindProjectFile_StopsAtVCSRoot(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	repo := path.Join(root, "repo")
+	nested := path.Join(repo, "a")
+	os.MkdirAll(path.Join(repo, ".git"), 0755)
+	os.MkdirAll(nested, 0755)
+
+	// The manifest above the working copy is not part of the project.
+	if err := ioutil.WriteFile(path.Join(root, ".churl.yaml"), []byte(projectManifest), 0644); err != nil {
+		t.Fatalf("Failed to write project manifest:\n%s", err.Error())
+	}
+
+	actual, ok, err := FindProjectFile(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if ok {
+		t.Errorf("Found project file '%s' above the working copy", actual)
+	}
+
+	expected := path.Join(repo, ".churl.yaml")
+	if err := ioutil.WriteFile(expected, []byte(projectManifest), 0644); err != nil {
+		t.Fatalf("Failed to write project manifest:\n%s", err.Error())
+	}
+
+	actual, ok, err = FindProjectFile(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if !ok || actual != expected {
+		t.Errorf("Incorrect project file; expected '%s', actual '%s'", expected, actual)
+	}
+}
+
+func Test_Manifest_Load(t *testing.T) {
+	root, _ := ioutil.TempDir("", uuid.New().String())
+	defer os.RemoveAll(root)
+
+	userFile := path.Join(root, "config.json")
+	ioutil.WriteFile(userFile, []byte(userManifest), 0644)
+	projectFile := path.Join(root, ".churl.yaml")
+	ioutil.WriteFile(projectFile, []byte(projectManifest), 0644)
+
+	m, err := Load(userFile, projectFile)
+	if err != nil {
+		t.Fatalf("Failed to load manifests:\n%s", err.Error())
+	}
+
+	if len(m.Museums) != 2 {
+		t.Errorf("Incorrect number of museums: expected 2, actual %d", len(m.Museums))
+	}
+	if m.CurrentName() != "dev" || m.CurrentOrigin() != projectFile {
+		t.Errorf("Incorrect current museum '%s' from '%s'", m.CurrentName(), m.CurrentOrigin())
+	}
+	if m.Current().KubeContext != "team" {
+		t.Errorf("Project museum did not take precedence")
+	}
+	if m.Origin("prod") != userFile {
+		t.Errorf("Incorrect origin for 'prod': '%s'", m.Origin("prod"))
+	}
+	if m.Origin("dev") != projectFile {
+		t.Errorf("Incorrect origin for 'dev': '%s'", m.Origin("dev"))
+	}
+}
+
+func Test_Manifest_Load_Invalid(t *testing.T) {
+	tcs := []struct {
+		name   string
+		layers []string
+	}{
+		{
+			name:   "no current",
+			layers: []string{`{"museums": [{"name": "aaa"}]}`},
+		},
+		{
+			name:   "undefined current",
+			layers: []string{`{"museums": [{"name": "aaa"}]}`, `{"current": "bbb"}`},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			files := make([]string, len(tc.layers))
+			for k, l := range tc.layers {
+				files[k] = writeManifestFile(t, l)
+				defer os.Remove(files[k])
+			}
+
+			_, err := Load(files...)
+			if err == nil {
+				t.Errorf("Expected error, got none")
+			}
+		})
+	}
+}
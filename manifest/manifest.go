@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
 const (
-	museumsKey string = "museums"
-	currentKey string = "current"
+	apiVersionKey string = "apiVersion"
+	museumsKey           = "museums"
+	currentKey           = "current"
 )
 
 // Manifest describes the configuration for the churl tool
@@ -21,14 +24,32 @@ type Manifest struct {
 
 	current string
 
+	// loadedVersion is the apiVersion of the document that this manifest was
+	// read from, before any migrations were applied
+	loadedVersion string
+
+	// format is the encoding used by WriteTo and Save
+	format Format
+
+	// origins maps each museum name to the file that defined it, and
+	// currentOrigin is the file that set `current`
+	origins       map[string]string
+	currentOrigin string
+
+	// partial manifests are layers that are merged with others, and so may
+	// omit `current` or refer to a museum defined in another layer
+	partial bool
+
 	f *os.File
 }
 
 // Init creates a new manifest instance and creates a new file at `target`.  If
 // `target` already exists, func fails.  File is created but has no contents
-// until Save is called.
+// until Save is called.  The file is written as YAML if `target` has a YAML
+// extension, and JSON otherwise.
 func Init(target string) (*Manifest, error) {
 	m := New()
+	m.format, _ = formatFromPath(target)
 
 	f, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
@@ -43,39 +64,95 @@ func Init(target string) (*Manifest, error) {
 // New creates a new manifest instance
 func New() *Manifest {
 	return &Manifest{
-		Museums: map[string]*ChartMuseum{},
+		Museums:       map[string]*ChartMuseum{},
+		loadedVersion: APIVersionLatest,
 	}
 }
 
-// Open creates a Manifest instance from the JSON content from the `r`
-// parameter
+// Open creates a Manifest instance from the JSON or YAML content from the `r`
+// parameter.  The format is determined by inspecting the content.
 func Open(r io.Reader) (*Manifest, error) {
-	m := &Manifest{}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the manifest")
+	}
+
+	return open(b, sniffFormat(b), false)
+}
+
+func open(b []byte, format Format, partial bool) (*Manifest, error) {
+	b, err := toJSON(b, format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode the manifest")
+	}
 
-	dec := json.NewDecoder(r)
+	m := &Manifest{
+		partial: partial,
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(m); err != nil {
 		return nil, errors.Wrapf(err, "Failed to decode the manifest")
 	}
 
+	m.format = format
+
 	return m, nil
 }
 
-// OpenFromFile creates a Manifest instance from the contents of the JSON-
-// encoded contents of the file at `manifestFilepath`.  An open reference to
-// the file is kept with the instance, so the caller is responsible for calling
-// `Close`.
+// OpenFromFile creates a Manifest instance from the contents of the JSON- or
+// YAML-encoded contents of the file at `manifestFilepath`.  The format is
+// determined by the file extension, or by the content if the extension is not
+// recognized; Save writes the file back in the same format.  An open reference
+// to the file is kept with the instance, so the caller is responsible for
+// calling `Close`.
 func OpenFromFile(manifestFilepath string) (*Manifest, error) {
-	f, err := os.Open(manifestFilepath)
+	return openFromFile(manifestFilepath, false)
+}
+
+// OpenLayerFromFile is like OpenFromFile, but the manifest is treated as one
+// layer of several (see Load): it may omit `current`, or set it to a museum
+// that is defined in another file.
+func OpenLayerFromFile(manifestFilepath string) (*Manifest, error) {
+	return openFromFile(manifestFilepath, true)
+}
+
+func openFromFile(manifestFilepath string, partial bool) (*Manifest, error) {
+	f, err := os.OpenFile(manifestFilepath, os.O_RDWR, 0)
+	if os.IsPermission(err) {
+		// The file can still be read; Save will fail.
+		f, err = os.Open(manifestFilepath)
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to open manifest file '%s'", manifestFilepath)
 	}
 
-	m, err := Open(f)
+	b, err := ioutil.ReadAll(f)
 	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "Failed to read manifest file '%s'", manifestFilepath)
+	}
+
+	format, ok := formatFromPath(manifestFilepath)
+	if !ok {
+		format = sniffFormat(b)
+	}
+
+	m, err := open(b, format, partial)
+	if err != nil {
+		f.Close()
 		return nil, errors.Wrapf(err, "Failed to open manifest from file '%s'", manifestFilepath)
 	}
 
+	m.origins = map[string]string{}
+	for name := range m.Museums {
+		m.origins[name] = manifestFilepath
+	}
+	if m.current != "" {
+		m.currentOrigin = manifestFilepath
+	}
+
 	m.f = f
 
 	return m, nil
@@ -90,6 +167,52 @@ func (m *Manifest) Current() *ChartMuseum {
 	return m.Museums[m.current]
 }
 
+// Origin returns the path of the file that defined the museum `name`, or an
+// empty string if the museum is unknown or the manifest was not read from a
+// file
+func (m *Manifest) Origin(name string) string {
+	return m.origins[name]
+}
+
+// Use makes the museum `name` the current chart museum.  The current museum no
+// longer has an origin file, and the change is only written to disk if Save
+// is called.
+func (m *Manifest) Use(name string) error {
+	if _, ok := m.Museums[name]; !ok {
+		return errors.Errorf("Museum '%s' is not defined; known museums are '%s'", name, strings.Join(m.Names(), "', '"))
+	}
+	m.current = name
+	m.currentOrigin = ""
+	return nil
+}
+
+// Names returns the names of the chart museums, sorted
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Museums))
+	for name := range m.Museums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentName returns the name of the current chart museum
+func (m *Manifest) CurrentName() string {
+	return m.current
+}
+
+// CurrentOrigin returns the path of the file that set the current museum
+func (m *Manifest) CurrentOrigin() string {
+	return m.currentOrigin
+}
+
+// LoadedVersion returns the apiVersion of the document that the manifest was
+// read from.  If it differs from APIVersionLatest, the document was migrated
+// when it was loaded, and calling Save will rewrite it in the latest format.
+func (m *Manifest) LoadedVersion() string {
+	return m.loadedVersion
+}
+
 // Save write the manifest file to disk, if it was opened with `OpenFromFile`
 // or created with `Init`
 func (m *Manifest) Save() error {
@@ -138,16 +261,14 @@ func (m *Manifest) Close() error {
 func (m *Manifest) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	buf.WriteString(`{"`)
+	buf.WriteString(apiVersionKey)
+	buf.WriteString(`": "`)
+	buf.WriteString(APIVersionLatest)
+	buf.WriteString(`", "`)
 	buf.WriteString(museumsKey)
 	buf.WriteString(`":[`)
 
-	names := make([]string, len(m.Museums))
-	offset := 0
-	for key := range m.Museums {
-		names[offset] = key
-		offset++
-	}
-	sort.Strings(names)
+	names := m.Names()
 
 	enc := json.NewEncoder(&buf)
 	enc.SetIndent("", "  ")
@@ -165,7 +286,9 @@ func (m *Manifest) MarshalJSON() ([]byte, error) {
 		}
 	}
 
-	buf.WriteString(`], "current": "`)
+	buf.WriteString(`], "`)
+	buf.WriteString(currentKey)
+	buf.WriteString(`": "`)
 	buf.WriteString(m.current)
 	buf.WriteString(`"}`)
 
@@ -180,26 +303,50 @@ func (m *Manifest) UnmarshalJSON(b []byte) error {
 		return errors.Wrapf(err, "failed to unmarshal top-level manifest")
 	}
 
-	m.Museums = map[string]*ChartMuseum{}
+	m.loadedVersion, err = migrate(objMap)
+	if err != nil {
+		return err
+	}
 
-	r, ok := objMap[museumsKey]
-	if !ok {
-		return errors.Wrapf(err, "Must have '%s' key", museumsKey)
+	extraKeys := []string{}
+	for k := range objMap {
+		switch k {
+		case apiVersionKey, currentKey, museumsKey:
+		default:
+			extraKeys = append(extraKeys, k)
+		}
+	}
+	if len(extraKeys) != 0 {
+		sort.Strings(extraKeys)
+		return errors.Errorf("Found extra keys '%s'", strings.Join(extraKeys, "', '"))
 	}
 
+	m.Museums = map[string]*ChartMuseum{}
+
 	var data []*intermediateMuseum
-	err = json.Unmarshal(*r, &data)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to unmarshal '%s' value into array of *intermediateMuseum", museumsKey)
+	r, ok := objMap[museumsKey]
+	if ok {
+		err = json.Unmarshal(*r, &data)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to unmarshal '%s' value into array of *intermediateMuseum", museumsKey)
+		}
+	} else if !m.partial {
+		return errors.Errorf("Must have '%s' key", museumsKey)
 	}
 
 	for _, v := range data {
+		if _, ok := m.Museums[v.name]; ok {
+			return errors.Errorf("Found duplicate museum name '%s'", v.name)
+		}
 		m.Museums[v.name] = v.ChartMuseum
 	}
 
 	// Read in the "current" key to set the current museum
 	r, ok = objMap[currentKey]
 	if !ok {
+		if m.partial {
+			return nil
+		}
 		return errors.Errorf("Must have '%s' key", currentKey)
 	}
 	err = json.Unmarshal(*r, &m.current)
@@ -207,22 +354,37 @@ func (m *Manifest) UnmarshalJSON(b []byte) error {
 		return errors.Wrapf(err, "Failed to unmarshal '%s' value into string", currentKey)
 	}
 
+	if m.partial {
+		// The current museum may be defined in another layer; it is validated
+		// once the layers are merged.
+		return nil
+	}
+
 	if _, ok = m.Museums[m.current]; !ok {
-		return errors.Errorf("Manifest JSON contains invalid 'current' museum '%s'", m.current)
+		return errors.Errorf("Manifest contains invalid 'current' museum '%s'", m.current)
 	}
 
 	return nil
 }
 
-// WriteTo satisfies the io.WriterTo interface
+// WriteTo satisfies the io.WriterTo interface.  The manifest is written in the
+// format that it was read in.
 func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
-	wc := writeCounter{
-		w: w,
-	}
-	enc := json.NewEncoder(&wc)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(m); err != nil {
-		return int64(wc.count), errors.Wrapf(err, "Failed to encode the manifest")
+		return 0, errors.Wrapf(err, "Failed to encode the manifest")
+	}
+
+	b, err := fromJSON(buf.Bytes(), m.format)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to encode the manifest as %s", m.format)
+	}
+
+	wc := writeCounter{
+		w: w,
 	}
-	return int64(wc.count), nil
+	_, err = wc.Write(b)
+	return int64(wc.count), err
 }
@@ -196,3 +196,25 @@ func writeManifestFile(t *testing.T, contents string) string {
 
 	return s
 }
+
+func Test_Manifest_Use(t *testing.T) {
+	m, err := Open(strings.NewReader(knownGoodManifest))
+	if err != nil {
+		t.Fatalf("Failed to open manifest string:\n%s", err.Error())
+	}
+
+	if err = m.Use("missing"); err == nil {
+		t.Errorf("Expected error using undefined museum, got none")
+	}
+	if m.CurrentName() != "default" {
+		t.Errorf("Failed Use changed the current museum to '%s'", m.CurrentName())
+	}
+
+	m.Museums["other"] = &ChartMuseum{}
+	if err = m.Use("other"); err != nil {
+		t.Fatalf("Unexpected error:\n%s", err.Error())
+	}
+	if m.CurrentName() != "other" {
+		t.Errorf("Incorrect current museum; expected 'other', actual '%s'", m.CurrentName())
+	}
+}
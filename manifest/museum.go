@@ -2,17 +2,31 @@ package manifest
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-// ChartMuseum describes the destination chart museum
+// ChartMuseum describes the destination chart museum.  Every field may
+// reference environment variables as `${VAR}` or `${VAR:-default}`; fields
+// tagged `sensitive` may also be a `file:PATH` or `exec:COMMAND` reference.
+// References are resolved by Resolve, when the museum is used.
+//
+// A museum is reached through a port forward to its service or pod, unless
+// URL is set, in which case it is reached directly.
 type ChartMuseum struct {
-	KubeContext string
-	ServiceName string
-	Namespace   string
-	Port        string
+	URL         string `json:"url,omitempty" description:"Base URL of a chart museum that is reachable without a port forward; when set, the Kubernetes fields are ignored"`
+	KubeContext string `json:"kubeContext,omitempty" description:"Kubernetes context used to reach the cluster hosting the chart museum"`
+	ServiceName string `json:"serviceName,omitempty" description:"Name of the chart museum service or pod"`
+	Namespace   string `json:"namespace,omitempty" description:"Namespace of the chart museum service or pod"`
+	Port        string `json:"port,omitempty" schemaTypes:"string,integer" description:"Port (number or name) exposed by the chart museum"`
+	Username    string `json:"username,omitempty" sensitive:"true" description:"Basic auth username; may be a file: or exec: reference"`
+	Password    string `json:"password,omitempty" sensitive:"true" description:"Basic auth password; may be a file: or exec: reference"`
+
+	// untrusted is the path of the manifest that defined the museum, if that
+	// manifest may not reference files and commands
+	untrusted string
 }
 
 type intermediateMuseum struct {
@@ -25,14 +39,29 @@ const (
 
 	kubeContextKey string = "kubeContext"
 	namespaceKey          = "namespace"
+	passwordKey           = "password"
 	portKey               = "port"
 	serviceNameKey        = "serviceName"
+	urlKey                = "url"
+	usernameKey           = "username"
 )
 
 func (cm *ChartMuseum) validate() error {
 	return nil
 }
 
+// MarshalJSON satisfies the encoding/json.Marshaler interface
+func (im intermediateMuseum) MarshalJSON() ([]byte, error) {
+	x := struct {
+		Name string `json:"name"`
+		*ChartMuseum
+	}{
+		Name:        im.name,
+		ChartMuseum: im.ChartMuseum,
+	}
+	return json.Marshal(x)
+}
+
 func (im *intermediateMuseum) UnmarshalJSON(b []byte) error {
 	if im == nil {
 		im = &intermediateMuseum{}
@@ -62,10 +91,19 @@ func (im *intermediateMuseum) UnmarshalJSON(b []byte) error {
 			err = json.Unmarshal(*v, &im.name)
 		case namespaceKey:
 			err = json.Unmarshal(*v, &im.Namespace)
+		case passwordKey:
+			err = json.Unmarshal(*v, &im.Password)
 		case portKey:
-			err = json.Unmarshal(*v, &im.Port)
+			im.Port, err = unmarshalPort(*v)
+			if err != nil {
+				return err
+			}
 		case serviceNameKey:
 			err = json.Unmarshal(*v, &im.ServiceName)
+		case urlKey:
+			err = json.Unmarshal(*v, &im.URL)
+		case usernameKey:
+			err = json.Unmarshal(*v, &im.Username)
 		default:
 			if _, ok := extraKeys[k]; ok {
 				return errors.Errorf("Found duplicate (extraneous) key '%s'", k)
@@ -94,3 +132,22 @@ func (im *intermediateMuseum) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// unmarshalPort reads a port as either a name or a number, i.e., `port: http`
+// or `port: 8080`, and returns it as a string
+func unmarshalPort(raw json.RawMessage) (string, error) {
+	var port string
+	if err := json.Unmarshal(raw, &port); err == nil {
+		return port, nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", errors.Errorf("Invalid port %s; must be a port name or number", string(raw))
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 1 || i > 65535 {
+		return "", errors.Errorf("Invalid port %s; must be a number from 1 to 65535", n.String())
+	}
+	return strconv.Itoa(i), nil
+}
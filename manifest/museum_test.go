@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +26,39 @@ func Test_Manifest_ChartMuseum_Unmarshal(t *testing.T) {
 		t.Errorf("Failed to unmarshal KubeContext; expected '%s', actual '%s'", "krobot", im.KubeContext)
 	}
 }
+
+func Test_Manifest_ChartMuseum_Port(t *testing.T) {
+	tcs := []struct {
+		name     string
+		port     string
+		expected string
+		err      string
+	}{
+		{name: "string", port: `"8080"`, expected: "8080"},
+		{name: "name", port: `"http"`, expected: "http"},
+		{name: "number", port: `8080`, expected: "8080"},
+		{name: "fraction", port: `8080.5`, err: "Invalid port 8080.5"},
+		{name: "out of range", port: `70000`, err: "Invalid port 70000"},
+		{name: "zero", port: `0`, err: "Invalid port 0"},
+		{name: "boolean", port: `true`, err: "Invalid port true"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			im := &intermediateMuseum{}
+			err := json.Unmarshal([]byte(`{"name": "default", "port": `+tc.port+`}`), &im)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Incorrect error; expected to contain '%s', actual '%v'", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to unmarshal museum:\n%s", err.Error())
+			}
+			if im.Port != tc.expected {
+				t.Errorf("Incorrect port; expected '%s', actual '%s'", tc.expected, im.Port)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MuseumForRepository returns the name of the museum that serves the helm
+// repository `repository`, as written in a chart's requirements.  A repository
+// matches a museum if it is `@NAME` or `alias:NAME`, if it is the museum's
+// URL, or if its host is the museum's in-cluster service address, i.e.,
+// `SERVICE`, `SERVICE.NAMESPACE`, or `SERVICE.NAMESPACE.svc[.cluster.local]`.
+func (m *Manifest) MuseumForRepository(repository string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	for _, prefix := range []string{"@", "alias:"} {
+		if strings.HasPrefix(repository, prefix) {
+			name := strings.TrimPrefix(repository, prefix)
+			_, ok := m.Museums[name]
+			return name, ok
+		}
+	}
+
+	u, err := url.Parse(repository)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+
+	for _, name := range m.Names() {
+		cm := m.Museums[name]
+		if cm == nil {
+			continue
+		}
+		// Only environment variables are expanded; a museum whose variables
+		// are not set cannot match.
+		cm, err := cm.resolve(false)
+		if err != nil {
+			continue
+		}
+		if cm.URL != "" {
+			if sameURL(cm.URL, u) {
+				return name, true
+			}
+			continue
+		}
+		if cm.servesHost(u.Hostname()) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// sameURL reports whether `raw` and `u` have the same scheme, host, and path,
+// ignoring any trailing slash
+func sameURL(raw string, u *url.URL) bool {
+	v, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(v.Scheme, u.Scheme) &&
+		strings.EqualFold(v.Host, u.Host) &&
+		strings.TrimSuffix(v.Path, "/") == strings.TrimSuffix(u.Path, "/")
+}
+
+// servesHost reports whether `host` is an in-cluster address of the museum's
+// service
+func (cm *ChartMuseum) servesHost(host string) bool {
+	service := cm.ServiceName
+	if i := strings.Index(service, "/"); i != -1 {
+		// "svc/NAME" or "service/NAME"; a pod has no service address.
+		if kind := service[:i]; kind != "svc" && kind != "service" && kind != "services" {
+			return false
+		}
+		service = service[i+1:]
+	}
+	if service == "" {
+		return false
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	service = strings.ToLower(service)
+	if host == service {
+		return true
+	}
+	if cm.Namespace == "" {
+		return false
+	}
+	qualified := service + "." + strings.ToLower(cm.Namespace)
+	return host == qualified || host == qualified+".svc" || host == qualified+".svc.cluster.local"
+}
@@ -0,0 +1,43 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func Test_Manifest_MuseumForRepository(t *testing.T) {
+	m := New()
+	m.Museums["cluster"] = &ChartMuseum{ServiceName: "svc/cm-chartmuseum", Namespace: "charts", Port: "8080"}
+	m.Museums["pod"] = &ChartMuseum{ServiceName: "pod/cm-0", Namespace: "charts"}
+	m.Museums["public"] = &ChartMuseum{URL: "https://charts.example.com/stable/"}
+
+	tcs := []struct {
+		name       string
+		repository string
+		expected   string
+	}{
+		{name: "alias", repository: "@public", expected: "public"},
+		{name: "alias prefix", repository: "alias:cluster", expected: "cluster"},
+		{name: "unknown alias", repository: "@other"},
+		{name: "url", repository: "https://charts.example.com/stable", expected: "public"},
+		{name: "url with different path", repository: "https://charts.example.com/incubator"},
+		{name: "service", repository: "http://cm-chartmuseum:8080", expected: "cluster"},
+		{name: "service and namespace", repository: "http://cm-chartmuseum.charts:8080", expected: "cluster"},
+		{name: "cluster address", repository: "http://cm-chartmuseum.charts.svc.cluster.local:8080", expected: "cluster"},
+		{name: "other namespace", repository: "http://cm-chartmuseum.other:8080"},
+		{name: "pod", repository: "http://cm-0.charts:8080"},
+		{name: "local", repository: "file://../bar"},
+		{name: "empty"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := m.MuseumForRepository(tc.repository)
+			if ok != (tc.expected != "") {
+				t.Fatalf("Incorrect match for '%s'; expected '%s', actual '%s' (%t)", tc.repository, tc.expected, name, ok)
+			}
+			if ok && name != tc.expected {
+				t.Errorf("Incorrect museum for '%s'; expected '%s', actual '%s'", tc.repository, tc.expected, name)
+			}
+		})
+	}
+}
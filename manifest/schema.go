@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaID identifies the JSON Schema document for the latest manifest format
+const SchemaID = "https://raw.githubusercontent.com/object88/churl/master/docs/manifest.schema.json"
+
+// Schema returns a JSON Schema document describing the latest manifest
+// format.  The museum properties are derived from the `json` and
+// `description` struct tags on ChartMuseum, so the schema stays in step with
+// the Go types; a `schemaTypes` tag lists the JSON types that a field accepts,
+// where it accepts more than its Go type.
+func Schema() ([]byte, error) {
+	museum, err := objectSchema(reflect.TypeOf(ChartMuseum{}))
+	if err != nil {
+		return nil, err
+	}
+	museum.Properties[nameKey] = &schemaNode{
+		Type:        "string",
+		Description: "Unique name of the chart museum",
+	}
+	museum.Required = append([]string{nameKey}, museum.Required...)
+
+	s := &schemaNode{
+		Schema:      "http://json-schema.org/draft-07/schema#",
+		ID:          SchemaID,
+		Title:       "churl manifest",
+		Description: "Configuration for the churl tool",
+		Type:        "object",
+		Properties: map[string]*schemaNode{
+			apiVersionKey: {
+				Type:        "string",
+				Description: "Version of the manifest format",
+				Enum:        []string{APIVersionLatest},
+			},
+			museumsKey: {
+				Type:        "array",
+				Description: "Chart museums that churl can query",
+				Items:       museum,
+			},
+			currentKey: {
+				Type:        "string",
+				Description: "Name of the chart museum used by default",
+			},
+		},
+		Required:             []string{apiVersionKey, museumsKey, currentKey},
+		AdditionalProperties: new(bool),
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Internal error: failed to encode manifest schema")
+	}
+	return append(b, '\n'), nil
+}
+
+type schemaNode struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type"` // a type name, or a list of them
+	Enum                 []string               `json:"enum,omitempty"`
+	Properties           map[string]*schemaNode `json:"properties,omitempty"`
+	Items                *schemaNode            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+func objectSchema(t reflect.Type) (*schemaNode, error) {
+	n := &schemaNode{
+		Type:                 "object",
+		Properties:           map[string]*schemaNode{},
+		AdditionalProperties: new(bool),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = f.Name
+		}
+
+		p, err := propertySchema(f.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to describe field '%s'", f.Name)
+		}
+		p.Description = f.Tag.Get("description")
+		if types := f.Tag.Get("schemaTypes"); types != "" {
+			p.Type = strings.Split(types, ",")
+		}
+		n.Properties[name] = p
+
+		omitempty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+		if !omitempty {
+			n.Required = append(n.Required, name)
+		}
+	}
+
+	return n, nil
+}
+
+func propertySchema(t reflect.Type) (*schemaNode, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return &schemaNode{Type: "string"}, nil
+	case reflect.Bool:
+		return &schemaNode{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schemaNode{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schemaNode{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := propertySchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schemaNode{Type: "array", Items: items}, nil
+	case reflect.Ptr:
+		return propertySchema(t.Elem())
+	case reflect.Struct:
+		return objectSchema(t)
+	default:
+		return nil, errors.Errorf("Unsupported kind '%s'", t.Kind())
+	}
+}
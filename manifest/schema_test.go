@@ -0,0 +1,26 @@
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Test_Manifest_Schema ensures that the published schema is regenerated when
+// the Go types change.  To update it, run `churl config schema` and write the
+// output to docs/manifest.schema.json.
+func Test_Manifest_Schema(t *testing.T) {
+	expected, err := ioutil.ReadFile("../docs/manifest.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read published schema:\n%s", err.Error())
+	}
+
+	actual, err := Schema()
+	if err != nil {
+		t.Fatalf("Failed to generate schema:\n%s", err.Error())
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Published schema at docs/manifest.schema.json is out of date")
+	}
+}
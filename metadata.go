@@ -1,8 +1,10 @@
 package churl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/object88/churl/internal/request"
 	"github.com/pkg/errors"
@@ -26,22 +28,31 @@ func NewMetadataReader(sourceport string) (*MetadataReader, error) {
 	return m, nil
 }
 
+// SetBasicAuth sets the credentials used to authenticate with the chart
+// museum
+func (m *MetadataReader) SetBasicAuth(username, password string) {
+	m.req.SetBasicAuth(username, password)
+}
+
+// SetTransport sets the http.RoundTripper used to perform requests
+func (m *MetadataReader) SetTransport(rt http.RoundTripper) {
+	m.req.Transport = rt
+}
+
 func (m *MetadataReader) Do(chartpath string) (*repo.ChartVersion, error) {
 	query := fmt.Sprintf("api/charts/%s", chartpath)
-	rc, ok, err := m.req.ProcessGet(query)
+	rc, code, err := m.req.Get(context.Background(), query)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to query for chart")
 	}
 	defer rc.Close()
 
-	dec := json.NewDecoder(rc)
-
-	if !ok {
-		aerr := &ApiError{}
-		dec.Decode(aerr)
-		return nil, aerr
+	if code != http.StatusOK {
+		return nil, responseError("", m.req.URL(query), code, rc, &NotFoundError{Chart: chartpath})
 	}
 
+	dec := json.NewDecoder(rc)
+
 	cvs := []*repo.ChartVersion{}
 	err = dec.Decode(&cvs)
 	if err != nil {
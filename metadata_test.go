@@ -2,11 +2,13 @@ package churl
 
 import (
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/object88/churl/churltest"
 	"github.com/object88/churl/internal/request"
 	"github.com/object88/churl/mocks"
 )
@@ -72,3 +74,45 @@ func Test_Metadata_Do_MissingChart(t *testing.T) {
 		t.Errorf("Expected error but got none")
 	}
 }
+
+func Test_Metadata_Do_Server(t *testing.T) {
+	s := churltest.NewServer(
+		&churltest.Chart{Name: "foo", Version: "1.0.0"},
+		&churltest.Chart{Name: "foo", Version: "1.2.0"},
+	)
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	mr, err := NewMetadataReader(port)
+	if err != nil {
+		t.Fatalf("Failed to create metadata reader:\n%s", err.Error())
+	}
+
+	tcs := []struct {
+		name     string
+		chart    string
+		expected string
+		kind     ErrorKind
+	}{
+		{name: "newest", chart: "foo", expected: "1.2.0"},
+		{name: "missing", chart: "bar", kind: KindNotFound},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cv, err := mr.Do(tc.chart)
+			if tc.kind != "" {
+				if actual := KindOf(err); actual != tc.kind {
+					t.Errorf("Incorrect error kind; expected '%s', actual '%s'", tc.kind, actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error:\n%s", err.Error())
+			}
+			if cv.Version != tc.expected {
+				t.Errorf("Incorrect version; expected '%s', actual '%s'", tc.expected, cv.Version)
+			}
+		})
+	}
+}